@@ -0,0 +1,98 @@
+package structof
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// isBigType reports whether t is big.Int, big.Float or big.Rat.
+func isBigType(t reflect.Type) bool {
+	return bigIntType == t || bigFloatType == t || bigRatType == t
+}
+
+// bigEncoder encodes a big.Int, big.Float or big.Rat as a decimal string.
+func bigEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
+	if !v.CanAddr() {
+		// The methods of the big types have pointer receivers.
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	e.setLeaf(key, bigString(v.Addr().Interface(), opts))
+}
+
+// bigString formats the *big.Int, *big.Float or *big.Rat x as a decimal
+// string, with opts.precision digits after the decimal point if set.
+func bigString(x any, opts encOpts) string {
+	switch x := x.(type) {
+	case *big.Int:
+		return x.String()
+	case *big.Float:
+		if opts.hasPrecision {
+			return x.Text('f', opts.precision)
+		}
+		return x.Text('g', -1)
+	case *big.Rat:
+		if opts.hasPrecision {
+			return x.FloatString(opts.precision)
+		}
+		return x.RatString()
+	}
+	panic("unreachable")
+}
+
+// decodeBig stores src into the big.Int, big.Float or big.Rat v, parsing
+// strings and json.Number values, and converting the other numbers exactly.
+func decodeBig(v reflect.Value, src any) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case json.Number:
+		s = string(src)
+	default:
+		sv := reflect.ValueOf(src)
+		switch {
+		case sv.CanInt():
+			s = strconv.FormatInt(sv.Int(), 10)
+		case sv.CanUint():
+			s = strconv.FormatUint(sv.Uint(), 10)
+		case sv.CanFloat():
+			if bigIntType == v.Type() {
+				f := sv.Float()
+				if f != math.Trunc(f) || math.IsInf(f, 0) {
+					return fmt.Errorf("value %v is not representable in %s", f, v.Type())
+				}
+				s = strconv.FormatFloat(f, 'f', -1, 64)
+				break
+			}
+			s = strconv.FormatFloat(sv.Float(), 'g', -1, sv.Type().Bits())
+		default:
+			return fmt.Errorf("cannot decode %T into %s", src, v.Type())
+		}
+	}
+
+	var ok bool
+	switch x := v.Addr().Interface().(type) {
+	case *big.Int:
+		_, ok = x.SetString(s, 10)
+	case *big.Float:
+		_, ok = x.SetString(s)
+	case *big.Rat:
+		_, ok = x.SetString(s)
+	}
+	if !ok {
+		return fmt.Errorf("invalid %s %q", v.Type(), s)
+	}
+	return nil
+}
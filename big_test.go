@@ -0,0 +1,78 @@
+package structof
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBigNumbers(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Supply  *big.Int   `structof:"supply"`
+		Balance big.Int    `structof:"balance"`
+		Rate    *big.Float `structof:"rate"`
+		Price   *big.Rat   `structof:"price,precision=2"`
+		Share   *big.Rat   `structof:"share"`
+		Amount  *big.Float `structof:"amount,precision=3"`
+		Missing *big.Int   `structof:"missing"`
+	}
+	supply, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	s := S{
+		Supply:  supply,
+		Balance: *big.NewInt(-42),
+		Rate:    big.NewFloat(0.25),
+		Price:   big.NewRat(1250, 100),
+		Share:   big.NewRat(1, 3),
+		Amount:  big.NewFloat(1.5),
+	}
+
+	m := MakeMap(s)
+	want := map[string]any{
+		"supply":  "123456789012345678901234567890",
+		"balance": "-42",
+		"rate":    "0.25",
+		"price":   "12.50",
+		"share":   "1/3",
+		"amount":  "1.500",
+		"missing": (*big.Int)(nil),
+	}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+
+	var got S
+	if err := FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Supply.Cmp(supply) != 0 || got.Balance.Int64() != -42 || got.Rate.Cmp(s.Rate) != 0 ||
+		got.Price.Cmp(s.Price) != 0 || got.Share.Cmp(s.Share) != 0 || got.Missing != nil {
+		t.Errorf("FillStruct = %+v", got)
+	}
+
+	m = map[string]any{
+		"supply":  json.Number("123456789012345678901234567890"),
+		"balance": 7,
+		"rate":    0.5,
+		"share":   uint8(2),
+	}
+	if err := FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Supply.Cmp(supply) != 0 || got.Balance.Int64() != 7 || got.Rate.String() != "0.5" || got.Share.RatString() != "2" {
+		t.Errorf("FillStruct of numbers = %+v", got)
+	}
+
+	for _, m := range []map[string]any{
+		{"supply": "12x"},
+		{"balance": 1.5},
+		{"rate": true},
+	} {
+		if err := FillStruct(m, &got); err == nil {
+			t.Errorf("FillStruct(%v) should return error", m)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package structof
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// A typeCache is a read-mostly map from types to the values computed for
+// them, such as their encoders and fields.
+//
+// Lookups load an immutable map through an atomic pointer, so that the hot
+// path of encoding takes no lock and shares no written memory between
+// goroutines. Stores, which happen once per type when it is first seen, go
+// to a dirty map under a mutex, which is merged into a copy of the immutable
+// map, published in its place, once the lookups missing the immutable map
+// have paid for the copy. Storing many types, as the instantiations of a
+// generic struct, thus costs amortized constant time per type instead of a
+// copy of the whole map each.
+type typeCache[V any] struct {
+	mu sync.Mutex // serializes stores and guards dirty and misses
+	m  atomic.Pointer[map[reflect.Type]V]
+
+	// The values stored since m was published, and the count of lookups
+	// found in dirty since then.
+	dirty  map[reflect.Type]V
+	misses int
+}
+
+// Load returns the value stored for t and whether it was found.
+func (c *typeCache[V]) Load(t reflect.Type) (V, bool) {
+	if m := c.m.Load(); m != nil {
+		if v, ok := (*m)[t]; ok {
+			return v, true
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load(t)
+}
+
+// load is like Load with c.mu held.
+func (c *typeCache[V]) load(t reflect.Type) (V, bool) {
+	var read map[reflect.Type]V
+	if m := c.m.Load(); m != nil {
+		read = *m
+	}
+	if v, ok := read[t]; ok {
+		return v, true
+	}
+	v, ok := c.dirty[t]
+	if ok {
+		c.misses++
+		if c.misses >= len(read)+len(c.dirty) {
+			c.publish(read)
+		}
+	}
+	return v, ok
+}
+
+// LoadOrStore returns the existing value for t if present.
+// Otherwise, it stores and returns v.
+// The loaded result is true if the value was loaded, false if stored.
+func (c *typeCache[V]) LoadOrStore(t reflect.Type, v V) (actual V, loaded bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if actual, ok := c.load(t); ok {
+		return actual, true
+	}
+	c.store(t, v)
+	return v, false
+}
+
+// Store sets the value for t.
+func (c *typeCache[V]) Store(t reflect.Type, v V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(t, v)
+}
+
+func (c *typeCache[V]) store(t reflect.Type, v V) {
+	if c.dirty == nil {
+		c.dirty = make(map[reflect.Type]V)
+	}
+	c.dirty[t] = v
+	if m := c.m.Load(); m != nil {
+		if _, ok := (*m)[t]; ok {
+			// Replace the published value.
+			c.publish(*m)
+		}
+	}
+}
+
+// publish publishes a copy of read with the values of dirty, with c.mu held.
+func (c *typeCache[V]) publish(read map[reflect.Type]V) {
+	m := make(map[reflect.Type]V, len(read)+len(c.dirty))
+	for k, e := range read {
+		m[k] = e
+	}
+	for k, e := range c.dirty {
+		m[k] = e
+	}
+	c.m.Store(&m)
+	c.dirty, c.misses = nil, 0
+}
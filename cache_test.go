@@ -0,0 +1,74 @@
+package structof
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTypeCache(t *testing.T) {
+	t.Parallel()
+
+	var c typeCache[int]
+	if _, ok := c.Load(reflect.TypeOf(0)); ok {
+		t.Error("Load on empty cache: found")
+	}
+
+	types := []reflect.Type{reflect.TypeOf(0), reflect.TypeOf(""), reflect.TypeOf(0.0), reflect.TypeOf(false)}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j, typ := range types {
+				if v, loaded := c.LoadOrStore(typ, i*len(types)+j); loaded && v%len(types) != j {
+					t.Errorf("LoadOrStore(%s) = %d", typ, v)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for j, typ := range types {
+		if v, ok := c.Load(typ); !ok || v%len(types) != j {
+			t.Errorf("Load(%s) = %d, %t", typ, v, ok)
+		}
+	}
+
+	c.Store(types[0], -1)
+	if v, _ := c.Load(types[0]); v != -1 {
+		t.Errorf("Load after Store = %d, want -1", v)
+	}
+}
+
+func TestTypeCacheMany(t *testing.T) {
+	t.Parallel()
+
+	var c typeCache[int]
+	types := make([]reflect.Type, 1000)
+	for i := range types {
+		types[i] = reflect.ArrayOf(i, reflect.TypeOf(0))
+		if _, loaded := c.LoadOrStore(types[i], i); loaded {
+			t.Fatalf("LoadOrStore(%s): loaded", types[i])
+		}
+	}
+	for n := 0; n < 2; n++ {
+		for i, typ := range types {
+			if v, ok := c.Load(typ); !ok || v != i {
+				t.Fatalf("Load(%s) = %d, %t", typ, v, ok)
+			}
+		}
+	}
+
+	// The lookups have published the stored values.
+	if m := c.m.Load(); m == nil {
+		t.Error("published no types")
+	} else if len(*m) != len(types) {
+		t.Errorf("published %d types, want %d", len(*m), len(types))
+	}
+
+	c.Store(types[1], -1)
+	if v, _ := c.Load(types[1]); v != -1 {
+		t.Errorf("Load after Store = %d, want -1", v)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var anyType = reflect.TypeOf((*any)(nil)).Elem()
+
+// basicTypes are the predeclared types, by name.
+var basicTypes = map[string]reflect.Type{
+	"bool":       reflect.TypeOf(false),
+	"int":        reflect.TypeOf(int(0)),
+	"int8":       reflect.TypeOf(int8(0)),
+	"int16":      reflect.TypeOf(int16(0)),
+	"int32":      reflect.TypeOf(int32(0)),
+	"rune":       reflect.TypeOf(rune(0)),
+	"int64":      reflect.TypeOf(int64(0)),
+	"uint":       reflect.TypeOf(uint(0)),
+	"uint8":      reflect.TypeOf(uint8(0)),
+	"byte":       reflect.TypeOf(byte(0)),
+	"uint16":     reflect.TypeOf(uint16(0)),
+	"uint32":     reflect.TypeOf(uint32(0)),
+	"uint64":     reflect.TypeOf(uint64(0)),
+	"uintptr":    reflect.TypeOf(uintptr(0)),
+	"float32":    reflect.TypeOf(float32(0)),
+	"float64":    reflect.TypeOf(float64(0)),
+	"complex64":  reflect.TypeOf(complex64(0)),
+	"complex128": reflect.TypeOf(complex128(0)),
+	"string":     reflect.TypeOf(""),
+	"any":        anyType,
+	"error":      anyType,
+}
+
+// A loader rebuilds the types declared by the source files of a package
+// as reflect types, so that structof resolves their fields as it does
+// for the compiled types.
+//
+// The types declared by other packages, as well as interface, function,
+// channel and generic types, are not known and become any. Since the
+// rebuilt struct types have no methods, the options depending on methods,
+// such as "stringer", are not reflected in the types.
+type loader struct {
+	names    []string // exported type names, in source order
+	specs    map[string]*ast.TypeSpec
+	types    map[string]reflect.Type
+	building map[string]bool
+
+	// Source types of the fields of the rebuilt struct types.
+	exprs map[reflect.Type][]ast.Expr
+}
+
+// load parses the non-test Go files of the package in dir.
+func load(dir string) (*loader, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: found %d packages, want 1", dir, len(pkgs))
+	}
+
+	l := &loader{
+		specs:    make(map[string]*ast.TypeSpec),
+		types:    make(map[string]reflect.Type),
+		building: make(map[string]bool),
+		exprs:    make(map[reflect.Type][]ast.Expr),
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.GenDecl)
+				if !ok || token.TYPE != decl.Tok {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					if spec.TypeParams != nil {
+						continue
+					}
+					l.specs[spec.Name.Name] = spec
+					if spec.Name.IsExported() {
+						l.names = append(l.names, spec.Name.Name)
+					}
+				}
+			}
+		}
+	}
+	return l, nil
+}
+
+// named returns the type declared as name, or any if it is not known
+// or is being built, to break the cycles of recursive types.
+func (l *loader) named(name string) reflect.Type {
+	if t, ok := l.types[name]; ok {
+		return t
+	}
+	spec, ok := l.specs[name]
+	if !ok || l.building[name] {
+		return anyType
+	}
+	l.building[name] = true
+	defer delete(l.building, name)
+
+	t := l.typeOf(spec.Type)
+	l.types[name] = t
+	return t
+}
+
+func (l *loader) typeOf(x ast.Expr) reflect.Type {
+	switch x := x.(type) {
+	case *ast.Ident:
+		if t, ok := basicTypes[x.Name]; ok {
+			return t
+		}
+		return l.named(x.Name)
+	case *ast.ParenExpr:
+		return l.typeOf(x.X)
+	case *ast.StarExpr:
+		return reflect.PointerTo(l.typeOf(x.X))
+	case *ast.ArrayType:
+		elem := l.typeOf(x.Elt)
+		if lit, ok := x.Len.(*ast.BasicLit); ok {
+			if n, err := strconv.Atoi(lit.Value); err == nil {
+				return reflect.ArrayOf(n, elem)
+			}
+		}
+		return reflect.SliceOf(elem)
+	case *ast.MapType:
+		key := l.typeOf(x.Key)
+		if !key.Comparable() {
+			key = anyType
+		}
+		return reflect.MapOf(key, l.typeOf(x.Value))
+	case *ast.StructType:
+		return l.structOf(x)
+	}
+	return anyType
+}
+
+func (l *loader) structOf(st *ast.StructType) reflect.Type {
+	var fields []reflect.StructField
+	var exprs []ast.Expr
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(s)
+			}
+		}
+		typ := l.typeOf(f.Type)
+
+		if len(f.Names) == 0 {
+			// Only embedded structs are promoted, the other embedded
+			// types are fields named by their type, if exported.
+			name := embeddedName(f.Type)
+			et := typ
+			if reflect.Pointer == et.Kind() {
+				et = et.Elem()
+			}
+			embedded := reflect.Struct == et.Kind()
+			if !ast.IsExported(name) {
+				if !embedded {
+					continue
+				}
+				// reflect.StructOf rejects unexported names.
+				r, size := utf8.DecodeRuneInString(name)
+				name = string(unicode.ToUpper(r)) + name[size:]
+			}
+			fields = append(fields, reflect.StructField{Name: name, Type: typ, Tag: tag, Anonymous: embedded})
+			exprs = append(exprs, f.Type)
+			continue
+		}
+
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fields = append(fields, reflect.StructField{Name: name.Name, Type: typ, Tag: tag})
+			exprs = append(exprs, f.Type)
+		}
+	}
+	t := reflect.StructOf(fields)
+	l.exprs[t] = exprs
+	return t
+}
+
+// embeddedName returns the field name of the embedded type x.
+func embeddedName(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return embeddedName(x.X)
+	case *ast.SelectorExpr:
+		return x.Sel.Name
+	case *ast.Ident:
+		return x.Name
+	case *ast.IndexExpr:
+		return embeddedName(x.X)
+	case *ast.IndexListExpr:
+		return embeddedName(x.X)
+	}
+	return ""
+}
+
+// fieldExpr returns the source type of the field at index of the struct type t.
+func (l *loader) fieldExpr(t reflect.Type, index []int) ast.Expr {
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if reflect.Pointer == t.Kind() {
+			t = t.Elem()
+		}
+	}
+	return l.exprs[t][index[len(index)-1]]
+}
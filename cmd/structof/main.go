@@ -0,0 +1,187 @@
+// Command structof prints the keys that FillMap emits for the struct types
+// declared by a Go package, as resolved from their structof tags.
+//
+// Usage:
+//
+//	structof [-json] [-type T,...] [dir]
+//
+// It loads the package in dir, the current directory by default, and prints
+// for each of its exported struct types, or the types listed by -type,
+// the key, the Go type, the Go field and the tag options of every key of
+// the encoded map, with the fields of inline structs flattened. With -json,
+// it prints a JSON Schema describing the maps instead, with a definition
+// for each type.
+//
+// The package is parsed, not type-checked: the types declared by other
+// packages are shown with their source type but described as any, and the
+// options depending on methods, such as "stringer", are not reflected.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/weiwenchen2022/structof"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "structof:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("structof", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print a JSON Schema")
+	typeNames := flags.String("type", "", "comma-separated list of type names; default all exported struct types")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: structof [-json] [-type T,...] [dir]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	dir := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		flags.Usage()
+		return errors.New("too many arguments")
+	}
+
+	l, err := load(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if *typeNames != "" {
+		for _, name := range strings.Split(*typeNames, ",") {
+			if t := l.named(name); reflect.Struct != t.Kind() {
+				return fmt.Errorf("%s: not a struct type", name)
+			}
+			names = append(names, name)
+		}
+	} else {
+		for _, name := range l.names {
+			if reflect.Struct == l.named(name).Kind() {
+				names = append(names, name)
+			}
+		}
+	}
+
+	if *asJSON {
+		return writeSchema(w, l, names)
+	}
+	return writeLayout(w, l, names)
+}
+
+// writeLayout prints the keys of the struct types names as a table.
+func writeLayout(w io.Writer, l *loader, names []string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "type %s\n", name)
+		fmt.Fprintln(tw, "KEY\tTYPE\tFIELD\tOPTIONS")
+		t := l.named(name)
+		var walk func(st reflect.Type, prefix string)
+		walk = func(st reflect.Type, prefix string) {
+			for _, f := range structof.TypeSchema(st) {
+				if ft := indirect(f.Type); f.Inline && reflect.Struct == ft.Kind() {
+					walk(ft, prefix+f.Name+".")
+					continue
+				}
+				_, opts, _ := strings.Cut(st.FieldByIndex(f.Index).Tag.Get("structof"), ",")
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Key, types.ExprString(l.fieldExpr(st, f.Index)), prefix+f.Name, opts)
+			}
+		}
+		walk(t, "")
+	}
+	return tw.Flush()
+}
+
+// writeSchema prints a JSON Schema with a definition for each of the struct
+// types names.
+func writeSchema(w io.Writer, l *loader, names []string) error {
+	defs := make(map[string]any, len(names))
+	for _, name := range names {
+		schema := structSchema(l.named(name))
+		schema["title"] = name
+		defs[name] = schema
+	}
+	b, err := json.MarshalIndent(map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$defs":   defs,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for _, f := range structof.TypeSchema(t) {
+			if ft := indirect(f.Type); f.Inline && reflect.Struct == ft.Kind() {
+				walk(ft)
+				continue
+			}
+			if f.Quoted {
+				properties[f.Key] = map[string]any{"type": "string"}
+				continue
+			}
+			properties[f.Key] = typeSchema(f.Type)
+		}
+	}
+	walk(t)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if reflect.Uint8 == t.Elem().Kind() {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return map[string]any{}
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for reflect.Pointer == t.Kind() {
+		t = t.Elem()
+	}
+	return t
+}
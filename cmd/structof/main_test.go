@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLayout(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := run([]string{"-type", "User", "testdata/users"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `type User
+KEY       TYPE               FIELD         OPTIONS
+id        int                ID
+name      string             Name          omitempty
+age       int64              Age           string
+created   time.Time          Meta.Created
+tags      []string           Meta.Tags     omitempty
+attrs     map[string]string  Attrs
+Data      []byte             Data
+Duration  time.Duration      Duration
+`
+	var got strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		got.WriteString(strings.TrimRight(line, " \n"))
+		if strings.HasSuffix(line, "\n") {
+			got.WriteString("\n")
+		}
+	}
+	if diff := cmp.Diff(want, got.String()); diff != "" {
+		t.Errorf("layout mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := run([]string{"-json", "testdata/users"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Defs map[string]struct {
+			Title      string
+			Properties map[string]map[string]any
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Defs) != 3 {
+		t.Errorf("got %d definitions, want 3", len(schema.Defs))
+	}
+	want := map[string]map[string]any{
+		"id":       {"type": "integer"},
+		"name":     {"type": "string"},
+		"age":      {"type": "string"},
+		"created":  {},
+		"tags":     {"type": "array", "items": map[string]any{"type": "string"}},
+		"attrs":    {"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+		"Data":     {"type": "string", "contentEncoding": "base64"},
+		"Duration": {},
+	}
+	if diff := cmp.Diff(want, schema.Defs["User"].Properties); diff != "" {
+		t.Errorf("User schema mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnknownType(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := run([]string{"-type", "Nope", "testdata/users"}, &buf); err == nil {
+		t.Error("run with an unknown type succeeded")
+	}
+}
@@ -0,0 +1,29 @@
+package users
+
+import "time"
+
+type base struct {
+	ID int `structof:"id"`
+}
+
+type Meta struct {
+	Created time.Time `structof:"created"`
+	Tags    []string  `structof:"tags,omitempty"`
+}
+
+type Node struct {
+	Name string `structof:"name"`
+	Next *Node  `structof:"next"`
+}
+
+type User struct {
+	base
+	Name   string            `structof:"name,omitempty"`
+	Age    int64             `structof:"age,string"`
+	Meta   *Meta             `structof:",inline"`
+	Attrs  map[string]string `structof:"attrs"`
+	Data   []byte
+	secret string
+	Skip   int `structof:"-"`
+	time.Duration
+}
@@ -0,0 +1,173 @@
+package structof
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// LoadConfig fills the struct pointed to by s with the configuration m, a
+// flat map with dotted keys as read by configuration libraries such as viper
+// and koanf, overridden by environment variables, in one call.
+//
+// The dotted keys of m are unflattened into nested maps, "server.port"
+// becoming the key "port" of the map under the key "server", which are
+// merged with the nested maps m may hold. A key that is both a value and the
+// prefix of a dotted key, as "server" and "server.port", is an error.
+//
+// Each key path of the struct, made of the keys of its fields as FillMap
+// names them, is then overridden by the environment variable named by
+// prefix, an underscore and the path in upper case with its dots and dashes
+// replaced by underscores, "APP_SERVER_PORT" for the path "server.port" and
+// the prefix "APP". The variables are not prefixed if prefix is empty.
+// The values are looked up by os.LookupEnv, unless the Decoder is configured
+// by WithLookupEnv.
+//
+// The struct is filled as by FillStruct, except that the string elements
+// are parsed according to the type of their field, as by
+// FillStructFromStrings, so that the precedence is, from the lowest: the
+// "default" options if the Decoder is configured by WithDefaults, the
+// elements of m, and the environment variables.
+func LoadConfig(m map[string]any, prefix string, s any) error {
+	return defaultDecoder.LoadConfig(m, prefix, s)
+}
+
+// LoadConfig is like the LoadConfig function but decodes with dec's configuration.
+func (dec *Decoder) LoadConfig(m map[string]any, prefix string, s any) error {
+	v := reflect.ValueOf(s)
+	if reflect.Pointer != v.Kind() || v.IsNil() || reflect.Struct != v.Type().Elem().Kind() {
+		return fmt.Errorf("structof: LoadConfig of non-pointer to struct %T", s)
+	}
+
+	config, err := unflatten(m)
+	if err != nil {
+		return err
+	}
+
+	lookup := dec.lookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	for _, path := range configPaths(nil, v.Type().Elem(), nil, nil) {
+		if value, ok := lookup(envName(prefix, path)); ok {
+			if err := setConfig(config, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return dec.fillStruct("LoadConfig", config, s, true)
+}
+
+// WithLookupEnv configures the Decoder's LoadConfig to look the environment
+// variables up by fn instead of os.LookupEnv, as for tests or for sources of
+// variables other than the process environment.
+func WithLookupEnv(fn func(key string) (string, bool)) DecoderOption {
+	return func(dec *Decoder) {
+		dec.lookupEnv = fn
+	}
+}
+
+// unflatten returns a copy of m with its dotted keys unflattened into
+// nested maps.
+func unflatten(m map[string]any) (map[string]any, error) {
+	config := make(map[string]any, len(m))
+	for key, elem := range m {
+		if nested, ok := elem.(map[string]any); ok {
+			var err error
+			if elem, err = unflatten(nested); err != nil {
+				return nil, err
+			}
+		}
+		if err := setConfig(config, strings.Split(key, "."), elem); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// setConfig sets the element at path in the nested maps of config to elem,
+// merging elem into the map already there if both are maps, and replacing
+// it otherwise.
+func setConfig(config map[string]any, path []string, elem any) error {
+	m := config
+	for i, key := range path[:len(path)-1] {
+		next, ok := m[key]
+		if !ok || next == nil {
+			next = make(map[string]any)
+			m[key] = next
+		}
+		nested, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("structof: config key %q is both a value and a map", strings.Join(path[:i+1], "."))
+		}
+		m = nested
+	}
+
+	key := path[len(path)-1]
+	nested, isMap := elem.(map[string]any)
+	_, oldIsMap := m[key].(map[string]any)
+	switch {
+	case isMap && oldIsMap:
+		for k, e := range nested {
+			if err := setConfig(config, append(path[:len(path):len(path)], k), e); err != nil {
+				return err
+			}
+		}
+	case isMap != oldIsMap && m[key] != nil && elem != nil:
+		return fmt.Errorf("structof: config key %q is both a value and a map", strings.Join(path, "."))
+	default:
+		m[key] = elem
+	}
+	return nil
+}
+
+// configPaths appends to paths the key paths, prefixed with path, of the
+// leaf fields of the struct type t: the fields other than the structs with
+// fields to fill, which are followed unless seen on the way, to break
+// recursion.
+func configPaths(path []string, t reflect.Type, seen map[reflect.Type]bool, paths [][]string) [][]string {
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	fields := cachedTypeFields(t)
+	for i := range fields.list {
+		f := &fields.list[i]
+		if f.encodeOnly {
+			continue
+		}
+		ft := indirectType(f.typ)
+		if f.inline {
+			paths = configPaths(path, ft, seen, paths)
+			continue
+		}
+
+		fpath := append(path[:len(path):len(path)], f.name)
+		if reflect.Struct == ft.Kind() && !configLeaf(ft) && !seen[ft] {
+			paths = configPaths(fpath, ft, seen, paths)
+			continue
+		}
+		paths = append(paths, fpath)
+	}
+	return paths
+}
+
+// configLeaf reports whether the struct type t is filled from a single
+// value, as time.Time and the types implementing encoding.TextUnmarshaler.
+func configLeaf(t reflect.Type) bool {
+	return timeType == t || reflect.PointerTo(t).Implements(textUnmarshalerType) ||
+		len(cachedTypeFields(t).list) == 0
+}
+
+// envName returns the name of the environment variable overriding the
+// config key path.
+func envName(prefix string, path []string) string {
+	name := strings.NewReplacer(".", "_", "-", "_").Replace(strings.ToUpper(strings.Join(path, "_")))
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
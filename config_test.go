@@ -0,0 +1,91 @@
+package structof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	type TLS struct {
+		Cert string `structof:"cert"`
+	}
+	type Server struct {
+		Host    string        `structof:"host,default=localhost"`
+		Port    int           `structof:"port,default=80"`
+		Timeout time.Duration `structof:"read-timeout"`
+		TLS     *TLS          `structof:"tls"`
+	}
+	type Config struct {
+		Name   string   `structof:"name"`
+		Server Server   `structof:"server"`
+		Tags   []string `structof:"tags"`
+		Debug  bool     `structof:"debug"`
+	}
+
+	env := map[string]string{
+		"APP_SERVER_PORT":         "9090",
+		"APP_SERVER_READ_TIMEOUT": "5s",
+		"APP_TAGS":                "a,b",
+		"SERVER_HOST":             "unprefixed",
+	}
+	dec := NewDecoder(WithDefaults(), WithLookupEnv(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}))
+
+	m := map[string]any{
+		"name":            "api",
+		"server.port":     8080,
+		"server.tls.cert": "/etc/cert.pem",
+		"server":          map[string]any{"read-timeout": "1s"},
+		"debug":           "true",
+	}
+	var c Config
+	if err := dec.LoadConfig(m, "APP", &c); err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		Name: "api",
+		Server: Server{
+			Host: "localhost", Port: 9090, Timeout: 5 * time.Second,
+			TLS: &TLS{"/etc/cert.pem"},
+		},
+		Tags:  []string{"a", "b"},
+		Debug: true,
+	}
+	if diff := cmp.Diff(want, c); diff != "" {
+		t.Errorf("LoadConfig mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, m := range []map[string]any{
+		{"server": 1, "server.port": 2},
+		{"server.port": "http"},
+	} {
+		if err := dec.LoadConfig(m, "", &c); err == nil {
+			t.Errorf("LoadConfig(%v) should return error", m)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		prefix string
+		path   []string
+		want   string
+	}{
+		{"", []string{"port"}, "PORT"},
+		{"APP", []string{"server", "read-timeout"}, "APP_SERVER_READ_TIMEOUT"},
+		{"app", []string{"db", "pool.size"}, "app_DB_POOL_SIZE"},
+	}
+	for _, tt := range tests {
+		if got := envName(tt.prefix, tt.path); tt.want != got {
+			t.Errorf("envName(%q, %q) = %q, want %q", tt.prefix, tt.path, got, tt.want)
+		}
+	}
+}
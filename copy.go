@@ -0,0 +1,227 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A CopyOption configures CopyFields.
+type CopyOption func(*copier)
+
+type copier struct {
+	convert bool
+
+	// Keys of the unmatched fields, set by WithUnmatched.
+	srcUnmatched, dstUnmatched *[]string
+}
+
+// WithConversion configures CopyFields to convert the value of a field
+// into the field of dst of another type when Go allows the conversion, as
+// an int32 into an int64 or a named string type into a string, except
+// integers into strings, which Go converts to the character they encode.
+func WithConversion() CopyOption {
+	return func(c *copier) {
+		c.convert = true
+	}
+}
+
+// WithUnmatched configures CopyFields to append to *src the keys of the
+// fields of the src struct without a field of the same key in dst, and to
+// *dst the keys of the fields of dst without one in src, for reporting the
+// fields not copied. Either of src and dst may be nil.
+func WithUnmatched(src, dst *[]string) CopyOption {
+	return func(c *copier) {
+		c.srcUnmatched, c.dstUnmatched = src, dst
+	}
+}
+
+// CopyFields copies the fields of the struct src, a struct or a pointer to
+// struct, into the fields of the struct pointed to by dst, of another type,
+// with the same key, as MakeMap names them, without encoding an
+// intermediate map. The fields of inline structs are matched by their own
+// keys, and the results of the "method" fields of src are copied too.
+//
+// The value of a field is assigned to the field of dst if its type is
+// assignable to it, and converted if CopyFields is configured by
+// WithConversion and Go allows it. A struct, or a pointer to struct, is
+// otherwise copied field by field into a struct of another type, the nil
+// pointers of dst being allocated. A field that cannot be copied is an
+// error, the fields before it being copied.
+func CopyFields(dst, src any, opts ...CopyOption) error {
+	dv := reflect.ValueOf(dst)
+	if reflect.Pointer != dv.Kind() || dv.IsNil() || reflect.Struct != dv.Type().Elem().Kind() {
+		return fmt.Errorf("structof: CopyFields into non-pointer to struct %T", dst)
+	}
+	sv := reflect.ValueOf(src)
+	if reflect.Pointer == sv.Kind() && !sv.IsNil() {
+		sv = sv.Elem()
+	}
+	if reflect.Struct != sv.Kind() {
+		return fmt.Errorf("structof: CopyFields from non-struct %T", src)
+	}
+
+	var c copier
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c.copyStruct("", dv.Elem(), sv, true)
+}
+
+// copyStruct copies the fields of the struct src into the struct dst,
+// recording the unmatched fields if top is set.
+func (c *copier) copyStruct(path string, dst, src reflect.Value, top bool) error {
+	dstFields, srcFields := cachedCopyFields(dst.Type()), cachedCopyFields(src.Type())
+	for i := range srcFields.list {
+		sf := &srcFields.list[i]
+		j, ok := dstFields.last(sf.name)
+		if ok && dstFields.list[j].method != "" {
+			ok = false
+		}
+		if !ok {
+			if top && c.srcUnmatched != nil {
+				*c.srcUnmatched = append(*c.srcUnmatched, sf.name)
+			}
+			continue
+		}
+		fv, ok := sf.value(src)
+		if !ok {
+			continue
+		}
+		df := &dstFields.list[j]
+		dv, err := fieldByIndexAlloc(dst, df.index)
+		if err != nil {
+			return fmt.Errorf("structof: field %q: %w", joinKey(path, sf.name), err)
+		}
+		if err := c.copyValue(joinKey(path, sf.name), dv, fv); err != nil {
+			return err
+		}
+	}
+
+	if top && c.dstUnmatched != nil {
+		for i := range dstFields.list {
+			df := &dstFields.list[i]
+			if _, ok := srcFields.last(df.name); !ok && df.method == "" {
+				*c.dstUnmatched = append(*c.dstUnmatched, df.name)
+			}
+		}
+	}
+	return nil
+}
+
+// copyValue copies the value src into dst.
+func (c *copier) copyValue(path string, dst, src reflect.Value) error {
+	st, dt := src.Type(), dst.Type()
+	switch {
+	case st.AssignableTo(dt):
+		dst.Set(src)
+		return nil
+	case c.convert && st.ConvertibleTo(dt) && !(reflect.String == dt.Kind() && isInteger(st.Kind())):
+		dst.Set(src.Convert(dt))
+		return nil
+	}
+
+	for reflect.Pointer == src.Kind() {
+		if src.IsNil() {
+			dst.SetZero()
+			return nil
+		}
+		src = src.Elem()
+	}
+	if reflect.Struct == src.Kind() && reflect.Struct == indirectType(dt).Kind() {
+		for reflect.Pointer == dst.Kind() {
+			if dst.IsNil() {
+				dst.Set(reflect.New(dst.Type().Elem()))
+			}
+			dst = dst.Elem()
+		}
+		return c.copyStruct(path, dst, src, false)
+	}
+	return fmt.Errorf("structof: field %q: cannot copy %s into %s", path, st, dt)
+}
+
+var copyFieldsCache typeCache[flatFields]
+
+// cachedCopyFields returns the fields of the struct type t copied by
+// CopyFields, with the fields of its inline structs flattened, the
+// "decodeonly" fields included.
+func cachedCopyFields(t reflect.Type) flatFields {
+	if f, ok := copyFieldsCache.Load(t); ok {
+		return f
+	}
+	f, _ := copyFieldsCache.LoadOrStore(t, newFlatFields(flattenFields(nil, t, true)))
+	return f
+}
+
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// flatFields are the fields of a struct type with the fields of its inline
+// structs flattened, indexed by key, several fields possibly sharing a key.
+type flatFields struct {
+	list   []field
+	byName map[string][]int
+}
+
+// newFlatFields returns the flatFields of list, indexed by key.
+func newFlatFields(list []field) flatFields {
+	byName := make(map[string][]int, len(list))
+	for i := range list {
+		byName[list[i].name] = append(byName[list[i].name], i)
+	}
+	return flatFields{list, byName}
+}
+
+// last returns the index of the last field of key, and whether there is one.
+func (ff flatFields) last(key string) (int, bool) {
+	idx := ff.byName[key]
+	if len(idx) == 0 {
+		return 0, false
+	}
+	return idx[len(idx)-1], true
+}
+
+// flattenFields returns the fields of the struct type t, prefixing their
+// index with index, and with inline struct fields replaced by their fields.
+// The fields with the "decodeonly" option are included if decodeOnly is set.
+// An inline struct of a type already being flattened, which would recur
+// forever, is a field itself.
+func flattenFields(index []int, t reflect.Type, decodeOnly bool) []field {
+	return appendFlatFields(nil, index, t, decodeOnly, make(map[reflect.Type]bool))
+}
+
+func appendFlatFields(list []field, index []int, t reflect.Type, decodeOnly bool, seen map[reflect.Type]bool) []field {
+	seen[t] = true
+	defer delete(seen, t)
+
+	for _, f := range cachedTypeFields(t).list {
+		if f.decodeOnly && !decodeOnly {
+			continue
+		}
+		if f.method != "" && len(index) > 0 {
+			// The method is called on the inline struct.
+			parent, method := fieldValue(index), f.value
+			f.value = func(v reflect.Value) (reflect.Value, bool) {
+				if pv, ok := parent(v); ok {
+					return method(pv)
+				}
+				return reflect.Value{}, false
+			}
+		}
+		f.index = append(append([]int(nil), index...), f.index...)
+		if f.method == "" {
+			f.value = fieldValue(f.index)
+		}
+		if ft := indirectType(f.typ); f.inline && reflect.Struct == ft.Kind() && !seen[ft] {
+			list = appendFlatFields(list, f.index, ft, decodeOnly, seen)
+			continue
+		}
+		list = append(list, f)
+	}
+	return list
+}
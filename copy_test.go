@@ -0,0 +1,93 @@
+package structof
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCopyFields(t *testing.T) {
+	t.Parallel()
+
+	type Level int32
+	type SrcAddress struct {
+		City string `structof:"city"`
+	}
+	type DstAddress struct {
+		City string `structof:"city"`
+		Zip  string `structof:"zip"`
+	}
+	type Audit struct {
+		By string `structof:"by"`
+	}
+	type Src struct {
+		ID      int64       `structof:"id"`
+		Name    string      `structof:"name"`
+		Level   Level       `structof:"level"`
+		Address SrcAddress  `structof:"address"`
+		Home    *SrcAddress `structof:"home"`
+		Audit   Audit       `structof:",inline"`
+		Extra   string      `structof:"extra"`
+	}
+	type Dst struct {
+		UserID   int64       `structof:"id"`
+		Name     string      `structof:"name"`
+		Level    int64       `structof:"level"`
+		Address  *DstAddress `structof:"address"`
+		Home     DstAddress  `structof:"home"`
+		By       string      `structof:"by"`
+		Password string      `structof:"password,decodeonly"`
+	}
+	src := Src{ID: 7, Name: "foo", Level: 2, Address: SrcAddress{"Paris"}, Home: &SrcAddress{"Rome"}, Audit: Audit{"admin"}, Extra: "x"}
+
+	var dst Dst
+	var srcUnmatched, dstUnmatched []string
+	if err := CopyFields(&dst, src, WithConversion(), WithUnmatched(&srcUnmatched, &dstUnmatched)); err != nil {
+		t.Fatal(err)
+	}
+	want := Dst{UserID: 7, Name: "foo", Level: 2, Address: &DstAddress{City: "Paris"}, Home: DstAddress{City: "Rome"}, By: "admin"}
+	if diff := cmp.Diff(want, dst); diff != "" {
+		t.Errorf("CopyFields mismatch (-want +got):\n%s", diff)
+	}
+	if !cmp.Equal([]string{"extra"}, srcUnmatched) || !cmp.Equal([]string{"password"}, dstUnmatched) {
+		t.Errorf("unmatched = %q, %q", srcUnmatched, dstUnmatched)
+	}
+
+	if err := CopyFields(&dst, &src); err == nil {
+		t.Error("CopyFields of Level into int64 without WithConversion should return error")
+	}
+	if err := CopyFields(&struct {
+		Name []byte `structof:"name"`
+	}{}, struct {
+		Name int `structof:"name"`
+	}{}, WithConversion()); err == nil {
+		t.Error("CopyFields of int into []byte should return error")
+	}
+	if err := CopyFields(&struct {
+		Name string `structof:"name"`
+	}{}, struct {
+		Name int `structof:"name"`
+	}{}, WithConversion()); err == nil {
+		t.Error("CopyFields of int into string should return error")
+	}
+	if err := CopyFields(dst, src); err == nil {
+		t.Error("CopyFields into non-pointer should return error")
+	}
+}
+
+func TestCopyFieldsRecursiveInline(t *testing.T) {
+	t.Parallel()
+
+	type R struct {
+		V    int
+		Next *R `structof:",inline"`
+	}
+	src := R{V: 1, Next: &R{V: 2}}
+	var dst R
+	if err := CopyFields(&dst, src); err != nil {
+		t.Fatalf("CopyFields error: %v", err)
+	}
+	if diff := cmp.Diff(src, dst); diff != "" {
+		t.Errorf("CopyFields mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,870 @@
+package structof
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FillStruct fills the struct pointed to by s with the elements of m,
+// reversing FillMap. It returns an error if s is not a non-nil pointer
+// to struct, or a *DecodeFieldError if an element cannot be stored in
+// its field.
+//
+// FillStruct matches the keys of m against the fields of the struct the way
+// FillMap names them, honoring the structof tags and the visibility rules
+// for embedded fields. Keys without a matching field are ignored, and fields
+// without a matching key keep their value; see WithDefaults to set them to
+// a default value instead. Nil embedded pointers leading to a matched field
+// are allocated.
+//
+// An element is stored into its field as follows. A nil element sets the
+// field to its zero value. An element whose type is assignable to the field
+// is stored as is. Otherwise the element is converted according to the
+// field's kind: pointers are allocated and filled with the pointed-to
+// value; structs are filled from a map[string]any element; maps with string
+// keys are filled from maps with string keys; slices and arrays are filled
+// element-wise from slices or arrays; and booleans, numbers and strings are
+// stored from elements of the same kind of value, with numbers converted
+// only if the value is represented exactly in the field's type.
+//
+// Pointer fields, including pointers to pointers, express the optional
+// fields of PATCH requests, where an absent key, a null and an empty value
+// differ: a field whose key is missing keeps its value, a nil element sets
+// it to nil, and any other element, even a zero value, sets it to a
+// pointer to the element, allocated unless the field already points to a
+// value, which is then overwritten.
+//
+// The fields of a struct field with the "inline" option are filled from
+// the keys of m itself, the way FillMap flattens them.
+//
+// A field with the "alias" option is decoded from the first of its name and
+// its aliases found among the keys of m.
+//
+// Durations and times, the types every configuration contains, are parsed
+// from common representations: a time.Duration is stored from a string in
+// the format accepted by time.ParseDuration, and a time.Time from a string
+// in RFC 3339 format or a number of seconds since the Unix epoch, either as
+// a number or in a string.
+//
+// A string is stored into a field of a type implementing
+// encoding.TextUnmarshaler, other than a string type, by its UnmarshalText
+// method, and into a url.URL by url.Parse.
+//
+// An interface field is filled with the element as is, or with a value of
+// the struct type named by the element if the Decoder is configured by
+// WithTypes, or by another element of m if the field has the "typekey"
+// option.
+//
+// The conversions are strict by default: a number with a fractional part is
+// not truncated into an integer field, an integer is not rounded into a
+// floating-point field, such as an int64 above 2^53 into a float64, a
+// float64 is not rounded into a float32 field unless it is the value of
+// the float32's shortest decimal representation, as 0.1 is, a number is not
+// stored into a boolean field, and a string is not parsed into a number or
+// boolean field, an error naming the path of the field and the offending
+// value being returned instead; see WithLenientConversions.
+//
+// The json.Number values, as decoded by a json.Decoder configured by
+// UseNumber, are stored into numeric fields with the same checks as other
+// numbers, and into string fields as their text. Elsewhere, as in interface
+// fields and at any depth of the map[string]any and []any elements, they
+// are replaced by an int64 if they are integers that fit, or else a float64,
+// unless the Decoder is configured by WithKeepJSONNumbers.
+//
+// A field with the "string" option is decoded from the quoted string
+// produced by FillMap: the element is unquoted and parsed according to the
+// field's type, which must be a boolean, number or string.
+func FillStruct(m map[string]any, s any) error {
+	return defaultDecoder.FillStruct(m, s)
+}
+
+// UnmarshalJSON parses the JSON object data and fills the struct pointed to
+// by s with its members, decoding them by json.Unmarshal into a
+// map[string]any filled into s by FillStruct, so that the structof tags of
+// the fields, not their json tags, govern the mapping of the keys. A JSON
+// null member sets its field to its zero value, and the JSON numbers are
+// decoded as json.Number values, so that large integers keep their precision.
+// See Decoder.FillStructFromJSON for decoding with a configuration.
+func UnmarshalJSON(data []byte, s any) error {
+	return defaultDecoder.FillStructFromJSON(data, s)
+}
+
+// FillStructFromStrings is like FillStruct but fills the struct from a map
+// of strings, as the contents of key/value stores, labels and snapshots of
+// environment variables, parsing each string according to the type of its
+// field as Field.SetFromString does: booleans and numbers with the strconv
+// package, durations with time.ParseDuration, types implementing
+// encoding.TextUnmarshaler with their UnmarshalText method, and slices by
+// splitting the string at commas.
+func FillStructFromStrings(m map[string]string, s any) error {
+	return defaultDecoder.FillStructFromStrings(m, s)
+}
+
+// A decodeState decodes a map[string]any into a struct.
+type decodeState struct {
+	dec *Decoder
+
+	// Go path of the field being decoded, and whether it is selected
+	// by WithOnlyFields with all its contents.
+	goPath   string
+	selected bool
+
+	// Whether the string elements are parsed according to the type of
+	// their field, as by FillStructFromStrings.
+	fromStrings bool
+
+	// Errors collected by WithCollectErrors.
+	errs []error
+}
+
+func (d *decodeState) decodeStruct(path string, m map[string]any, v reflect.Value) error {
+	fields := cachedTypeFields(v.Type())
+	for i := range fields.list {
+		f := &fields.list[i]
+		if f.encodeOnly {
+			continue
+		}
+
+		goPath, selected := d.goPath, d.selected
+		if !selected {
+			d.goPath = joinKey(goPath, v.Type().FieldByIndex(f.index).Name)
+			d.selected = d.dec.onlyFields[d.goPath]
+			if !d.selected && !d.dec.onlyParents[d.goPath] {
+				d.goPath = goPath
+				continue
+			}
+		}
+		err := d.decodeField(path, m, v, f)
+		d.goPath, d.selected = goPath, selected
+		if err != nil {
+			if err = d.collect(err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// A DecodeFieldError describes a field that FillStruct cannot decode,
+// so that callers map the failures back to the fields of a request.
+// With WithCollectErrors, the DecodeFieldErrors of the fields are joined;
+// errors.As finds the first one, and the Unwrap method of the joined error
+// returns them all.
+type DecodeFieldError struct {
+	// Path is the dotted path of the keys leading to the element from the
+	// top-level map, with the indexes of the elements of the slices and
+	// arrays in brackets, as in "items[2].price".
+	Path string
+
+	// Key is the key of the field's element in its map, the last key of Path.
+	Key string
+
+	// Value is the offending element, nil if the field has none.
+	Value any
+
+	// TargetType is the type the element is decoded into.
+	TargetType reflect.Type
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *DecodeFieldError) Error() string {
+	return "structof: field " + strconv.Quote(e.Path) + ": " + e.Err.Error()
+}
+
+func (e *DecodeFieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError returns a DecodeFieldError of the element value at path,
+// decoded into the type t, failing with err.
+func fieldError(path string, value any, t reflect.Type, err error) error {
+	key := path
+	for strings.HasSuffix(key, "]") {
+		key = key[:strings.LastIndexByte(key, '[')]
+	}
+	key = key[strings.LastIndexByte(key, '.')+1:]
+	return &DecodeFieldError{Path: path, Key: key, Value: value, TargetType: t, Err: err}
+}
+
+// fieldErrorf is like fieldError with the error formatted by fmt.Errorf.
+func fieldErrorf(path string, value any, t reflect.Type, format string, args ...any) error {
+	return fieldError(path, value, t, fmt.Errorf(format, args...))
+}
+
+// collect records err and returns nil if the Decoder is configured by
+// WithCollectErrors, and returns err otherwise.
+func (d *decodeState) collect(err error) error {
+	if !d.dec.collectErrors {
+		return err
+	}
+	d.errs = append(d.errs, err)
+	return nil
+}
+
+// decodeField fills the field f of v from its element in m.
+func (d *decodeState) decodeField(path string, m map[string]any, v reflect.Value, f *field) error {
+	if f.inline {
+		return d.decodeInline(path, m, v, f)
+	}
+
+	src, key, ok := f.lookup(m)
+	if !ok {
+		if !d.dec.defaults || !f.hasDefault {
+			return nil
+		}
+		fpath := joinKey(path, f.name)
+		fv, err := fieldByIndexAlloc(v, f.index)
+		if err != nil {
+			return fieldError(fpath, nil, f.sf.Type, err)
+		}
+		return d.decodeDefault(fpath, f.defaultValue, fv)
+	}
+
+	fpath := joinKey(path, key)
+	if d.dec.transforms && len(f.transforms) > 0 {
+		x, err := transform(f.transforms, src)
+		if err != nil {
+			return fieldError(fpath, src, f.sf.Type, err)
+		}
+		src = x
+	}
+	fv, err := fieldByIndexAlloc(v, f.index)
+	if err != nil {
+		return fieldError(fpath, src, f.sf.Type, err)
+	}
+	if d.dec.zeroFields {
+		fv.SetZero()
+	}
+	if f.asJSON {
+		return decodeJSON(fpath, src, fv)
+	}
+	if name, ok := m[f.typeKey].(string); f.typeKey != "" && ok && src != nil {
+		return d.decodeNamed(fpath, name, src, fv)
+	}
+	return d.decodeValue(fpath, src, fv, f.quoted)
+}
+
+// decodeInline fills the inline struct field f of v from the keys of m
+// itself, reversing the flattening of the encoding. A nil pointer to the
+// inline struct is allocated only if m has a key for it, or the Decoder
+// has defaults for it.
+func (d *decodeState) decodeInline(path string, m map[string]any, v reflect.Value, f *field) error {
+	if !hasInlineKeys(m, f.typ) && !(d.dec.defaults && hasDefaults(f.typ)) {
+		return nil
+	}
+
+	fv, err := fieldByIndexAlloc(v, f.index)
+	if err != nil {
+		return fieldError(joinKey(path, f.name), nil, f.sf.Type, err)
+	}
+	for reflect.Pointer == fv.Kind() {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	return d.decodeStruct(path, m, fv)
+}
+
+// hasInlineKeys reports whether m has a key for a field of the struct type t,
+// including the fields of its own inline fields.
+func hasInlineKeys(m map[string]any, t reflect.Type) bool {
+	fields := cachedTypeFields(t)
+	for i := range fields.list {
+		f := &fields.list[i]
+		if f.encodeOnly {
+			continue
+		}
+		if f.inline {
+			if hasInlineKeys(m, f.typ) {
+				return true
+			}
+			continue
+		}
+		if _, _, ok := f.lookup(m); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeValue stores src into v, converting it as documented by FillStruct.
+func (d *decodeState) decodeValue(path string, src any, v reflect.Value, quoted bool) error {
+	if src == nil {
+		v.SetZero()
+		return nil
+	}
+
+	if quoted {
+		return d.decodeQuoted(path, src, v)
+	}
+
+	if reflect.Interface == v.Kind() && d.dec.typeKey != "" {
+		if ok, err := d.decodeTyped(path, src, v); ok {
+			return err
+		}
+	}
+
+	if isBigType(v.Type()) && !reflect.TypeOf(src).AssignableTo(v.Type()) {
+		if err := decodeBig(v, src); err != nil {
+			return fieldError(path, src, v.Type(), err)
+		}
+		return nil
+	}
+
+	if !d.dec.keepNumbers || reflect.Interface != v.Kind() {
+		// The big numbers parse the text of a json.Number exactly.
+		if n, ok := src.(json.Number); ok && reflect.String != v.Kind() && !isBigType(indirectType(v.Type())) {
+			x, err := numberValue(n)
+			if err != nil {
+				return fieldError(path, src, v.Type(), err)
+			}
+			src = x
+		}
+	}
+	if !d.dec.keepNumbers && holdsInterfaces(v.Type()) && hasNumbers(src) {
+		var err error
+		if src, err = convertNumbers(src); err != nil {
+			return fieldError(path, src, v.Type(), err)
+		}
+	}
+
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(v.Type()) {
+		v.Set(sv)
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		if s, ok := src.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fieldError(path, src, v.Type(), err)
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+	case timeType:
+		t, err := parseTime(sv)
+		if err != nil {
+			return fieldError(path, src, v.Type(), err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if ok, err := d.decodeProto(path, src, v); ok {
+		return err
+	}
+	if s, ok := src.(string); ok && reflect.String != v.Kind() {
+		if ok, err := unmarshalString(v, s); ok {
+			if err != nil {
+				return fieldError(path, src, v.Type(), err)
+			}
+			return nil
+		}
+	}
+
+	if s, ok := src.(string); ok && d.fromStrings {
+		if err := parseString(v, s); err != nil {
+			return fieldError(path, src, v.Type(), err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(path, src, v.Elem(), false)
+	case reflect.Struct:
+		m, ok := src.(map[string]any)
+		if !ok {
+			break
+		}
+		return d.decodeStruct(path, m, v)
+	case reflect.Map:
+		if reflect.Map != sv.Kind() || reflect.String != sv.Type().Key().Kind() || reflect.String != v.Type().Key().Kind() {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), sv.Len()))
+		}
+		elemType := v.Type().Elem()
+		for it := sv.MapRange(); it.Next(); {
+			key := it.Key().String()
+			elem := reflect.New(elemType).Elem()
+			if err := d.decodeValue(joinKey(path, key), it.Value().Interface(), elem, false); err != nil {
+				if err = d.collect(err); err != nil {
+					return err
+				}
+				continue
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		if reflect.Slice != sv.Kind() && reflect.Array != sv.Kind() {
+			break
+		}
+		n := sv.Len()
+		if reflect.Slice == v.Kind() {
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+		} else if n > v.Len() {
+			return fieldErrorf(path, src, v.Type(), "%d elements do not fit in %s", n, v.Type())
+		}
+		for i := 0; i < v.Len(); i++ {
+			if i >= n {
+				v.Index(i).SetZero()
+				continue
+			}
+			if err := d.decodeValue(joinIndex(path, i), sv.Index(i).Interface(), v.Index(i), false); err != nil {
+				if err = d.collect(err); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		if d.dec.lenient {
+			if ok, err := setLenient(v, sv); ok {
+				if err != nil {
+					return fieldError(path, src, v.Type(), err)
+				}
+				return nil
+			}
+		}
+		if err := setScalar(v, sv); err != nil {
+			return fieldError(path, src, v.Type(), err)
+		}
+		return nil
+	}
+	return fieldErrorf(path, src, v.Type(), "cannot decode %T into %s", src, v.Type())
+}
+
+// numberValue returns the value of the json.Number n,
+// an int64 if n is an integer that fits, and a float64 otherwise.
+func numberValue(n json.Number) (any, error) {
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return u, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", n)
+	}
+	return f, nil
+}
+
+// holdsInterfaces reports whether t is an interface type,
+// or a map or slice type of interface elements.
+func holdsInterfaces(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice:
+		t = t.Elem()
+	}
+	return reflect.Interface == t.Kind()
+}
+
+// hasNumbers reports whether the map[string]any or []any x holds a
+// json.Number, at any depth.
+func hasNumbers(x any) bool {
+	switch x := x.(type) {
+	case map[string]any:
+		for _, elem := range x {
+			if _, ok := elem.(json.Number); ok || hasNumbers(elem) {
+				return true
+			}
+		}
+	case []any:
+		for _, elem := range x {
+			if _, ok := elem.(json.Number); ok || hasNumbers(elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convertNumbers returns a copy of x with the json.Number values it holds,
+// at any depth, replaced by their value as by numberValue.
+func convertNumbers(x any) (any, error) {
+	var err error
+	switch x := x.(type) {
+	case json.Number:
+		return numberValue(x)
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for key, elem := range x {
+			if m[key], err = convertNumbers(elem); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []any:
+		a := make([]any, len(x))
+		for i, elem := range x {
+			if a[i], err = convertNumbers(elem); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	return x, nil
+}
+
+// decodeJSON stores into v the value encoded by the "json" option in src.
+func decodeJSON(path string, src any, v reflect.Value) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		v.SetZero()
+		return nil
+	case string:
+		data = []byte(src)
+	case []byte:
+		data = src
+	default:
+		return fieldErrorf(path, src, v.Type(), "json option expects a string, got %T", src)
+	}
+	if err := json.Unmarshal(data, v.Addr().Interface()); err != nil {
+		return fieldErrorf(path, src, v.Type(), "json option: %w", err)
+	}
+	return nil
+}
+
+// decodeQuoted stores into v the value quoted in src by the "string" option.
+func (d *decodeState) decodeQuoted(path string, src any, v reflect.Value) error {
+	s, ok := src.(string)
+	if !ok {
+		return fieldErrorf(path, src, v.Type(), "string option expects a string, got %T", src)
+	}
+	s, err := strconv.Unquote(s)
+	if err != nil {
+		return fieldErrorf(path, src, v.Type(), "string option: %w", err)
+	}
+
+	for reflect.Pointer == v.Kind() {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if err := parseScalar(v, s); err != nil {
+		return fieldErrorf(path, src, v.Type(), "string option: %w", err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// parseTime returns the time represented by sv, either a string in RFC 3339
+// format or a number of seconds since the Unix epoch, possibly in a string.
+func parseTime(sv reflect.Value) (time.Time, error) {
+	switch {
+	case reflect.String == sv.Kind():
+		s := sv.String()
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err == nil {
+			return t, nil
+		}
+		if f, ferr := strconv.ParseFloat(s, 64); ferr == nil {
+			return unixTime(f), nil
+		}
+		return time.Time{}, err
+	case sv.CanInt():
+		return time.Unix(sv.Int(), 0), nil
+	case sv.CanUint():
+		return time.Unix(int64(sv.Uint()), 0), nil
+	case sv.CanFloat():
+		return unixTime(sv.Float()), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot decode %s into time.Time", sv.Type())
+}
+
+// unixTime returns the time f seconds after the Unix epoch.
+func unixTime(f float64) time.Time {
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// setScalar stores the boolean, number or string sv into v,
+// which is of one of these kinds too.
+func setScalar(v, sv reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if reflect.Bool == sv.Kind() {
+			v.SetBool(sv.Bool())
+			return nil
+		}
+	case reflect.String:
+		if reflect.String == sv.Kind() {
+			v.SetString(sv.String())
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case sv.CanInt():
+			n := sv.Int()
+			if v.OverflowInt(n) {
+				return fmt.Errorf("value %d overflows %s", n, v.Type())
+			}
+			v.SetInt(n)
+			return nil
+		case sv.CanUint():
+			n := sv.Uint()
+			if n > math.MaxInt64 || v.OverflowInt(int64(n)) {
+				return fmt.Errorf("value %d overflows %s", n, v.Type())
+			}
+			v.SetInt(int64(n))
+			return nil
+		case sv.CanFloat():
+			f := sv.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || v.OverflowInt(int64(f)) {
+				return fmt.Errorf("value %v is not representable in %s", f, v.Type())
+			}
+			v.SetInt(int64(f))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch {
+		case sv.CanInt():
+			n := sv.Int()
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return fmt.Errorf("value %d overflows %s", n, v.Type())
+			}
+			v.SetUint(uint64(n))
+			return nil
+		case sv.CanUint():
+			n := sv.Uint()
+			if v.OverflowUint(n) {
+				return fmt.Errorf("value %d overflows %s", n, v.Type())
+			}
+			v.SetUint(n)
+			return nil
+		case sv.CanFloat():
+			f := sv.Float()
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || v.OverflowUint(uint64(f)) {
+				return fmt.Errorf("value %v is not representable in %s", f, v.Type())
+			}
+			v.SetUint(uint64(f))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case sv.CanInt():
+			n := sv.Int()
+			if f := roundFloat(v, float64(n)); f < math.MinInt64 || f >= math.MaxInt64 || int64(f) != n {
+				return fmt.Errorf("value %d is not representable in %s", n, v.Type())
+			}
+			v.SetFloat(float64(n))
+			return nil
+		case sv.CanUint():
+			n := sv.Uint()
+			if f := roundFloat(v, float64(n)); f >= math.MaxUint64 || uint64(f) != n {
+				return fmt.Errorf("value %d is not representable in %s", n, v.Type())
+			}
+			v.SetFloat(float64(n))
+			return nil
+		case sv.CanFloat():
+			f := sv.Float()
+			if v.OverflowFloat(f) {
+				return fmt.Errorf("value %v overflows %s", f, v.Type())
+			}
+			if reflect.Float32 == v.Kind() && !exactFloat32(f) {
+				return fmt.Errorf("value %v is not representable in %s", f, v.Type())
+			}
+			v.SetFloat(f)
+			return nil
+		}
+	}
+	if reflect.String == sv.Kind() {
+		return fmt.Errorf("cannot decode %s %q into %s", sv.Type(), sv, v.Type())
+	}
+	if sv.Kind() <= reflect.Complex128 {
+		return fmt.Errorf("cannot decode %s %v into %s", sv.Type(), sv, v.Type())
+	}
+	return fmt.Errorf("cannot decode %s into %s", sv.Type(), v.Type())
+}
+
+// roundFloat returns f rounded to the precision of the float field v.
+func roundFloat(v reflect.Value, f float64) float64 {
+	if reflect.Float32 == v.Kind() {
+		return float64(float32(f))
+	}
+	return f
+}
+
+// exactFloat32 reports whether f is stored into a float32 without losing
+// precision: whether f is the float32 rounding of f, or the float64 value
+// of the shortest decimal representation of that rounding, as for 0.1 read
+// from a JSON document, whose float32 is printed as 0.1 too.
+func exactFloat32(f float64) bool {
+	r := float32(f)
+	if float64(r) == f || math.IsNaN(f) {
+		return true
+	}
+	d, err := strconv.ParseFloat(strconv.FormatFloat(float64(r), 'g', -1, 32), 64)
+	return err == nil && d == f
+}
+
+// setLenient stores the scalar sv into the scalar v with the conversions
+// allowed by WithLenientConversions, reporting whether one applies.
+func setLenient(v, sv reflect.Value) (bool, error) {
+	switch {
+	case reflect.String == sv.Kind() && reflect.String != v.Kind():
+		s := strings.TrimSpace(sv.String())
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if f, err := strconv.ParseFloat(s, 64); err == nil && strings.ContainsAny(s, ".eE") {
+				// A string such as "1.5" or "1e3" parses as a float.
+				return setLenient(v, reflect.ValueOf(f))
+			}
+		}
+		return true, parseScalar(v, s)
+	case reflect.Bool == v.Kind() && sv.CanInt():
+		v.SetBool(sv.Int() != 0)
+	case reflect.Bool == v.Kind() && sv.CanUint():
+		v.SetBool(sv.Uint() != 0)
+	case reflect.Bool == v.Kind() && sv.CanFloat():
+		v.SetBool(sv.Float() != 0)
+	case sv.CanFloat() && (v.CanInt() || v.CanUint()):
+		return true, setScalar(v, reflect.ValueOf(math.Trunc(sv.Float())))
+	case v.CanFloat() && (sv.CanInt() || sv.CanUint() || sv.CanFloat()):
+		// The number is rounded to the precision of v.
+		f := sv.Convert(reflect.TypeOf(0.0)).Float()
+		if v.OverflowFloat(f) {
+			return true, fmt.Errorf("value %v overflows %s", f, v.Type())
+		}
+		v.SetFloat(f)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseScalar parses s into v according to v's kind,
+// which must be a boolean, number or string.
+func parseScalar(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		v.SetString(s)
+	default:
+		return fmt.Errorf("cannot parse string into %s", v.Type())
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// parseString parses s into the settable v according to v's type,
+// as documented by Field.SetFromString.
+func parseString(v reflect.Value, s string) error {
+	if ok, err := unmarshalString(v, s); ok {
+		return err
+	}
+
+	switch {
+	case durationType == v.Type():
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case reflect.Pointer == v.Kind():
+		p := reflect.New(v.Type().Elem())
+		if err := parseString(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	case reflect.Slice == v.Kind() && reflect.Uint8 == v.Type().Elem().Kind():
+		v.SetBytes([]byte(s))
+		return nil
+	case reflect.Slice == v.Kind():
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		sv := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := parseString(sv.Index(i), strings.TrimSpace(part)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		v.Set(sv)
+		return nil
+	}
+	return parseScalar(v, s)
+}
+
+// fieldByIndexAlloc returns the nested field of v at index,
+// allocating the nil embedded pointers on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && reflect.Pointer == v.Kind() {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func joinIndex(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
@@ -0,0 +1,540 @@
+package structof
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFillStruct(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			Country string `structof:"country"`
+		}
+		Base struct {
+			ID uint16 `structof:"id"`
+		}
+		Person struct {
+			*Base
+			Name      string             `structof:"name"`
+			Age       int                `structof:"age"`
+			Score     float32            `structof:"score"`
+			Born      time.Time          `structof:"born"`
+			Home      *Address           `structof:"home"`
+			Addresses []Address          `structof:"addresses"`
+			Labels    map[string]string  `structof:"labels"`
+			Offices   map[string]Address `structof:"offices"`
+			Pair      [2]int             `structof:"pair"`
+			Any       any                `structof:"any"`
+		}
+	)
+
+	born := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	want := Person{
+		Base:      &Base{23},
+		Name:      "foobar",
+		Age:       46,
+		Score:     1.5,
+		Born:      born,
+		Home:      &Address{"Turkey"},
+		Addresses: []Address{{"England"}, {"Italy"}},
+		Labels:    map[string]string{"foo": "bar"},
+		Offices:   map[string]Address{"hq": {"France"}},
+		Pair:      [2]int{1, 2},
+		Any:       []int{3},
+	}
+
+	var got Person
+	if err := FillStruct(MakeMap(want), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	// Numbers of other types are converted if represented exactly.
+	got = Person{}
+	m := map[string]any{"id": 23.0, "age": int8(46), "score": 2, "unknown": true}
+	if err := FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Person{Base: &Base{23}, Age: 46, Score: 2}); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFillStructErrors(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		A int      `structof:"a"`
+		B uint8    `structof:"b"`
+		C []string `structof:"c"`
+		D struct {
+			E bool `structof:"e"`
+		} `structof:"d"`
+	}
+
+	tests := []map[string]any{
+		{"a": "23"},
+		{"a": 1.5},
+		{"b": -1},
+		{"b": 256},
+		{"c": []any{"x", 1}},
+		{"d": map[string]any{"e": "true"}},
+		{"d": 1},
+	}
+	for _, m := range tests {
+		var s S
+		if err := FillStruct(m, &s); err == nil {
+			t.Errorf("FillStruct(%v) should return error", m)
+		}
+	}
+
+	if err := FillStruct(map[string]any{}, S{}); err == nil {
+		t.Error("FillStruct of non-pointer should return error")
+	}
+}
+
+func TestFillStructStringOption(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Int64  int64   `structof:",string"`
+		Uint   uint    `structof:",string"`
+		Float  float64 `structof:",string"`
+		Bool   bool    `structof:",string"`
+		String string  `structof:",string"`
+		Ptr    *int    `structof:",string"`
+	}
+
+	n := 46
+	want := S{-23, 23, 1.5, true, "foobar", &n}
+	m := MakeMap(want)
+	if m["Int64"] != `"-23"` {
+		t.Fatalf("MakeMap quoted Int64 got %#v", m["Int64"])
+	}
+
+	var got S
+	if err := FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	for _, m := range []map[string]any{
+		{"Int64": -23},
+		{"Int64": "-23"},
+		{"Bool": `"yes"`},
+	} {
+		if err := FillStruct(m, &got); err == nil {
+			t.Errorf("FillStruct(%v) should return error", m)
+		}
+	}
+}
+
+func TestFillStructDurationTime(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Timeout time.Duration  `structof:"timeout"`
+		Retry   *time.Duration `structof:"retry"`
+		At      time.Time      `structof:"at"`
+	}
+
+	at := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		m    map[string]any
+		want S
+	}{
+		{map[string]any{"timeout": "30s"}, S{Timeout: 30 * time.Second}},
+		{map[string]any{"timeout": int64(time.Minute)}, S{Timeout: time.Minute}},
+		{map[string]any{"at": "2009-11-10T23:00:00Z"}, S{At: at}},
+		{map[string]any{"at": at.Unix()}, S{At: time.Unix(at.Unix(), 0)}},
+		{map[string]any{"at": "1257894000.5"}, S{At: time.Unix(at.Unix(), 5e8)}},
+	}
+	for _, tt := range tests {
+		var s S
+		if err := FillStruct(tt.m, &s); err != nil {
+			t.Errorf("FillStruct(%v): %v", tt.m, err)
+			continue
+		}
+		if !s.At.Equal(tt.want.At) || s.Timeout != tt.want.Timeout {
+			t.Errorf("FillStruct(%v) = %+v, want %+v", tt.m, s, tt.want)
+		}
+	}
+
+	var s S
+	if err := FillStruct(map[string]any{"retry": "1m30s"}, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Retry == nil || *s.Retry != 90*time.Second {
+		t.Errorf("FillStruct retry got %v", s.Retry)
+	}
+
+	for _, m := range []map[string]any{
+		{"timeout": "soon"},
+		{"at": "yesterday"},
+		{"at": true},
+	} {
+		if err := FillStruct(m, &s); err == nil {
+			t.Errorf("FillStruct(%v) should return error", m)
+		}
+	}
+}
+
+func TestFillStructFromStrings(t *testing.T) {
+	t.Parallel()
+
+	type Limits struct {
+		Burst uint8 `structof:"burst"`
+	}
+	type S struct {
+		Name    string        `structof:"name"`
+		Port    int           `structof:"port,alias=PORT"`
+		Debug   bool          `structof:"debug"`
+		Ratio   *float64      `structof:"ratio"`
+		Timeout time.Duration `structof:"timeout"`
+		At      time.Time     `structof:"at"`
+		Hosts   []string      `structof:"hosts"`
+		Ports   []int         `structof:"ports"`
+		Limits  `structof:",inline"`
+	}
+
+	m := map[string]string{
+		"name":    "api",
+		"PORT":    "8080",
+		"debug":   "true",
+		"ratio":   "0.5",
+		"timeout": "1m30s",
+		"at":      "2009-11-10T23:00:00Z",
+		"hosts":   "a.example.com, b.example.com",
+		"ports":   "80,443",
+		"burst":   "10",
+		"unknown": "x",
+	}
+	var s S
+	if err := FillStructFromStrings(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	ratio := 0.5
+	want := S{
+		Name: "api", Port: 8080, Debug: true, Ratio: &ratio, Timeout: 90 * time.Second,
+		At:    time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+		Hosts: []string{"a.example.com", "b.example.com"}, Ports: []int{80, 443},
+		Limits: Limits{10},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("FillStructFromStrings mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, m := range []map[string]string{
+		{"port": "http"},
+		{"burst": "300"},
+		{"ports": "80,x"},
+		{"debug": "maybe"},
+	} {
+		if err := FillStructFromStrings(m, &s); err == nil {
+			t.Errorf("FillStructFromStrings(%v) should return error", m)
+		}
+	}
+	if err := FillStructFromStrings(m, s); err == nil {
+		t.Error("FillStructFromStrings of non-pointer should return error")
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `structof:"city" json:"town"`
+	}
+	type S struct {
+		ID      int           `structof:"id" json:"ID"`
+		Name    string        `structof:"user_name" json:"name"`
+		Timeout time.Duration `structof:"timeout"`
+		Address *Address      `structof:"address"`
+		Tags    []string      `structof:"tags"`
+	}
+	data := []byte(`{"id": 23, "user_name": "foo", "name": "bar", "timeout": "1s",
+		"address": {"city": "Paris", "town": "Lyon"}, "tags": ["a", "b"]}`)
+	var s S
+	if err := UnmarshalJSON(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	want := S{23, "foo", time.Second, &Address{"Paris"}, []string{"a", "b"}}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("UnmarshalJSON mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, data := range []string{`[1]`, `{"id": 1.5}`, `{"id": `, `{"tags": "a"}`} {
+		if err := UnmarshalJSON([]byte(data), &s); err == nil {
+			t.Errorf("UnmarshalJSON(%s) should return error", data)
+		}
+	}
+}
+
+func TestFillStructJSONNumber(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		ID    int64          `structof:"id"`
+		Count uint8          `structof:"count"`
+		Ratio float64        `structof:"ratio"`
+		Text  string         `structof:"text"`
+		Any   any            `structof:"any"`
+		Doc   map[string]any `structof:"doc"`
+		List  []any          `structof:"list"`
+	}
+	m := map[string]any{
+		"id":    json.Number("9007199254740993"),
+		"count": json.Number("255"),
+		"ratio": json.Number("1e-3"),
+		"text":  json.Number("42"),
+		"any":   json.Number("1.5"),
+		"doc":   map[string]any{"n": json.Number("7"), "a": []any{json.Number("18446744073709551615")}},
+		"list":  []any{json.Number("1"), "x"},
+	}
+	var s S
+	if err := FillStruct(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	want := S{
+		ID: 9007199254740993, Count: 255, Ratio: 1e-3, Text: "42", Any: 1.5,
+		Doc:  map[string]any{"n": int64(7), "a": []any{uint64(18446744073709551615)}},
+		List: []any{int64(1), "x"},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("FillStruct mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, m := range []map[string]any{
+		{"count": json.Number("256")},
+		{"id": json.Number("1.5")},
+		{"id": json.Number("x")},
+	} {
+		if err := FillStruct(m, &s); err == nil {
+			t.Errorf("FillStruct(%v) should return error", m)
+		}
+	}
+
+	s = S{}
+	if err := NewDecoder(WithKeepJSONNumbers()).FillStruct(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	want = S{
+		ID: 9007199254740993, Count: 255, Ratio: 1e-3, Text: "42", Any: json.Number("1.5"),
+		Doc:  map[string]any{"n": json.Number("7"), "a": []any{json.Number("18446744073709551615")}},
+		List: []any{json.Number("1"), "x"},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("FillStruct with WithKeepJSONNumbers mismatch (-want +got):\n%s", diff)
+	}
+
+	s = S{}
+	if err := UnmarshalJSON([]byte(`{"id": 9007199254740993}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 9007199254740993 {
+		t.Errorf("UnmarshalJSON id = %d, want 9007199254740993", s.ID)
+	}
+	if err := UnmarshalJSON([]byte(`{"id": 1} {}`), &s); err == nil {
+		t.Error("UnmarshalJSON with trailing data should return error")
+	}
+}
+
+func TestFillStructPointers(t *testing.T) {
+	t.Parallel()
+
+	type Patch struct {
+		Name  *string        `structof:"name"`
+		Age   *int           `structof:"age"`
+		Admin *bool          `structof:"admin"`
+		Score **float64      `structof:"score"`
+		Code  *int           `structof:"code,string"`
+		Tags  *[]string      `structof:"tags"`
+		Wait  *time.Duration `structof:"wait"`
+	}
+
+	age := 30
+	p := Patch{Age: &age, Admin: new(bool)}
+	m := map[string]any{
+		"name":  "",
+		"admin": nil,
+		"score": 1.5,
+		"code":  `"7"`,
+		"tags":  []any{},
+		"wait":  "1s",
+	}
+	if err := FillStruct(m, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Name == nil || *p.Name != "" {
+		t.Errorf("empty name = %v, want pointer to empty string", p.Name)
+	}
+	if p.Age != &age || age != 30 {
+		t.Errorf("absent age = %v, want unchanged", p.Age)
+	}
+	if p.Admin != nil {
+		t.Errorf("null admin = %v, want nil", p.Admin)
+	}
+	if p.Score == nil || *p.Score == nil || **p.Score != 1.5 {
+		t.Errorf("score = %v, want pointer to pointer to 1.5", p.Score)
+	}
+	if p.Code == nil || *p.Code != 7 {
+		t.Errorf("code = %v, want pointer to 7", p.Code)
+	}
+	if p.Tags == nil || *p.Tags == nil || len(*p.Tags) != 0 {
+		t.Errorf("tags = %v, want pointer to empty slice", p.Tags)
+	}
+	if p.Wait == nil || *p.Wait != time.Second {
+		t.Errorf("wait = %v, want pointer to 1s", p.Wait)
+	}
+
+	if err := FillStruct(map[string]any{"age": 31, "score": nil}, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Age != &age || age != 31 {
+		t.Errorf("age = %v, want existing pointer set to 31", p.Age)
+	}
+	if p.Score != nil {
+		t.Errorf("null score = %v, want nil", p.Score)
+	}
+}
+
+func TestFillStructInline(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Audit struct {
+			CreatedBy string `structof:"created_by"`
+		}
+		Meta struct {
+			Version int    `structof:"version"`
+			Audit   *Audit `structof:",squash"`
+		}
+		Doc struct {
+			Title string `structof:"title"`
+			Meta  *Meta  `structof:",inline"`
+		}
+	)
+
+	want := Doc{Title: "foobar", Meta: &Meta{2, &Audit{"gopher"}}}
+	m := MakeMap(want)
+	wantMap := map[string]any{"title": "foobar", "version": 2, "created_by": "gopher"}
+	if !cmp.Equal(wantMap, m) {
+		t.Fatal(cmp.Diff(wantMap, m))
+	}
+
+	var got Doc
+	if err := FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	got = Doc{}
+	if err := FillStruct(map[string]any{"title": "baz"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Meta != nil {
+		t.Errorf("inline pointer without keys should stay nil, got %+v", got.Meta)
+	}
+}
+
+func TestFillStructAlias(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		ID   int    `structof:"id,alias=user_id|uid"`
+		Name string `structof:"name,omitempty,alias=login"`
+	}
+
+	tests := []struct {
+		m    map[string]any
+		want S
+	}{
+		{map[string]any{"id": 1, "uid": 2}, S{ID: 1}},
+		{map[string]any{"user_id": 2, "uid": 3}, S{ID: 2}},
+		{map[string]any{"uid": 3, "login": "foobar"}, S{ID: 3, Name: "foobar"}},
+	}
+	for _, tt := range tests {
+		var s S
+		if err := FillStruct(tt.m, &s); err != nil {
+			t.Errorf("FillStruct(%v): %v", tt.m, err)
+			continue
+		}
+		if tt.want != s {
+			t.Errorf("FillStruct(%v) = %+v, want %+v", tt.m, s, tt.want)
+		}
+	}
+
+	m := MakeMap(S{ID: 1})
+	if want := map[string]any{"id": 1}; !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+}
+
+func TestFillStructJSON(t *testing.T) {
+	t.Parallel()
+
+	type Doc struct {
+		A int `json:"a"`
+	}
+	type S struct {
+		Doc  Doc  `structof:"doc,json"`
+		Ptr  *Doc `structof:"ptr,json"`
+		Null *Doc `structof:"null,json"`
+	}
+
+	s := S{Null: &Doc{}}
+	m := map[string]any{"doc": `{"a":1}`, "ptr": []byte(`{"a":2}`), "null": "null"}
+	if err := FillStruct(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	if want := (S{Doc: Doc{A: 1}, Ptr: &Doc{A: 2}}); !cmp.Equal(want, s) {
+		t.Error(cmp.Diff(want, s))
+	}
+
+	if err := FillStruct(map[string]any{"doc": "{"}, &s); err == nil {
+		t.Error("FillStruct of invalid JSON: got nil error")
+	}
+	if err := FillStruct(map[string]any{"doc": 1}, &s); err == nil {
+		t.Error("FillStruct of non-string JSON: got nil error")
+	}
+}
+
+func TestEncodeOnlyDecodeOnly(t *testing.T) {
+	t.Parallel()
+
+	type User struct {
+		Name     string `structof:"name"`
+		Password string `structof:"password,decodeonly"`
+		Created  int64  `structof:"created,encodeonly"`
+	}
+
+	var u User
+	in := map[string]any{"name": "foo", "password": "hunter2", "created": int64(1)}
+	if err := FillStruct(in, &u); err != nil {
+		t.Fatal(err)
+	}
+	if want := (User{Name: "foo", Password: "hunter2"}); want != u {
+		t.Errorf("FillStruct = %+v, want %+v", u, want)
+	}
+
+	u.Created = 2
+	m := MakeMap(u)
+	if want := map[string]any{"name": "foo", "created": int64(2)}; !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	if _, ok := MakeLazyMap(u).Get("password"); ok {
+		t.Error(`LazyMap Get("password"): found decodeonly field`)
+	}
+}
@@ -0,0 +1,338 @@
+package structof
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// A Decoder decodes maps into structs like FillStruct, with a configuration
+// given by DecoderOptions.
+// A Decoder is safe for concurrent use by multiple goroutines.
+type Decoder struct {
+	defaults   bool
+	zeroStruct bool
+	zeroFields bool
+	transforms bool
+
+	// Whether the errors of the fields are collected instead of stopping
+	// the decoding, set by WithCollectErrors.
+	collectErrors bool
+
+	// Whether the scalars are converted leniently,
+	// set by WithLenientConversions.
+	lenient bool
+
+	// Whether the json.Number values are kept in interface fields,
+	// set by WithKeepJSONNumbers.
+	keepNumbers bool
+
+	// Go paths of the fields selected by WithOnlyFields,
+	// and of the struct fields containing them.
+	onlyFields  map[string]bool
+	onlyParents map[string]bool
+
+	// Key naming the type of the maps decoded into interfaces, and the
+	// types by name, set by WithTypes.
+	typeKey string
+	types   map[string]reflect.Type
+
+	// Lookup of the environment variables of LoadConfig, set by WithLookupEnv.
+	lookupEnv func(key string) (string, bool)
+}
+
+// A DecoderOption configures a Decoder.
+type DecoderOption func(*Decoder)
+
+// defaultDecoder is the Decoder used by the package functions.
+var defaultDecoder = NewDecoder()
+
+// NewDecoder returns a new Decoder configured by opts.
+func NewDecoder(opts ...DecoderOption) *Decoder {
+	dec := &Decoder{}
+	for _, opt := range opts {
+		opt(dec)
+	}
+	return dec
+}
+
+// FillStruct fills the struct pointed to by s with the elements of m,
+// like the package function FillStruct but configured by the Decoder's options.
+func (dec *Decoder) FillStruct(m map[string]any, s any) error {
+	return dec.fillStruct("FillStruct", m, s, false)
+}
+
+// FillStructFromStrings is like the FillStructFromStrings function but
+// decodes with dec's configuration.
+func (dec *Decoder) FillStructFromStrings(m map[string]string, s any) error {
+	src := make(map[string]any, len(m))
+	for key, elem := range m {
+		src[key] = elem
+	}
+	return dec.fillStruct("FillStructFromStrings", src, s, true)
+}
+
+// FillStructFromJSON is like the UnmarshalJSON function but decodes with
+// dec's configuration. It is not named UnmarshalJSON, which would make the
+// Decoder a json.Unmarshaler.
+func (dec *Decoder) FillStructFromJSON(data []byte, s any) error {
+	var m map[string]any
+	jd := json.NewDecoder(bytes.NewReader(data))
+	jd.UseNumber()
+	if err := jd.Decode(&m); err != nil {
+		return fmt.Errorf("structof: FillStructFromJSON: %w", err)
+	}
+	if _, err := jd.Token(); err != io.EOF {
+		return errors.New("structof: FillStructFromJSON: invalid data after top-level value")
+	}
+	return dec.fillStruct("FillStructFromJSON", m, s, false)
+}
+
+// fillStruct fills the struct pointed to by s with the elements of m,
+// parsing their strings if fromStrings is set; fn names the caller in errors.
+func (dec *Decoder) fillStruct(fn string, m map[string]any, s any, fromStrings bool) error {
+	v := reflect.ValueOf(s)
+	if reflect.Pointer != v.Kind() || v.IsNil() || reflect.Struct != v.Type().Elem().Kind() {
+		return fmt.Errorf("structof: %s of non-pointer to struct %T", fn, s)
+	}
+
+	if dec.zeroStruct {
+		v.Elem().SetZero()
+	}
+	d := decodeState{dec: dec, selected: dec.onlyFields == nil, fromStrings: fromStrings}
+	if err := d.decodeStruct("", m, v.Elem()); err != nil {
+		return err
+	}
+	return errors.Join(d.errs...)
+}
+
+// WithDefaults configures the Decoder to set the fields whose key is missing
+// from the map to the value of their "default" tag option, instead of leaving
+// them unchanged. A key present in the map, even with a zero or nil element,
+// always takes precedence over the default.
+//
+// The default value is parsed according to the field's type, which must be a
+// boolean, number, string, time.Duration or time.Time, or a pointer to one of
+// these, the pointer being allocated. Since tag options are separated by
+// commas, a default value cannot contain a comma:
+//
+//	Port    int           `structof:"port,default=8080"`
+//	Timeout time.Duration `structof:"timeout,default=30s"`
+//
+// Defaults apply to the fields of every struct the Decoder fills, including
+// the fields of inline structs.
+func WithDefaults() DecoderOption {
+	return func(dec *Decoder) {
+		dec.defaults = true
+	}
+}
+
+// WithZeroStruct configures the Decoder to set the struct to its zero value
+// before filling it, so that a struct reused across decodes does not keep
+// the values of the previous map in the fields missing from the next one.
+// Defaults set by WithDefaults are then applied to the zeroed struct.
+func WithZeroStruct() DecoderOption {
+	return func(dec *Decoder) {
+		dec.zeroStruct = true
+	}
+}
+
+// WithZeroFields configures the Decoder to set each field with a matching key
+// to its zero value before decoding the element into it, so that the maps,
+// slices, pointed-to values and structs of the field are replaced instead of
+// being merged with the decoded element. The fields without a matching key
+// are left unchanged.
+func WithZeroFields() DecoderOption {
+	return func(dec *Decoder) {
+		dec.zeroFields = true
+	}
+}
+
+// WithCollectErrors configures the Decoder to go on decoding the other
+// fields when a field cannot be decoded, and to return the errors of all the
+// fields that cannot, joined by errors.Join in the order of the fields, so
+// that an API reports all the invalid fields of a request at once. The
+// errors of the elements of the slices, arrays and maps are collected too.
+// The fields that cannot be decoded are left partially filled.
+func WithCollectErrors() DecoderOption {
+	return func(dec *Decoder) {
+		dec.collectErrors = true
+	}
+}
+
+// WithLenientConversions configures the Decoder to convert the scalars
+// that FillStruct rejects by default, for callers choosing convenience over
+// safety, as with loosely typed inputs: a number with a fractional part is
+// truncated toward zero into an integer field, a number is rounded to the
+// precision of a floating-point field, a number is stored into a boolean
+// field as true unless it is zero, and a string is parsed into a number or
+// boolean field by the strconv package, surrounding spaces being trimmed.
+// The numbers out of range of their field are still errors.
+func WithLenientConversions() DecoderOption {
+	return func(dec *Decoder) {
+		dec.lenient = true
+	}
+}
+
+// WithKeepJSONNumbers configures the Decoder to store the json.Number
+// values as they are into interface fields, including the elements of their
+// map[string]any and []any values, instead of converting them to int64 or
+// float64, for the callers handling the numbers with their exact text.
+func WithKeepJSONNumbers() DecoderOption {
+	return func(dec *Decoder) {
+		dec.keepNumbers = true
+	}
+}
+
+// WithOnlyFields configures the Decoder to fill only the fields selected by
+// paths, leaving every other field unchanged even if its key is in the map.
+// This guards against mass assignment when a map from an untrusted source
+// is decoded into a struct with fields the source must not set.
+//
+// A path is a sequence of Go field names separated by dots, as in
+// "Address.City", selecting a field of a struct field; a path to a struct
+// field selects all its fields. The fields of an inline struct are selected
+// through the name of the inline field, and the elements of a slice, array
+// or map field share the path of the field, so "Items.Name" selects the
+// Name field of each element of Items. The fields of an embedded struct
+// are selected by their own names, as for FieldByName.
+//
+// WithZeroStruct zeroes the whole struct regardless of the selected fields.
+func WithOnlyFields(paths ...string) DecoderOption {
+	return func(dec *Decoder) {
+		if dec.onlyFields == nil {
+			dec.onlyFields = make(map[string]bool)
+			dec.onlyParents = make(map[string]bool)
+		}
+		for _, path := range paths {
+			dec.onlyFields[path] = true
+			for i := strings.LastIndexByte(path, '.'); i >= 0; i = strings.LastIndexByte(path, '.') {
+				path = path[:i]
+				dec.onlyParents[path] = true
+			}
+		}
+	}
+}
+
+// WithDecodeTransformers configures the Decoder to run the Transformers
+// named in the tag options of a field on its element of the map, before
+// storing it into the field. See RegisterTransformer.
+func WithDecodeTransformers() DecoderOption {
+	return func(dec *Decoder) {
+		dec.transforms = true
+	}
+}
+
+// WithTypes configures the Decoder to decode a map with the key into an
+// interface, such as a field of type any, as a value of the struct type it
+// names, among types and the types registered by RegisterType, as named by
+// an Encoder configured by WithTypeKey:
+// the value is stored into the interface if its type implements it, and a
+// pointer to the value otherwise. A map naming a type not in types is an
+// error, and a map without the key is stored as without the option.
+//
+// The types are named by their package path, as by FullTypeName.
+// Each of types may be a struct, a pointer to struct, or the reflect.Type
+// of either. WithTypes panics if one of them is not, or if two distinct
+// types have the same name, as the types declared in distinct functions.
+func WithTypes(key string, types ...any) DecoderOption {
+	named := make(map[string]reflect.Type, len(types))
+	for _, i := range types {
+		t := structType(i)
+		name := fullTypeName(t)
+		if u, dup := named[name]; dup && u != t {
+			panic("structof: WithTypes called with two types named " + name)
+		}
+		named[name] = t
+	}
+	return func(dec *Decoder) {
+		dec.typeKey = key
+		if dec.types == nil {
+			dec.types = make(map[string]reflect.Type, len(named))
+		}
+		for name, t := range named {
+			if u, dup := dec.types[name]; dup && u != t {
+				panic("structof: WithTypes called with two types named " + name)
+			}
+			dec.types[name] = t
+		}
+	}
+}
+
+// decodeTyped stores into the interface v the value of the type named by
+// the typeKey element of src, and reports whether src names a type.
+func (d *decodeState) decodeTyped(path string, src any, v reflect.Value) (bool, error) {
+	m, ok := src.(map[string]any)
+	if !ok {
+		return false, nil
+	}
+	name, ok := m[d.dec.typeKey].(string)
+	if !ok {
+		return false, nil
+	}
+	return true, d.decodeNamed(path, name, m, v)
+}
+
+// decodeNamed stores into the interface v the value of the type named name
+// filled from src.
+func (d *decodeState) decodeNamed(path string, name string, src any, v reflect.Value) error {
+	t, ok := d.dec.lookupType(name)
+	if !ok {
+		return fieldErrorf(path, src, v.Type(), "unknown type %q", name)
+	}
+	m, ok := src.(map[string]any)
+	if !ok {
+		return fieldErrorf(path, src, t, "cannot decode %T into %s", src, t)
+	}
+
+	pv := reflect.New(t)
+	if err := d.decodeStruct(path, m, pv.Elem()); err != nil {
+		return err
+	}
+	switch {
+	case t.AssignableTo(v.Type()):
+		v.Set(pv.Elem())
+	case pv.Type().AssignableTo(v.Type()):
+		v.Set(pv)
+	default:
+		return fieldErrorf(path, src, v.Type(), "type %s does not implement %s", t, v.Type())
+	}
+	return nil
+}
+
+// decodeDefault stores into v the default value s of its field.
+func (d *decodeState) decodeDefault(path string, s string, v reflect.Value) error {
+	for reflect.Pointer == v.Kind() {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if durationType == v.Type() || timeType == v.Type() {
+		return d.decodeValue(path, s, v, false)
+	}
+	if err := parseScalar(v, s); err != nil {
+		return fieldErrorf(path, s, v.Type(), "default option: %w", err)
+	}
+	return nil
+}
+
+// hasDefaults reports whether a field of the struct type t has a default
+// value, including the fields of its own inline fields.
+func hasDefaults(t reflect.Type) bool {
+	fields := cachedTypeFields(t)
+	for i := range fields.list {
+		f := &fields.list[i]
+		if f.encodeOnly {
+			continue
+		}
+		if f.inline && hasDefaults(f.typ) || f.hasDefault {
+			return true
+		}
+	}
+	return false
+}
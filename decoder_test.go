@@ -0,0 +1,563 @@
+package structof
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/weiwenchen2022/structof/testdata/genpkg"
+	genpkgv2 "github.com/weiwenchen2022/structof/testdata/genpkg/v2"
+)
+
+func TestDecoderDefaults(t *testing.T) {
+	t.Parallel()
+
+	type Inline struct {
+		Level string `structof:"level,default=info"`
+	}
+	type S struct {
+		Host    string        `structof:"host,default=localhost"`
+		Port    int           `structof:"port,omitempty,default=8080"`
+		Debug   *bool         `structof:"debug,default=true"`
+		Timeout time.Duration `structof:"timeout,default=30s"`
+		Name    string        `structof:"name"`
+		*Inline `structof:",inline"`
+	}
+
+	var s S
+	if err := NewDecoder(WithDefaults()).FillStruct(map[string]any{"port": 0, "name": "foo"}, &s); err != nil {
+		t.Fatal(err)
+	}
+	debug := true
+	want := S{
+		Host:    "localhost",
+		Port:    0,
+		Debug:   &debug,
+		Timeout: 30 * time.Second,
+		Name:    "foo",
+		Inline:  &Inline{Level: "info"},
+	}
+	if !cmp.Equal(want, s) {
+		t.Error(cmp.Diff(want, s))
+	}
+
+	s = S{Host: "example.com"}
+	if err := FillStruct(map[string]any{}, &s); err != nil {
+		t.Fatal(err)
+	}
+	if want := (S{Host: "example.com"}); !cmp.Equal(want, s) {
+		t.Error(cmp.Diff(want, s))
+	}
+
+	type Bad struct {
+		N int `structof:"n,default=foo"`
+	}
+	if err := NewDecoder(WithDefaults()).FillStruct(nil, &Bad{}); err == nil {
+		t.Error("FillStruct with invalid default: got nil error")
+	}
+}
+
+func TestDecoderZero(t *testing.T) {
+	t.Parallel()
+
+	type Nested struct {
+		A int `structof:"a"`
+		B int `structof:"b"`
+	}
+	type S struct {
+		Name   string            `structof:"name"`
+		Email  string            `structof:"email"`
+		Labels map[string]string `structof:"labels"`
+		Nested Nested            `structof:"nested"`
+	}
+	m := map[string]any{
+		"name":   "foo",
+		"labels": map[string]any{"new": "x"},
+		"nested": map[string]any{"a": 1},
+	}
+	old := S{
+		Name:   "old",
+		Email:  "old@example.com",
+		Labels: map[string]string{"old": "y"},
+		Nested: Nested{A: 2, B: 3},
+	}
+
+	tests := []struct {
+		opt  DecoderOption
+		want S
+	}{
+		{WithZeroStruct(), S{
+			Name:   "foo",
+			Labels: map[string]string{"new": "x"},
+			Nested: Nested{A: 1},
+		}},
+		{WithZeroFields(), S{
+			Name:   "foo",
+			Email:  "old@example.com",
+			Labels: map[string]string{"new": "x"},
+			Nested: Nested{A: 1},
+		}},
+	}
+	for _, tt := range tests {
+		s := old
+		s.Labels = map[string]string{"old": "y"}
+		if err := NewDecoder(tt.opt).FillStruct(m, &s); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(tt.want, s) {
+			t.Error(cmp.Diff(tt.want, s))
+		}
+	}
+}
+
+func TestDecoderOnlyFields(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City    string `structof:"city"`
+		Country string `structof:"country"`
+	}
+	type Base struct {
+		ID int `structof:"id"`
+	}
+	type S struct {
+		Base
+		Name    string  `structof:"name"`
+		Email   string  `structof:"email"`
+		Admin   bool    `structof:"admin"`
+		Address Address `structof:"address"`
+		Home    Address `structof:"home"`
+	}
+
+	m := map[string]any{
+		"id":      1,
+		"name":    "foo",
+		"email":   "foo@example.com",
+		"admin":   true,
+		"address": map[string]any{"city": "Paris", "country": "FR"},
+		"home":    map[string]any{"city": "Lyon", "country": "FR"},
+	}
+	s := S{Base: Base{ID: 7}, Address: Address{Country: "US"}}
+	dec := NewDecoder(WithOnlyFields("Name", "Email", "Address.City"), WithOnlyFields("Home"))
+	if err := dec.FillStruct(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	want := S{
+		Base:    Base{ID: 7},
+		Name:    "foo",
+		Email:   "foo@example.com",
+		Address: Address{City: "Paris", Country: "US"},
+		Home:    Address{City: "Lyon", Country: "FR"},
+	}
+	if !cmp.Equal(want, s) {
+		t.Error(cmp.Diff(want, s))
+	}
+}
+
+type typedShape interface {
+	Area() float64
+}
+
+type typedSquare struct {
+	Side float64 `structof:"side"`
+}
+
+func (s typedSquare) Area() float64 { return s.Side * s.Side }
+
+type typedCircle struct {
+	R float64 `structof:"r"`
+}
+
+func (c *typedCircle) Area() float64 { return 3 * c.R * c.R }
+
+type typedPing struct{}
+
+func TestDecoderLenientConversions(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		N     int     `structof:"n"`
+		U     uint8   `structof:"u"`
+		F     float32 `structof:"f"`
+		B     bool    `structof:"b"`
+		Ready bool    `structof:"ready"`
+		Name  string  `structof:"name"`
+	}
+
+	m := map[string]any{"n": 2.9, "u": " 7 ", "f": "0.5", "b": 1, "ready": "true", "name": "x"}
+	var s S
+	err := FillStruct(m, &s)
+	if err == nil {
+		t.Fatal("strict FillStruct should return error")
+	}
+
+	for _, tt := range []struct {
+		m       map[string]any
+		wantErr string
+	}{
+		{map[string]any{"n": 2.9}, `structof: field "n": value 2.9 is not representable in int`},
+		{map[string]any{"n": "12"}, `structof: field "n": cannot decode string "12" into int`},
+		{map[string]any{"b": 1}, `structof: field "b": cannot decode int 1 into bool`},
+	} {
+		if err := FillStruct(tt.m, &s); err == nil || tt.wantErr != err.Error() {
+			t.Errorf("FillStruct(%v) error = %v, want %s", tt.m, err, tt.wantErr)
+		}
+	}
+
+	dec := NewDecoder(WithLenientConversions())
+	s = S{}
+	if err := dec.FillStruct(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	want := S{N: 2, U: 7, F: 0.5, B: true, Ready: true, Name: "x"}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("FillStruct mismatch (-want +got):\n%s", diff)
+	}
+	if err := dec.FillStruct(map[string]any{"n": "-3.7"}, &s); err != nil || s.N != -3 {
+		t.Errorf("FillStruct n = %d, %v, want -3", s.N, err)
+	}
+
+	for _, m := range []map[string]any{
+		{"u": 300.5},
+		{"u": "-1"},
+		{"n": "many"},
+		{"b": "yes"},
+	} {
+		if err := dec.FillStruct(m, &s); err == nil {
+			t.Errorf("lenient FillStruct(%v) should return error", m)
+		}
+	}
+}
+
+func TestDecoderFloatConversions(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		F64 float64 `structof:"f64"`
+		F32 float32 `structof:"f32"`
+	}
+
+	for _, tt := range []struct {
+		m       map[string]any
+		want    S
+		wantErr string
+	}{
+		{m: map[string]any{"f64": int64(1) << 53, "f32": 1 << 24}, want: S{1 << 53, 1 << 24}},
+		{m: map[string]any{"f64": uint64(1) << 63}, want: S{F64: 1 << 63}},
+		{m: map[string]any{"f32": 0.1, "f64": 0.1}, want: S{0.1, 0.1}},
+		{m: map[string]any{"f32": float32(0.1)}, want: S{F32: 0.1}},
+		{m: map[string]any{"f32": 1.5}, want: S{F32: 1.5}},
+		{m: map[string]any{"f64": int64(1)<<53 + 1}, wantErr: `structof: field "f64": value 9007199254740993 is not representable in float64`},
+		{m: map[string]any{"f64": uint64(math.MaxUint64)}, wantErr: `structof: field "f64": value 18446744073709551615 is not representable in float64`},
+		{m: map[string]any{"f32": 1<<24 + 1}, wantErr: `structof: field "f32": value 16777217 is not representable in float32`},
+		{m: map[string]any{"f32": math.Nextafter(0.3, 1)}, wantErr: `structof: field "f32": value 0.30000000000000004 is not representable in float32`},
+		{m: map[string]any{"f32": math.Pi}, wantErr: `structof: field "f32": value 3.141592653589793 is not representable in float32`},
+		{m: map[string]any{"f32": 1e300}, wantErr: `structof: field "f32": value 1e+300 overflows float32`},
+	} {
+		var s S
+		err := FillStruct(tt.m, &s)
+		if tt.wantErr != "" {
+			if err == nil || tt.wantErr != err.Error() {
+				t.Errorf("FillStruct(%v) error = %v, want %s", tt.m, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FillStruct(%v) error = %v", tt.m, err)
+		} else if tt.want != s {
+			t.Errorf("FillStruct(%v) = %+v, want %+v", tt.m, s, tt.want)
+		}
+	}
+
+	var s S
+	dec := NewDecoder(WithLenientConversions())
+	m := map[string]any{"f64": int64(1)<<53 + 1, "f32": math.Pi}
+	if err := dec.FillStruct(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	if want := (S{1 << 53, math.Pi}); want != s {
+		t.Errorf("lenient FillStruct(%v) = %+v, want %+v", m, s, want)
+	}
+	if err := dec.FillStruct(map[string]any{"f32": 1e300}, &s); err == nil {
+		t.Error("lenient FillStruct of an overflowing float32 should return error")
+	}
+}
+
+func TestDecoderCollectErrors(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		Zip int `structof:"zip"`
+	}
+	type S struct {
+		Name    string         `structof:"name"`
+		Age     int            `structof:"age"`
+		Address Address        `structof:"address"`
+		Ports   []uint16       `structof:"ports"`
+		Limits  map[string]int `structof:"limits"`
+		Email   string         `structof:"email"`
+	}
+	m := map[string]any{
+		"name":    1,
+		"age":     "old",
+		"address": map[string]any{"zip": true},
+		"ports":   []any{80, -1, 443, 70000},
+		"limits":  map[string]any{"burst": 10},
+		"email":   "foo@example.com",
+	}
+
+	var s S
+	err := NewDecoder(WithCollectErrors()).FillStruct(m, &s)
+	if err == nil {
+		t.Fatal("FillStruct should return error")
+	}
+	want := strings.Join([]string{
+		`structof: field "name": cannot decode int 1 into string`,
+		`structof: field "age": cannot decode string "old" into int`,
+		`structof: field "address.zip": cannot decode bool true into int`,
+		`structof: field "ports[1]": value -1 overflows uint16`,
+		`structof: field "ports[3]": value 70000 overflows uint16`,
+	}, "\n")
+	if want != err.Error() {
+		t.Errorf("FillStruct error =\n%v\nwant\n%s", err, want)
+	}
+	if s.Email != "foo@example.com" || s.Ports[2] != 443 || s.Limits["burst"] != 10 {
+		t.Errorf("FillStruct did not decode the valid fields: %+v", s)
+	}
+
+	var fe *DecodeFieldError
+	if !errors.As(err, &fe) || "name" != fe.Path || "name" != fe.Key || 1 != fe.Value || reflect.TypeOf("") != fe.TargetType {
+		t.Errorf("errors.As(%v) = %+v", err, fe)
+	}
+	var paths []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		if errors.As(err, &fe) {
+			paths = append(paths, fe.Path+" "+fe.Key)
+		}
+	}
+	if want := []string{"name name", "age age", "address.zip zip", "ports[1] ports", "ports[3] ports"}; !cmp.Equal(want, paths) {
+		t.Error(cmp.Diff(want, paths))
+	}
+
+	if err := FillStruct(m, &s); err == nil || strings.Contains(err.Error(), "\n") {
+		t.Errorf("FillStruct error = %v, want the first error only", err)
+	}
+	if err := NewDecoder(WithCollectErrors()).FillStruct(map[string]any{"age": 1}, &s); err != nil {
+		t.Errorf("FillStruct error = %v, want nil", err)
+	}
+}
+
+func TestDecoderTypes(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Shape  typedShape   `structof:"shape"`
+		Shapes []typedShape `structof:"shapes"`
+		Any    any          `structof:"any"`
+		Ping   any          `structof:"ping"`
+		Plain  any          `structof:"plain"`
+	}
+	s := S{
+		Shape:  typedSquare{2},
+		Shapes: []typedShape{&typedCircle{1}, typedSquare{3}},
+		Any:    &typedSquare{4},
+		Ping:   typedPing{},
+		Plain:  map[string]any{"a": 1},
+	}
+
+	enc := NewEncoder(WithTypeKey("__type"), WithEmptyStructMaps())
+	m := enc.MakeMap(s)
+	want := map[string]any{
+		"shape": map[string]any{"__type": "github.com/weiwenchen2022/structof.typedSquare", "side": 2.0},
+		"shapes": []any{
+			map[string]any{"__type": "github.com/weiwenchen2022/structof.typedCircle", "r": 1.0},
+			map[string]any{"__type": "github.com/weiwenchen2022/structof.typedSquare", "side": 3.0},
+		},
+		"any":   map[string]any{"__type": "github.com/weiwenchen2022/structof.typedSquare", "side": 4.0},
+		"ping":  map[string]any{"__type": "github.com/weiwenchen2022/structof.typedPing"},
+		"plain": map[string]any{"a": 1},
+	}
+	if !cmp.Equal(want, m) {
+		t.Fatal(cmp.Diff(want, m))
+	}
+
+	var got S
+	dec := NewDecoder(WithTypes("__type", typedSquare{}, &typedCircle{}, reflect.TypeOf(typedPing{})))
+	if err := dec.FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	wantS := S{
+		Shape:  typedSquare{2},
+		Shapes: []typedShape{&typedCircle{1}, typedSquare{3}},
+		Any:    typedSquare{4},
+		Ping:   typedPing{},
+		Plain:  map[string]any{"a": 1},
+	}
+	if !cmp.Equal(wantS, got) {
+		t.Error(cmp.Diff(wantS, got))
+	}
+
+	m["shape"] = map[string]any{"__type": "github.com/weiwenchen2022/structof.typedHexagon"}
+	err := dec.FillStruct(m, &got)
+	if want := `structof: field "shape": unknown type "github.com/weiwenchen2022/structof.typedHexagon"`; err == nil || want != err.Error() {
+		t.Errorf("FillStruct of an unknown type returned %v, want %s", err, want)
+	}
+
+	m["shape"] = map[string]any{"__type": "github.com/weiwenchen2022/structof.typedPing"}
+	err = dec.FillStruct(m, &got)
+	if want := `structof: field "shape": type structof.typedPing does not implement structof.typedShape`; err == nil || want != err.Error() {
+		t.Errorf("FillStruct of a type not implementing the interface returned %v, want %s", err, want)
+	}
+}
+
+func TestDecoderTypesSameName(t *testing.T) {
+	t.Parallel()
+
+	// The two Point types are both named genpkg.Point by reflect.Type.String.
+	type S struct {
+		A any `structof:"a"`
+		B any `structof:"b"`
+	}
+	s := S{genpkg.Point{X: 1, Y: 2}, genpkgv2.Point{X: 3, Y: 4, Z: 5}}
+
+	m := NewEncoder(WithTypeKey("__type")).MakeMap(s)
+	want := map[string]any{
+		"a": map[string]any{"__type": "github.com/weiwenchen2022/structof/testdata/genpkg.Point", "X": 1, "Y": 2},
+		"b": map[string]any{"__type": "github.com/weiwenchen2022/structof/testdata/genpkg/v2.Point", "X": 3, "Y": 4, "Z": 5},
+	}
+	if !cmp.Equal(want, m) {
+		t.Fatal(cmp.Diff(want, m))
+	}
+
+	var got S
+	dec := NewDecoder(WithTypes("__type", genpkg.Point{}, genpkgv2.Point{}))
+	if err := dec.FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(s, got) {
+		t.Error(cmp.Diff(s, got))
+	}
+}
+
+func TestWithTypesCollision(t *testing.T) {
+	t.Parallel()
+
+	// Declared in distinct functions, the types have the same name.
+	p1 := func() any { type P struct{ X int }; return P{} }()
+	p2 := func() any { type P struct{ Y int }; return P{} }()
+
+	const want = "structof: WithTypes called with two types named github.com/weiwenchen2022/structof.P"
+	for _, f := range []func(){
+		func() { WithTypes("__type", p1, p2) },
+		func() { NewDecoder(WithTypes("__type", p1), WithTypes("__type", p2)) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); want != r {
+					t.Errorf("recover() = %v, want %s", r, want)
+				}
+			}()
+			f()
+		}()
+	}
+
+	// The same type may be given twice.
+	NewDecoder(WithTypes("__type", p1, p1), WithTypes("__type", reflect.TypeOf(p1)))
+}
+
+type registryOrderCreated struct {
+	ID    int     `structof:"id"`
+	Total float64 `structof:"total"`
+}
+
+type registryOrderShipped struct {
+	ID      int    `structof:"id"`
+	Carrier string `structof:"carrier"`
+}
+
+func init() {
+	RegisterType("test.order.created", registryOrderCreated{})
+	RegisterType("test.order.shipped", &registryOrderShipped{})
+}
+
+func TestRegisterType(t *testing.T) {
+	t.Parallel()
+
+	type Envelope struct {
+		Type string `structof:"type"`
+		Data any    `structof:"data,typekey=type"`
+	}
+
+	var events []Envelope
+	for _, m := range []map[string]any{
+		{"type": "test.order.created", "data": map[string]any{"id": 1, "total": 9.5}},
+		{"type": "test.order.shipped", "data": map[string]any{"id": 1, "carrier": "ups"}},
+		{"data": map[string]any{"id": 2}},
+	} {
+		var e Envelope
+		if err := FillStruct(m, &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	want := []Envelope{
+		{"test.order.created", registryOrderCreated{1, 9.5}},
+		{"test.order.shipped", registryOrderShipped{1, "ups"}},
+		{"", map[string]any{"id": 2}},
+	}
+	if !cmp.Equal(want, events) {
+		t.Error(cmp.Diff(want, events))
+	}
+
+	err := FillStruct(map[string]any{"type": "test.order.lost", "data": map[string]any{}}, &Envelope{})
+	if want := `structof: field "data": unknown type "test.order.lost"`; err == nil || want != err.Error() {
+		t.Errorf("FillStruct of an unknown type returned %v, want %s", err, want)
+	}
+
+	// The registered names are used by WithTypeKey and WithTypes.
+	type S struct {
+		Event any `structof:"event"`
+	}
+	m := NewEncoder(WithTypeKey("@type")).MakeMap(S{&registryOrderCreated{2, 1}})
+	wantMap := map[string]any{"event": map[string]any{"@type": "test.order.created", "id": 2, "total": 1.0}}
+	if !cmp.Equal(wantMap, m) {
+		t.Error(cmp.Diff(wantMap, m))
+	}
+	var s S
+	if err := NewDecoder(WithTypes("@type")).FillStruct(m, &s); err != nil {
+		t.Fatal(err)
+	}
+	if want := (S{registryOrderCreated{2, 1}}); !cmp.Equal(want, s) {
+		t.Error(cmp.Diff(want, s))
+	}
+}
+
+func TestRegisterTypePanics(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		i    any
+	}{
+		{"", struct{}{}},
+		{"test.order.created", struct{ A int }{}},
+		{"test.order.other", registryOrderCreated{}},
+		{"test.int", 0},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterType(%q, %T) did not panic", tt.name, tt.i)
+				}
+			}()
+			RegisterType(tt.name, tt.i)
+		}()
+	}
+	if _, ok := registeredTypes.Load("test.order.other"); ok {
+		t.Error("RegisterType of a registered type kept its new name")
+	}
+}
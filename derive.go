@@ -0,0 +1,163 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// structType returns the struct type of i, following a pointer.
+// It panics if i is not a struct, a pointer to struct or a reflect.Type
+// of either.
+func structType(i any) reflect.Type {
+	t, ok := i.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(i)
+	}
+	if t != nil && reflect.Pointer == t.Kind() {
+		t = t.Elem()
+	}
+	if t == nil || reflect.Struct != t.Kind() {
+		panic("not struct or pointer to struct")
+	}
+	return t
+}
+
+// RetagType returns a struct type identical to the type of i except that
+// the tag of each field is replaced with the result of fn.
+// The i may be a struct, a pointer to struct, or the reflect.Type of either.
+//
+// Because struct conversion ignores tags, values of the returned type
+// and of i's type convert into each other; see ConvertStruct.
+// Only the fields of the outermost struct are retagged, nested struct
+// types are kept as they are.
+//
+// RetagType panics if i's type has unexported fields, which
+// reflect.StructOf cannot declare, or if reflect.StructOf panics otherwise,
+// notably for embedded fields whose type has methods.
+func RetagType(i any, fn func(f reflect.StructField) reflect.StructTag) reflect.Type {
+	t := structType(i)
+	fields := make([]reflect.StructField, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		checkExported("RetagType", t, f)
+		f.Tag = fn(f)
+		f.Offset, f.Index = 0, nil
+		fields[i] = f
+	}
+	return reflect.StructOf(fields)
+}
+
+// checkExported panics naming the field f of t and the calling function fn
+// if f is unexported, instead of the less helpful panic of reflect.StructOf.
+func checkExported(fn string, t reflect.Type, f reflect.StructField) {
+	if !f.IsExported() {
+		panic(fmt.Sprintf("structof: %s: field %s.%s is unexported", fn, t, f.Name))
+	}
+}
+
+// ConvertStruct returns a pointer to a new value of the struct type t
+// holding a copy of the struct i, converted as by a Go conversion.
+// It is typically used to move values between a type and the type
+// returned by RetagType, so that they can be re-encoded under different keys.
+//
+// ConvertStruct panics if i is not a struct or a non-nil pointer to struct,
+// or if its type is not convertible to t.
+func ConvertStruct(i any, t reflect.Type) any {
+	v := reflect.ValueOf(i)
+	if reflect.Pointer == v.Kind() && !v.IsNil() {
+		v = v.Elem()
+	}
+	if reflect.Struct != v.Kind() {
+		panic("not struct or pointer to struct")
+	}
+	if !v.Type().ConvertibleTo(t) {
+		panic(fmt.Sprintf("%s not convertible to %s", v.Type(), t))
+	}
+
+	p := reflect.New(t)
+	p.Elem().Set(v.Convert(t))
+	return p.Interface()
+}
+
+// ExtendType returns a new struct type with the fields of i's type followed
+// by the extra fields, such as audit columns added to a projection.
+// The i may be a struct, a pointer to struct, or the reflect.Type of either.
+// It panics if i's type has unexported fields, as RetagType does,
+// if a field name is duplicated or if reflect.StructOf panics otherwise.
+func ExtendType(i any, extra ...reflect.StructField) reflect.Type {
+	t := structType(i)
+	fields := make([]reflect.StructField, 0, t.NumField()+len(extra))
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		checkExported("ExtendType", t, f)
+		f.Offset, f.Index = 0, nil
+		fields = append(fields, f)
+	}
+	fields = append(fields, extra...)
+	return reflect.StructOf(fields)
+}
+
+// RemoveFields returns a new struct type with the fields of i's type except
+// the ones with the given names.
+// The i may be a struct, a pointer to struct, or the reflect.Type of either.
+// It panics if a name does not match a field of i's type, or matches a
+// field promoted from an embedded struct, which cannot be removed alone,
+// or if one of the fields kept is unexported, as RetagType does.
+func RemoveFields(i any, names ...string) reflect.Type {
+	t := structType(i)
+	remove := make(map[string]bool, len(names))
+	for _, name := range names {
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			panic(fmt.Sprintf("field %q not found", name))
+		}
+		if len(sf.Index) != 1 {
+			panic(fmt.Sprintf("field %q is promoted", name))
+		}
+		remove[name] = true
+	}
+
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if remove[f.Name] {
+			continue
+		}
+		checkExported("RemoveFields", t, f)
+		f.Offset, f.Index = 0, nil
+		fields = append(fields, f)
+	}
+	return reflect.StructOf(fields)
+}
+
+// ProjectStruct returns a pointer to a new value of the struct type t whose
+// fields are copied from the fields of the struct i with the same name and
+// an assignable type. Fields of t without a counterpart are left zero.
+// It is the conversion for types derived by ExtendType and RemoveFields.
+//
+// ProjectStruct panics if i is not a struct or a non-nil pointer to struct,
+// or if t is not a struct type.
+func ProjectStruct(i any, t reflect.Type) any {
+	v := reflect.ValueOf(i)
+	if reflect.Pointer == v.Kind() && !v.IsNil() {
+		v = v.Elem()
+	}
+	if reflect.Struct != v.Kind() || reflect.Struct != t.Kind() {
+		panic("not struct or pointer to struct")
+	}
+
+	p := reflect.New(t)
+	dst := p.Elem()
+	for i := 0; i < t.NumField(); i++ {
+		df := t.Field(i)
+		if !df.IsExported() {
+			continue
+		}
+		sf, ok := v.Type().FieldByName(df.Name)
+		if !ok || len(sf.Index) != 1 || !sf.IsExported() || !sf.Type.AssignableTo(df.Type) {
+			continue
+		}
+		dst.Field(i).Set(v.Field(sf.Index[0]))
+	}
+	return p.Interface()
+}
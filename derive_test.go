@@ -0,0 +1,153 @@
+package structof
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRetagType(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A int    `structof:"a"`
+		B string `structof:"b"`
+	}
+
+	rt := RetagType(T{}, func(f reflect.StructField) reflect.StructTag {
+		return reflect.StructTag(`structof:"` + strings.ToUpper(f.Tag.Get("structof")) + `"`)
+	})
+	if reflect.Struct != rt.Kind() || rt.NumField() != 2 {
+		t.Fatalf("RetagType returned %s", rt)
+	}
+
+	p := ConvertStruct(&T{23, "foobar"}, rt)
+	m := MakeMap(p)
+	want := map[string]any{"A": 23, "B": "foobar"}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	back := ConvertStruct(p, reflect.TypeOf(T{})).(*T)
+	if (T{23, "foobar"}) != *back {
+		t.Errorf("ConvertStruct back got %+v", *back)
+	}
+}
+
+func TestDeriveUnexported(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A int
+		b string
+	}
+	retag := func(f reflect.StructField) reflect.StructTag { return f.Tag }
+
+	tests := []struct {
+		f    func()
+		want string
+	}{
+		{func() { RetagType(T{}, retag) }, "structof: RetagType: field structof.T.b is unexported"},
+		{func() { ExtendType(&T{}) }, "structof: ExtendType: field structof.T.b is unexported"},
+		{func() { RemoveFields(T{}, "A") }, "structof: RemoveFields: field structof.T.b is unexported"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if r := recover(); tt.want != r {
+					t.Errorf("recover() = %v, want %s", r, tt.want)
+				}
+			}()
+			tt.f()
+		}()
+	}
+
+	// The unexported fields removed are not declared.
+	if rt := RemoveFields(T{}, "b"); rt.NumField() != 1 || rt.Field(0).Name != "A" {
+		t.Errorf("RemoveFields returned %s", rt)
+	}
+}
+
+func TestConvertStructNotConvertible(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("ConvertStruct to an unrelated type should panic")
+		}
+	}()
+	ConvertStruct(struct{ A int }{}, reflect.TypeOf(struct{ B int }{}))
+}
+
+func TestExtendType(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A int
+		B string
+	}
+
+	et := ExtendType(T{}, reflect.StructField{
+		Name: "CreatedBy",
+		Type: reflect.TypeOf(""),
+		Tag:  `structof:"created_by"`,
+	})
+	p := ProjectStruct(T{23, "foobar"}, et)
+	MakeStruct(p).v.FieldByName("CreatedBy").SetString("gopher")
+
+	m := MakeMap(p)
+	want := map[string]any{"A": 23, "B": "foobar", "created_by": "gopher"}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+}
+
+func TestRemoveFields(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A        int
+		B        string
+		Password string
+	}
+
+	rt := RemoveFields(&T{}, "Password")
+	if rt.NumField() != 2 {
+		t.Fatalf("RemoveFields returned %s", rt)
+	}
+
+	m := MakeMap(ProjectStruct(&T{23, "foobar", "secret"}, rt))
+	want := map[string]any{"A": 23, "B": "foobar"}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("RemoveFields with an unknown name should panic")
+		}
+	}()
+	RemoveFields(T{}, "C")
+}
+
+func TestRemoveFieldsPromoted(t *testing.T) {
+	t.Parallel()
+
+	type Base struct{ ID int }
+	type T struct {
+		Base
+		Name string
+	}
+	if rt := RemoveFields(T{}, "Base"); rt.NumField() != 1 {
+		t.Errorf("RemoveFields returned %s", rt)
+	}
+
+	defer func() {
+		if r := recover(); r != `field "ID" is promoted` {
+			t.Errorf("RemoveFields of a promoted field panicked with %v", r)
+		}
+	}()
+	RemoveFields(T{}, "ID")
+}
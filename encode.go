@@ -1,9 +1,12 @@
 package structof
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -69,12 +72,115 @@ var mapType = reflect.TypeOf(map[string]any(nil))
 //
 //	Int64String int64 `structof:",string"`
 //
+// The "stringer" option signals that a field implementing fmt.Stringer is
+// stored as the plain string its String method returns, as for enums.
+// A field not implementing fmt.Stringer, or holding a nil pointer, is stored
+// as without the option. The option only applies to encoding:
+//
+//	// Field appears in map as key "level", as the string Level.String returns.
+//	Field Level `structof:"level,stringer"`
+//
+// The "json" option signals that a field is stored as its JSON encoding,
+// in a string, for nested documents traveling as serialized text such as
+// jsonb columns. FillStruct decodes such a field from its JSON encoding,
+// in a string or a []byte:
+//
+//	// Field appears in map as key "doc", as a string such as `{"a":1}`.
+//	Field Document `structof:"doc,json"`
+//
+// The "encodeonly" option signals that a field is encoded but ignored by
+// FillStruct, as for computed fields, and the "decodeonly" option that a field
+// is filled by FillStruct but never encoded, as for passwords, so that one
+// struct serves as both the input and the output model:
+//
+//	// Field is filled from key "password", and never appears in map.
+//	Field string `structof:"password,decodeonly"`
+//
+// The "method" option of a blank field adds to the map the result of calling
+// the named method of the struct, which takes no argument and returns one
+// value, under the key of the field, so that derived values appear along
+// with the stored fields. Such a field is ignored by FillStruct:
+//
+//	// The result of the FullName method appears in map as key "full_name".
+//	_ struct{} `structof:"full_name,method=FullName"`
+//
 // The "inline" option signals a non-embedded struct field flatten its fields
 // in the outside map. Example:
 //
 //	// The F's fields will be flattened into the output map.
 //	F struct {A int; B string} `structof:",inline"`
 //
+// The "squash" option is a synonym of "inline".
+//
+// The "alias" option lists alternative key names, separated by "|", that
+// FillStruct accepts for the field when its name is missing from the map.
+// The field is always encoded under its name:
+//
+//	// Field appears in map as key "id", and is decoded from the first
+//	// of the keys "id", "user_id" and "uid" present in the map.
+//	Field int `structof:"id,alias=user_id|uid"`
+//
+// The "default" option gives the value FillStruct sets the field to when
+// its key is missing from the map, if the Decoder is configured by WithDefaults.
+//
+// The "indexmap" option signals that a slice or array field is stored as a
+// map[string]any keyed by the index of its elements, and the "keyby" option
+// that it is stored as a map[string]any keyed by the value, formatted with
+// fmt.Sprint, of the given key of its struct elements, which must be unique.
+// The options only apply to encoding:
+//
+//	// Field appears in map as key "users", such as {"0": {...}, "1": {...}}.
+//	Field []User `structof:"users,indexmap"`
+//
+//	// Field appears in map as key "users", such as {"alice": {...}}.
+//	Field []User `structof:"users,keyby=name"`
+//
+// The "typekey" option signals that an interface field is decoded by
+// FillStruct as a value of the struct type named, as by RegisterType, by the
+// given key of the map, as for the payloads of event envelopes:
+//
+//	// Field is decoded from key "data" as the type named by key "type".
+//	Field any `structof:"data,typekey=type"`
+//
+// The "since" and "until" options give the first version a field is encoded
+// at, and the version it is no longer encoded from, if the Encoder is
+// configured by WithVersion:
+//
+//	// Field appears in map as key "email" at versions 2 to 3.x only.
+//	Field string `structof:"email,since=2,until=4"`
+//
+// The big.Int, big.Float and big.Rat values encode as decimal strings, and
+// FillStruct decodes them back from strings and numbers. The "precision"
+// option gives the number of digits after the decimal point of the
+// big.Float and big.Rat values, which are otherwise encoded with the
+// fewest digits representing them exactly, a big.Rat as a fraction "a/b"
+// if it is not an integer:
+//
+//	// Field appears in map as key "price", such as "12.50".
+//	Field *big.Rat `structof:"price,precision=2"`
+//
+// The "groups" option lists the groups, separated by "|", a field belongs
+// to. Such a field is encoded only if the Encoder is configured by
+// WithGroups with one of its groups:
+//
+//	// Field appears in map as key "email" for the groups admin and internal.
+//	Field string `structof:"email,groups=admin|internal"`
+//
+// The "order" option gives an integer weight overriding the declaration
+// order of a field in MakeSlice, Keys, Values and Fields, for wire formats
+// mandating an order of fields, such as signed canonical payloads. The
+// fields with a weight come first, by increasing weight, ties keeping the
+// declaration order, followed by the other fields in declaration order:
+//
+//	// Field appears first in MakeSlice, as key "id".
+//	Field int `structof:"id,order=1"`
+//
+// The other options without a value name the Transformers run on the
+// value of the field, in order; see RegisterTransformer:
+//
+//	// Field appears in map as key "email", trimmed and lower-cased.
+//	Field string `structof:"email,trim,lowercase"`
+//
 // The key name will be used if it's a non-empty string consisting of
 // only Unicode letters, digits, and ASCII punctuation except quotation
 // marks, backslash, and comma.
@@ -85,7 +191,8 @@ var mapType = reflect.TypeOf(map[string]any(nil))
 // An anonymous struct field with a name given in its structof's tag is treated as
 // having that name, rather than being anonymous.
 // An anonymous struct field of interface type is treated the same as having
-// that type as its name, rather than being anonymous.
+// that type as its name, rather than being anonymous: a struct held by it is
+// encoded as a nested map under that name, and any other value is stored as is.
 //
 // The Go visibility rules for struct fields are amended for structof when
 // deciding which field to marshal or unmarshal. If there are
@@ -108,14 +215,75 @@ var mapType = reflect.TypeOf(map[string]any(nil))
 //
 // Interface values encode as the value contained in the interface if it not nil.
 // A nil interface value omitted.
+// A map of interface values, named or not, encodes as a map[string]any, and
+// the structs held by interface values, at any depth, as nested maps.
+//
+// A url.URL encodes as the string its String method returns, and the array
+// and slice types implementing encoding.TextMarshaler, such as uuid.UUID and
+// net.IP, as the text they marshal to rather than as their bytes.
+// FillStruct decodes them back from strings.
+//
+// The container types implementing MapExporter or SliceExporter, such as
+// ordered maps, sets and ring buffers, encode as the map or slice their
+// method returns, except at the top level and inline. FillStruct does not
+// decode them back.
+//
+// The protobuf wrapper messages, such as wrapperspb.StringValue, encode as
+// the value they wrap, and timestamppb.Timestamp as a time.Time, so that the
+// structs generated alongside protobuf models do not expose the internals
+// of the messages. FillStruct decodes them back.
 //
 // Channel, complex, and function values unsupported.
 // Attempting to encode such a value causes FillMap to panics with
-// an UnsupportedTypeError.
+// an UnsupportedTypeError; see WithFuncNames for encoding functions and
+// WithDrainChannels for channels, and MarshalAny for encoding any value.
 //
 // Passing cyclic structures to FillMap will result in
-// panics.
+// panics with an UnsupportedValueError; see WithCyclePolicy
+// for encoding them.
+//
+// FillMap uses the default configuration; see Encoder for the options
+// customizing the encoding.
 func FillMap(s, i any) {
+	defaultEncoder.FillMap(s, i)
+}
+
+// MakeMap is like FillMap. Instead allocates a new map and returns it.
+// See FillMap function's documentation for more information.
+func MakeMap(i any) map[string]any {
+	return defaultEncoder.MakeMap(i)
+}
+
+// MakeSlice returns a list of field/value pairs of the struct.
+// See FillMap function's documentation for more information.
+func MakeSlice(i any) []any {
+	return defaultEncoder.MakeSlice(i)
+}
+
+// Precompile builds and caches the field lists and encoders of the struct
+// types of types, and of the types of their fields, which otherwise are
+// built by the first encoding of a value of each type. Calling it at
+// startup moves this reflective work out of the first request a service
+// handles, and makes the panics of malformed types surface at once.
+// The types of values held in interface fields are only known during
+// encoding, and are not precompiled.
+//
+// Each of types may be a struct, a pointer to struct, or the reflect.Type
+// of either. Precompile panics if one of them is not.
+func Precompile(types ...any) {
+	defaultEncoder.Precompile(types...)
+}
+
+// Precompile is like the Precompile function. The compiled encoders are
+// shared by all Encoders, enc's configuration being applied when encoding.
+func (enc *Encoder) Precompile(types ...any) {
+	for _, i := range types {
+		typeEncoder(structType(i))
+	}
+}
+
+// FillMap is like the FillMap function but encodes with enc's configuration.
+func (enc *Encoder) FillMap(s, i any) {
 	rs := reflect.ValueOf(s)
 	for reflect.Pointer == rs.Kind() && !rs.IsNil() {
 		rs = rs.Elem()
@@ -134,22 +302,39 @@ func FillMap(s, i any) {
 		v.Set(reflect.MakeMap(mapType))
 	}
 
-	e, put := newEncodeState(v.Interface())
+	e, put := newEncodeState(enc, v.Interface())
 	defer put()
 	e.marshal(s, encOpts{})
 }
 
-// MakeMap is like FillMap. Instead allocates a new map and returns it.
-// See FillMap function's documentation for more information.
-func MakeMap(i any) map[string]any {
+// makeMap is like MakeMap but records into types the type of the field of
+// each key, if types is not nil.
+func (enc *Encoder) makeMap(s any, types map[string]reflect.Type) map[string]any {
+	rs := reflect.ValueOf(s)
+	for reflect.Pointer == rs.Kind() && !rs.IsNil() {
+		rs = rs.Elem()
+	}
+	if reflect.Struct != rs.Kind() {
+		panic("not struct or pointer to struct")
+	}
+
+	m := make(map[string]any)
+	e, put := newEncodeState(enc, m)
+	defer put()
+	e.types = types
+	e.marshal(s, encOpts{})
+	return m
+}
+
+// MakeMap is like the MakeMap function but encodes with enc's configuration.
+func (enc *Encoder) MakeMap(i any) map[string]any {
 	var m map[string]any
-	FillMap(i, &m)
+	enc.FillMap(i, &m)
 	return m
 }
 
-// MakeSlice returns a list of field/value pairs of the struct.
-// See FillMap function's documentation for more information.
-func MakeSlice(i any) []any {
+// MakeSlice is like the MakeSlice function but encodes with enc's configuration.
+func (enc *Encoder) MakeSlice(i any) []any {
 	v := reflect.ValueOf(i)
 	for reflect.Pointer == v.Kind() && !v.IsNil() {
 		v = v.Elem()
@@ -159,12 +344,51 @@ func MakeSlice(i any) []any {
 	}
 
 	var a []any
-	e, put := newEncodeState(a)
+	e, put := newEncodeState(enc, a)
 	defer put()
 	e.marshal(i, encOpts{structConvertToSlice: true})
 	return e.Interface().([]any)
 }
 
+// Keys returns the encoded key names of the struct's fields, in the order
+// of MakeSlice. Fields omitted from the encoding are not included.
+// See FillMap function's documentation for more information.
+func Keys(i any) []string {
+	keys, _ := defaultEncoder.keysValues(i)
+	return keys
+}
+
+// Values returns the encoded values of the struct's fields, in the order
+// of Keys. Nested structs are encoded as maps, as by MakeMap.
+// See FillMap function's documentation for more information.
+func Values(i any) []any {
+	_, values := defaultEncoder.keysValues(i)
+	return values
+}
+
+// keysValues encodes the struct i into key/value pairs, like MakeSlice,
+// but with nested structs encoded as maps.
+func (enc *Encoder) keysValues(i any) ([]string, []any) {
+	v := reflect.ValueOf(i)
+	for reflect.Pointer == v.Kind() && !v.IsNil() {
+		v = v.Elem()
+	}
+	if reflect.Struct != v.Kind() {
+		panic("not struct or pointer to struct")
+	}
+
+	e, put := newEncodeState(enc, []any(nil))
+	defer put()
+	e.marshal(i, encOpts{})
+
+	keys := make([]string, len(e.s)/2)
+	values := make([]any, len(e.s)/2)
+	for i := range keys {
+		keys[i], values[i] = e.s[2*i].(string), e.s[2*i+1]
+	}
+	return keys, values
+}
+
 // An encodeState encodes struct into a map[string]any or []any.
 type encodeState struct {
 	m   map[string]any
@@ -176,25 +400,54 @@ type encodeState struct {
 	// Keep track of what pointers we've seen in the current recursive call
 	// path, to avoid cycles that could lead to a stack overflow. Only do
 	// the relatively expensive map operations if ptrLevel is larger than
-	// startDetectingCyclesAfter, so that we skip the work if we're within a
+	// the Encoder's cycle threshold, so that we skip the work if we're within a
 	// reasonable amount of nested pointers deep.
 	ptrLevel uint
-	ptrSeen  map[any]struct{}
+	ptrSeen  map[any]ptrVisit
+
+	// Dotted key path of the value encoded, for the CycleRef policy.
+	path string
+
+	// Types of the fields by key, recorded for MakeValueMap.
+	types map[string]reflect.Type
+
+	// Go path of the field being encoded, and whether it is selected with
+	// all its fields by WithFields, as for WithOnlyFields on decode.
+	goPath   string
+	selected bool
+
+	// Dotted path of the keys of the field being encoded, before their
+	// renaming by WithKeyRenames.
+	keyPath string
+
+	// The configuration of the encoding.
+	enc *Encoder
 }
 
+// A ptrVisit records the visits of a pointer in the current recursive call path.
+type ptrVisit struct {
+	path  string // of the first visit
+	count int
+}
+
+// startDetectingCyclesAfter is the default cycle threshold of Encoders.
 const startDetectingCyclesAfter = 1000
 
 var encodeStatePool sync.Pool
 
-func newEncodeState(i any) (e *encodeState, put func()) {
+func newEncodeState(enc *Encoder, i any) (e *encodeState, put func()) {
 	if v := encodeStatePool.Get(); v != nil {
 		e = v.(*encodeState)
 		if len(e.ptrSeen) > 0 {
 			panic("ptrEncoder.encode should have emptied ptrSeen via defers")
 		}
 		e.ptrLevel = 0
+		e.path = ""
+		e.types = nil
+		e.goPath, e.selected = "", false
+		e.keyPath = ""
 	} else {
-		e = &encodeState{ptrSeen: make(map[any]struct{})}
+		e = &encodeState{ptrSeen: make(map[any]ptrVisit)}
 	}
 
 	if e.m, e.mOK = i.(map[string]any); !e.mOK {
@@ -212,10 +465,67 @@ func newEncodeState(i any) (e *encodeState, put func()) {
 	if !e.mOK && !e.sOK {
 		panic(fmt.Sprintf("unexpected value type %T", i))
 	}
+	e.enc = enc
 	put = func() { encodeStatePool.Put(e) }
 	return e, put
 }
 
+// nested returns an encodeState encoding into i the value under key of the
+// value e encodes, sharing the cycle detection state of e.
+func (e *encodeState) nested(key string, i any) (*encodeState, func()) {
+	ne, put := newEncodeState(e.enc, i)
+	ptrSeen := ne.ptrSeen
+	ne.ptrLevel, ne.ptrSeen = e.ptrLevel, e.ptrSeen
+	ne.path = joinKey(e.path, key)
+	ne.goPath, ne.selected = e.goPath, e.selected
+	ne.keyPath = e.keyPath
+	return ne, func() {
+		ne.ptrSeen = ptrSeen
+		put()
+	}
+}
+
+// visit records the visit of ptr, identifying the pointer, map or slice v
+// encoded under key. If v repeats on the current recursive call path more
+// times than the Encoder allows, visit applies the Encoder's CyclePolicy
+// and reports false, and v must not be encoded. Otherwise the returned
+// leave must be called once v is encoded.
+func (e *encodeState) visit(key string, v reflect.Value, ptr any) (leave func(), ok bool) {
+	prev, seen := e.ptrSeen[ptr]
+	if seen && prev.count > e.enc.cycleRepeats {
+		switch e.enc.cyclePolicy {
+		case CycleNil:
+			e.setKeyNil(key)
+		case CycleRef:
+			e.setKeyValue(key, map[string]any{"$ref": prev.path})
+		default:
+			e.error(&UnsupportedValueError{v, fmt.Sprintf("encountered a cycle via %s", v.Type())})
+		}
+		return nil, false
+	}
+
+	visit := ptrVisit{path: joinKey(e.path, key), count: 1}
+	if seen {
+		visit = ptrVisit{path: prev.path, count: prev.count + 1}
+	}
+	e.ptrSeen[ptr] = visit
+	return func() {
+		if seen {
+			e.ptrSeen[ptr] = prev
+		} else {
+			delete(e.ptrSeen, ptr)
+		}
+	}, true
+}
+
+// detectCycles reports whether the pointers, maps and slices encoded at the
+// current ptrLevel are checked for cycles.
+func (e *encodeState) detectCycles() bool {
+	// Truncating a cycle must happen at its first repetition, while
+	// panicking can wait for a depth legitimate data rarely reaches.
+	return e.ptrLevel > e.enc.cycleThreshold || e.enc.cyclePolicy != CyclePanic
+}
+
 func (e *encodeState) Interface() any {
 	switch {
 	case e.mOK:
@@ -265,6 +575,68 @@ func (e *encodeState) setKeyValue(key string, elem any) {
 	}
 }
 
+// setLeaf sets key to the leaf value elem, a value that is not built from
+// the fields or elements of the value encoded, after transforming it by the
+// Encoder's WithValueFunc.
+func (e *encodeState) setLeaf(key string, elem any) {
+	if e.enc.valueFunc == nil {
+		e.setKeyValue(key, elem)
+		return
+	}
+	e.transformLeaf(key, elem)
+}
+
+// setLeafNil is like setLeaf for an untyped nil leaf, set as by setKeyNil.
+func (e *encodeState) setLeafNil(key string) {
+	if e.enc.valueFunc == nil {
+		e.setKeyNil(key)
+		return
+	}
+	e.transformLeaf(key, nil)
+}
+
+func (e *encodeState) transformLeaf(key string, elem any) {
+	elem, ok := e.enc.valueFunc(joinKey(e.path, key), elem)
+	switch {
+	case !ok:
+	case elem == nil:
+		e.setKeyNil(key)
+	default:
+		e.setKeyValue(key, elem)
+	}
+}
+
+// setKeyNil sets key to an untyped nil, which setKeyValue omits.
+func (e *encodeState) setKeyNil(key string) {
+	switch {
+	case e.mOK:
+		e.m[key] = nil
+	case e.sOK:
+		e.s = append(e.s, key, nil)
+	}
+}
+
+// setNil sets key to the nil pointer, map, slice or function v,
+// or to an untyped nil or nothing if the configuration asks so.
+func (e *encodeState) setNil(key string, v reflect.Value) {
+	switch {
+	case e.enc.omitNils && !e.enc.complete:
+	case e.enc.jsonCompatible || e.enc.untypedNils:
+		e.setLeafNil(key)
+	default:
+		e.setLeaf(key, v.Interface())
+	}
+}
+
+// setOpaque sets key to v, a value without fields to encode.
+func (e *encodeState) setOpaque(key string, v reflect.Value) {
+	if e.enc.jsonCompatible {
+		e.setLeaf(key, e.jsonOpaque(v))
+		return
+	}
+	e.setLeaf(key, v.Interface())
+}
+
 // An UnsupportedTypeError is returned by MapTo when attempting
 // to encode an unsupported value type.
 type UnsupportedTypeError struct {
@@ -331,6 +703,13 @@ type encOpts struct {
 	inline bool
 	// structConvertToSlice causes struct fields to be encoded inside slice.
 	structConvertToSlice bool
+	// typeName is the name of the struct type held by an interface,
+	// added to its map under the key of WithTypeKey.
+	typeName string
+	// precision is the number of digits after the decimal point of the
+	// big.Float and big.Rat values, if hasPrecision.
+	precision    int
+	hasPrecision bool
 }
 
 type encoderFunc func(*encodeState, string, reflect.Value, encOpts)
@@ -342,11 +721,11 @@ func valueEncoder(v reflect.Value) encoderFunc {
 	return typeEncoder(v.Type())
 }
 
-var encoderCache sync.Map // map[reflect.Type]encoderFunc
+var encoderCache typeCache[encoderFunc]
 
 func typeEncoder(t reflect.Type) encoderFunc {
-	if fi, ok := encoderCache.Load(t); ok {
-		return fi.(encoderFunc)
+	if f, ok := encoderCache.Load(t); ok {
+		return f
 	}
 
 	// To deal with recursive types, populate the map with an
@@ -363,7 +742,7 @@ func typeEncoder(t reflect.Type) encoderFunc {
 		f(e, key, elem, opts)
 	}))
 	if loaded {
-		return fi.(encoderFunc)
+		return fi
 	}
 
 	// Compute the real encoder and replace the indirect func with it.
@@ -376,16 +755,36 @@ func typeEncoder(t reflect.Type) encoderFunc {
 // newTypeEncoder constructs an encoderFunc for a type.
 // The returned encoder only checks CanAddr when allowAddr is true.
 func newTypeEncoder(t reflect.Type) encoderFunc {
+	if enc := newExportEncoder(t); enc != nil {
+		return enc
+	}
+	return newDefaultEncoder(t)
+}
+
+// newDefaultEncoder is newTypeEncoder ignoring the exporter methods of t.
+func newDefaultEncoder(t reflect.Type) encoderFunc {
+	if enc := newTextEncoder(t); enc != nil {
+		return enc
+	}
+	if isBigType(t) {
+		return bigEncoder
+	}
 	switch t.Kind() {
 	case reflect.Bool,
 		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
 		reflect.Float32, reflect.Float64,
 		reflect.String:
+		if t.PkgPath() == "" {
+			return predeclaredEncoder
+		}
 		return primitiveEncoder
 	case reflect.Interface:
 		return interfaceEncoder
 	case reflect.Struct:
+		if enc := newProtoEncoder(t); enc != nil {
+			return enc
+		}
 		return newStructEncoder(t)
 	case reflect.Map:
 		return newMapEncoder(t)
@@ -395,6 +794,10 @@ func newTypeEncoder(t reflect.Type) encoderFunc {
 		return newArrayEncoder(t)
 	case reflect.Pointer:
 		return newPtrEncoder(t)
+	case reflect.Func:
+		return funcEncoder
+	case reflect.Chan:
+		return newChanEncoder(t)
 	default:
 		return unsupportedTypeEncoder
 	}
@@ -406,20 +809,162 @@ func invalidValueEncoder(e *encodeState, key string, _ reflect.Value, _ encOpts)
 }
 
 func primitiveEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
-	if opts.quoted {
-		e.setKeyValue(key, strconv.Quote(fmt.Sprint(v)))
-	} else {
-		e.setKeyValue(key, v.Interface())
+	switch {
+	case opts.quoted:
+		e.setLeaf(key, strconv.Quote(fmt.Sprint(v)))
+	case e.enc.jsonCompatible:
+		e.setLeaf(key, e.jsonPrimitive(v))
+	case e.enc.widenNumbers:
+		e.setLeaf(key, widenNumber(v))
+	default:
+		e.setLeaf(key, v.Interface())
+	}
+}
+
+// predeclaredEncoder is primitiveEncoder for the predeclared boolean,
+// numeric and string types. For the fields of a struct encoded through a
+// pointer, v.Interface() copies the field into a new allocation; boxing the
+// results of the typed getters instead lets the runtime avoid allocating
+// booleans, small integers, zeros and empty strings.
+func predeclaredEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
+	if opts.quoted || e.enc.jsonCompatible || e.enc.widenNumbers || !v.CanAddr() {
+		primitiveEncoder(e, key, v, opts)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		e.setLeaf(key, v.Bool())
+	case reflect.Int:
+		e.setLeaf(key, int(v.Int()))
+	case reflect.Int8:
+		e.setLeaf(key, int8(v.Int()))
+	case reflect.Int16:
+		e.setLeaf(key, int16(v.Int()))
+	case reflect.Int32:
+		e.setLeaf(key, int32(v.Int()))
+	case reflect.Int64:
+		e.setLeaf(key, v.Int())
+	case reflect.Uint:
+		e.setLeaf(key, uint(v.Uint()))
+	case reflect.Uint8:
+		e.setLeaf(key, uint8(v.Uint()))
+	case reflect.Uint16:
+		e.setLeaf(key, uint16(v.Uint()))
+	case reflect.Uint32:
+		e.setLeaf(key, uint32(v.Uint()))
+	case reflect.Uint64:
+		e.setLeaf(key, v.Uint())
+	case reflect.Uintptr:
+		e.setLeaf(key, uintptr(v.Uint()))
+	case reflect.Float32:
+		e.setLeaf(key, float32(v.Float()))
+	case reflect.Float64:
+		e.setLeaf(key, v.Float())
+	case reflect.String:
+		e.setLeaf(key, v.String())
 	}
 }
 
 func interfaceEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
-	if !v.IsNil() {
-		valueEncoder(v.Elem())(e, key, v.Elem(), opts)
+	if v.IsNil() {
+		if e.enc.nilInterfaces {
+			e.setLeafNil(key)
+		}
+		return
+	}
+	elem := v.Elem()
+	if key != "" && e.enc.roundTrip {
+		// The dynamic type of a value encoded into a map would be lost.
+		e.setLeaf(key, elem.Interface())
+		return
 	}
+	if key != "" && !opts.quoted && e.enc.isOpaqueStruct(elem.Type()) && !e.enc.emptyStructMap(elem.Type()) {
+		// A struct without exported fields, such as the *os.File held
+		// by an io.Reader, is stored as the dynamic value itself.
+		e.setOpaque(key, elem)
+		return
+	}
+	if e.enc.typeKey != "" && key != "" {
+		if t := indirectType(elem.Type()); reflect.Struct == t.Kind() {
+			opts.typeName = typeName(t)
+		}
+	}
+	valueEncoder(elem)(e, key, elem, opts)
 }
 
-func unsupportedTypeEncoder(e *encodeState, key string, elem reflect.Value, _ encOpts) {
+// indirectType returns the type t points to, at any depth.
+func indirectType(t reflect.Type) reflect.Type {
+	for reflect.Pointer == t.Kind() {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isOpaqueStruct reports whether t is a struct, or pointer to struct,
+// without fields to encode.
+func (enc *Encoder) isOpaqueStruct(t reflect.Type) bool {
+	for reflect.Pointer == t.Kind() {
+		t = t.Elem()
+	}
+	return reflect.Struct == t.Kind() && len(enc.structFields(t).list) == 0
+}
+
+// funcEncoder encodes a function as its name if the Encoder is configured
+// by WithFuncNames.
+func funcEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
+	if !e.enc.funcNames {
+		unsupportedTypeEncoder(e, key, v, opts)
+		return
+	}
+	if v.IsNil() {
+		e.setNil(key, v)
+		return
+	}
+	name := "func"
+	if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+		name = fn.Name()
+	}
+	e.setLeaf(key, name)
+}
+
+// newChanEncoder returns the encoder of the channel type t, draining the
+// channels into slices if the Encoder is configured by WithDrainChannels.
+func newChanEncoder(t reflect.Type) encoderFunc {
+	if t.ChanDir()&reflect.RecvDir == 0 {
+		return unsupportedTypeEncoder
+	}
+	st := reflect.SliceOf(t.Elem())
+	return func(e *encodeState, key string, v reflect.Value, opts encOpts) {
+		if e.enc.drainChannels == 0 {
+			unsupportedTypeEncoder(e, key, v, opts)
+			return
+		}
+		if v.IsNil() {
+			e.setNil(key, v)
+			return
+		}
+		n := v.Len()
+		if e.enc.drainChannels > 0 && n > e.enc.drainChannels {
+			n = e.enc.drainChannels
+		}
+		elems := reflect.MakeSlice(st, 0, n)
+		for i := 0; i < n; i++ {
+			x, ok := v.TryRecv()
+			if !ok {
+				break
+			}
+			elems = reflect.Append(elems, x)
+		}
+		typeEncoder(st)(e, key, elems, opts)
+	}
+}
+
+func unsupportedTypeEncoder(e *encodeState, key string, elem reflect.Value, opts encOpts) {
+	if e.enc.fallbacks {
+		fallbackEncoder(e, key, elem, opts)
+		return
+	}
 	e.error(&UnsupportedTypeError{elem.Type(), key})
 }
 
@@ -432,12 +977,27 @@ type structFields struct {
 }
 
 func (se structEncoder) encode(e *encodeState, key string, v reflect.Value, opts encOpts) {
-	if len(se.fields.list) == 0 {
+	v, fields := e.enc.fieldsToEncode(v, se.fields)
+	if len(fields.list) == 0 {
 		if key != "" && !opts.inline {
-			if opts.quoted {
-				e.setKeyValue(key, strconv.Quote(fmt.Sprint(v)))
-			} else {
-				e.setKeyValue(key, v.Interface())
+			switch {
+			case opts.quoted:
+				e.setLeaf(key, strconv.Quote(fmt.Sprint(v)))
+			case e.enc.emptyStructMap(v.Type()):
+				switch {
+				case opts.structConvertToSlice && opts.typeName != "":
+					e.setKeyValue(key, []any{e.enc.typeKey, opts.typeName})
+				case opts.structConvertToSlice:
+					e.setKeyValue(key, []any{})
+				case opts.typeName != "":
+					m := e.enc.newMap(1)
+					m[e.enc.typeKey] = opts.typeName
+					e.setKeyValue(key, m)
+				default:
+					e.setKeyValue(key, e.enc.newMap(0))
+				}
+			default:
+				e.setOpaque(key, v)
 			}
 		}
 		return
@@ -451,40 +1011,214 @@ func (se structEncoder) encode(e *encodeState, key string, v reflect.Value, opts
 		if opts.structConvertToSlice {
 			i = []any(nil)
 		} else {
-			i = make(map[string]any)
+			i = e.enc.newMap(0)
 		}
-		e, put := newEncodeState(i)
+		e, put := e.nested(key, i)
 		defer put()
 		ne = e
+		if opts.typeName != "" {
+			ne.setKeyValue(e.enc.typeKey, opts.typeName)
+		}
 	}
+	opts.typeName = ""
 
-FieldLoop:
-	for i := range se.fields.list {
-		f := &se.fields.list[i]
+	goPath, selected, keyPath := ne.goPath, ne.selected, ne.keyPath
+	for i := range fields.list {
+		ne.encodeField(v, &fields.list[i], goPath, selected, keyPath, opts)
+	}
+	ne.goPath, ne.selected, ne.keyPath = goPath, selected, keyPath
+	if e != ne {
+		e.setKeyValue(key, ne.Interface())
+	}
+}
 
-		// Find the nested struct field by following f.index.
-		fv := v
-		for _, i := range f.index {
-			if reflect.Pointer == fv.Kind() {
-				if fv.IsNil() {
-					continue FieldLoop
-				}
-				fv = fv.Elem()
-			}
-			fv = fv.Field(i)
+// encodeField encodes into e, the encodeState of the struct v, the field f
+// of v with its options, unless the field or its value is omitted. The
+// goPath, selected and keyPath are those of the struct, which e is left
+// with the values of the field's; the caller restores them.
+// LazyMap encodes its fields with it too, so that they are those of MakeMap.
+func (e *encodeState) encodeField(v reflect.Value, f *field, goPath string, selected bool, keyPath string, opts encOpts) {
+	if f.decodeOnly || !e.enc.activeField(f) || e.enc.ignoredField(v.Type(), f) {
+		return
+	}
+
+	fv, ok := f.value(v)
+	if !ok {
+		return
+	}
+
+	if e.enc.nonZero && e.path == "" {
+		// The top-level fields of Struct.NonZeroMap.
+		if fv.IsZero() {
+			return
 		}
+	} else if f.omitEmpty && !e.enc.complete && isEmptyValue(fv) {
+		return
+	}
 
-		if f.omitEmpty && isEmptyValue(fv) {
-			continue
+	key, enc := e.enc.fieldKey(f), f.encoder
+	if e.enc.keyRenames != nil {
+		key = e.renamedKey(keyPath, key, f)
+	}
+	if path := joinKey(e.path, key); e.enc.filtered(path, f) || e.enc.omitted(path, f, v, fv) {
+		return
+	}
+	if e.enc.projected() {
+		e.goPath, e.selected = joinKey(goPath, f.sf.Name), selected
+		if !e.projectedField() {
+			return
 		}
+	}
+	if e.types != nil && !f.inline {
+		e.types[key] = fv.Type()
+	}
 
-		opts.quoted = f.quoted
-		opts.inline = f.inline
-		f.encoder(ne, f.name, fv, opts)
+	opts.quoted = f.quoted
+	opts.inline = f.inline
+	opts.precision, opts.hasPrecision = f.precision, f.hasPrecision
+	switch {
+	case f.inline:
+	case f.asJSON:
+		b, err := json.Marshal(fv.Interface())
+		if err != nil {
+			e.error(fmt.Errorf("structof: field %q: %w", joinKey(e.path, key), err))
+		}
+		fv, enc = reflect.ValueOf(string(b)), primitiveEncoder
+	case f.stringer && !e.enc.roundTrip:
+		if sv, ok := stringerValue(fv); ok {
+			fv, enc = sv, primitiveEncoder
+		}
 	}
-	if e != ne {
-		e.setKeyValue(key, ne.Interface())
+	if f.indexMap && !e.enc.roundTrip {
+		e.indexMapField(key, f, fv, opts)
+		return
+	}
+	if len(f.transforms) > 0 && !f.inline && !e.enc.roundTrip {
+		e.transformField(key, f, fv, opts)
+		return
+	}
+	enc(e, key, fv, opts)
+}
+
+// fieldsToEncode returns the fields of the struct v that enc encodes, given
+// those of its type, and v, copied if its unexported fields are read.
+func (enc *Encoder) fieldsToEncode(v reflect.Value, fields structFields) (reflect.Value, structFields) {
+	if !enc.unexported && !enc.tagless {
+		return v, fields
+	}
+	fields = enc.structFields(v.Type())
+	if enc.unexported && !v.CanAddr() {
+		// The unexported fields are read through their address.
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	return v, fields
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// stringerValue returns the result of the String method of v as a Value,
+// or false if v does not implement fmt.Stringer or is a nil pointer or
+// interface.
+func stringerValue(v reflect.Value) (reflect.Value, bool) {
+	if reflect.Interface == v.Kind() {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if !v.Type().Implements(stringerType) {
+		if !v.CanAddr() || !reflect.PointerTo(v.Type()).Implements(stringerType) {
+			return reflect.Value{}, false
+		}
+		v = v.Addr()
+	}
+	if reflect.Pointer == v.Kind() && v.IsNil() {
+		return reflect.Value{}, false
 	}
+	return reflect.ValueOf(v.Interface().(fmt.Stringer).String()), true
+}
+
+// transformField encodes under key the value of the field f, fv,
+// transformed by the Transformers of f.
+func (e *encodeState) transformField(key string, f *field, fv reflect.Value, opts encOpts) {
+	x, err := transform(f.transforms, fv.Interface())
+	if err != nil {
+		e.error(fmt.Errorf("structof: field %q: %w", joinKey(e.path, key), err))
+	}
+	if x == nil {
+		e.setLeafNil(key)
+		return
+	}
+	v := reflect.ValueOf(x)
+	typeEncoder(v.Type())(e, key, v, opts)
+}
+
+// indexMapField encodes the slice or array fv of the field f as a map keyed
+// by the index of its elements, or by the value of their f.keyBy key.
+func (e *encodeState) indexMapField(key string, f *field, fv reflect.Value, opts encOpts) {
+	if reflect.Pointer == fv.Kind() {
+		if fv.IsNil() {
+			e.setNil(key, fv)
+			return
+		}
+		fv = fv.Elem()
+	}
+	if reflect.Slice == fv.Kind() && fv.IsNil() {
+		e.setNil(key, fv)
+		return
+	}
+
+	n := fv.Len()
+	m := e.enc.newMap(n)
+	ne, put := e.nested(key, m)
+	defer put()
+	elemEnc := typeEncoder(fv.Type().Elem())
+	for i := 0; i < n; i++ {
+		elemEnc(ne, strconv.Itoa(i), fv.Index(i), opts)
+	}
+	if f.keyBy == "" {
+		e.setKeyValue(key, m)
+		return
+	}
+
+	km := e.enc.newMap(len(m))
+	for i := 0; i < n; i++ {
+		elem, ok := m[strconv.Itoa(i)]
+		if !ok {
+			// Omitted, as a nil interface.
+			continue
+		}
+		k, ok := lookupKey(elem, f.keyBy)
+		if !ok {
+			e.error(fmt.Errorf("structof: field %q: element %d has no key %q", joinKey(e.path, key), i, f.keyBy))
+		}
+		ks := fmt.Sprint(k)
+		if _, dup := km[ks]; dup {
+			e.error(fmt.Errorf("structof: field %q: duplicate %s %q", joinKey(e.path, key), f.keyBy, ks))
+		}
+		km[ks] = elem
+	}
+	e.enc.putMap(m)
+	e.setKeyValue(key, km)
+}
+
+// lookupKey returns the value of key in the encoded struct x,
+// a map or a slice of key/value pairs.
+func lookupKey(x any, key string) (any, bool) {
+	switch x := x.(type) {
+	case map[string]any:
+		v, ok := x[key]
+		return v, ok
+	case []any:
+		for i := 0; i+1 < len(x); i += 2 {
+			if x[i] == key {
+				return x[i+1], true
+			}
+		}
+	}
+	return nil, false
 }
 
 func newStructEncoder(t reflect.Type) encoderFunc {
@@ -498,46 +1232,52 @@ type mapEncoder struct {
 
 func (me mapEncoder) encode(e *encodeState, key string, v reflect.Value, opts encOpts) {
 	if v.IsNil() {
-		e.setKeyValue(key, v.Interface())
+		if e.enc.uniformMaps && e.enc.typedNils() {
+			e.setLeaf(key, map[string]any(nil))
+			return
+		}
+		e.setNil(key, v)
 		return
 	}
 
-	if e.ptrLevel++; e.ptrLevel > startDetectingCyclesAfter {
-		// We're a large number of nested ptrEncoder.encode calls deep;
+	if e.ptrLevel++; e.detectCycles() {
+		// We're a large number of nested ptrEncoder.encode calls deep,
+		// or the CyclePolicy must cut the first repetition;
 		// start checking if we've run into a pointer cycle.
-		ptr := v.UnsafePointer()
-		if _, ok := e.ptrSeen[ptr]; ok {
-			e.error(&UnsupportedValueError{v, fmt.Sprintf("encountered a cycle via %s", v.Type())})
+		leave, ok := e.visit(key, v, v.UnsafePointer())
+		if !ok {
+			e.ptrLevel--
+			return
 		}
-		e.ptrSeen[ptr] = struct{}{}
-		defer delete(e.ptrSeen, ptr)
+		defer leave()
 	}
 
 	// Extract keys and values.
-	m := make(map[string]any, v.Len())
-	ne, put := newEncodeState(m)
+	m := e.enc.newMap(v.Len())
+	ne, put := e.nested(key, m)
 	defer put()
 
 	for mi := v.MapRange(); mi.Next(); {
 		me.elemEnc(ne, mi.Key().String(), mi.Value(), opts)
 	}
 
-	elemType := v.Type().Elem()
-	if elemType.Kind() == reflect.Pointer {
-		elemType = elemType.Elem()
+	mt := v.Type()
+	if et := e.enc.widenType(mt.Elem()); et != mt.Elem() {
+		mt = reflect.MapOf(mt.Key(), et)
 	}
-	if elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Array {
-		elemType = elemType.Elem()
-	}
-
-	if elemType.Kind() == reflect.Struct {
+	// The values of interface elements are encoded into arbitrary shapes,
+	// so that a map of them, such as a named map[string]any, is emitted as
+	// a map[string]any.
+	if hasStructElem(mt.Elem()) || reflect.Interface == mt.Elem().Kind() ||
+		e.enc.jsonCompatible || e.enc.uniformMaps || !allAssignable(mt.Elem(), m) {
 		e.setKeyValue(key, m)
 	} else {
-		vm := reflect.MakeMapWithSize(v.Type(), v.Len())
-		for k, e := range m {
-			vm.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(e))
+		vm := reflect.MakeMapWithSize(mt, len(m))
+		for k, elem := range m {
+			vm.SetMapIndex(reflect.ValueOf(k).Convert(mt.Key()), valueOrZero(mt.Elem(), elem))
 		}
 		e.setKeyValue(key, vm.Interface())
+		e.enc.putMap(m)
 	}
 
 	e.ptrLevel--
@@ -560,12 +1300,22 @@ type sliceEncoder struct {
 
 func (se sliceEncoder) encode(e *encodeState, key string, v reflect.Value, opts encOpts) {
 	if v.IsNil() {
-		e.setKeyValue(key, v.Interface())
+		if e.enc.uniformSlice(v.Type()) && e.enc.typedNils() {
+			e.setLeaf(key, []any(nil))
+			return
+		}
+		e.setNil(key, v)
+		return
+	}
+	if e.enc.jsonCompatible && reflect.Uint8 == v.Type().Elem().Kind() {
+		// As in encoding/json, byte slices encode as base64 strings.
+		e.setLeaf(key, base64.StdEncoding.EncodeToString(v.Bytes()))
 		return
 	}
 
-	if e.ptrLevel++; e.ptrLevel > startDetectingCyclesAfter {
-		// We're a large number of nested ptrEncoder.encode calls deep;
+	if e.ptrLevel++; e.detectCycles() {
+		// We're a large number of nested ptrEncoder.encode calls deep,
+		// or the CyclePolicy must cut the first repetition;
 		// start checking if we've run into a pointer cycle.
 		// Here we use a struct to memorize the pointer to the first element of the slice
 		// and its length.
@@ -573,11 +1323,12 @@ func (se sliceEncoder) encode(e *encodeState, key string, v reflect.Value, opts
 			ptr interface{} // always an unsafe.Pointer, but avoids a dependency on package unsafe
 			len int
 		}{v.UnsafePointer(), v.Len()}
-		if _, ok := e.ptrSeen[ptr]; ok {
-			e.error(&UnsupportedValueError{v, fmt.Sprintf("encountered a cycle via %s", v.Type())})
+		leave, ok := e.visit(key, v, ptr)
+		if !ok {
+			e.ptrLevel--
+			return
 		}
-		e.ptrSeen[ptr] = struct{}{}
-		defer delete(e.ptrSeen, ptr)
+		defer leave()
 	}
 	opts.convertToSlice = true
 	se.arrayEnc(e, key, v, opts)
@@ -597,12 +1348,16 @@ var anyType = reflect.TypeOf((*any)(nil)).Elem()
 
 func (ae arrayEncoder) encode(e *encodeState, key string, v reflect.Value, opts encOpts) {
 	s := make([]any, 0, v.Len()*2)
-	ne, put := newEncodeState(s)
+	ne, put := e.nested(key, s)
 	defer put()
 
+	// The conversion of a slice to a slice applies to itself only,
+	// the arrays it holds are kept as arrays.
+	elemOpts := opts
+	elemOpts.convertToSlice = false
 	n := v.Len()
 	for i := 0; i < n; i++ {
-		ae.elemEnc(ne, strconv.Itoa(i), v.Index(i), opts)
+		ae.elemEnc(ne, strconv.Itoa(i), v.Index(i), elemOpts)
 	}
 	s = ne.s
 
@@ -611,23 +1366,79 @@ func (ae arrayEncoder) encode(e *encodeState, key string, v reflect.Value, opts
 		elemType = elemType.Elem()
 	}
 
-	var a reflect.Value
-	if elemType.Kind() == reflect.Struct {
-		a = reflect.New(reflect.ArrayOf(v.Len(), anyType)).Elem()
-	} else {
-		a = reflect.New(reflect.ArrayOf(v.Len(), elemType)).Elem()
+	elemType = e.enc.widenType(elemType)
+	if hasStructElem(elemType) || e.enc.jsonCompatible || e.enc.uniformSlice(v.Type()) {
+		elemType = anyType
 	}
-	for i := 0; i < a.Len(); i++ {
-		a.Index(i).Set(reflect.ValueOf(s[i*2+1]))
+	for j := 1; j < len(s); j += 2 {
+		if !assignable(elemType, s[j]) {
+			elemType = anyType
+			break
+		}
 	}
 
-	if opts.convertToSlice {
+	a := reflect.New(reflect.ArrayOf(v.Len(), elemType)).Elem()
+	// Elements may be omitted, such as nil interfaces,
+	// so place them by the index recorded as their key.
+	for j := 0; j+1 < len(s); j += 2 {
+		i, _ := strconv.Atoi(s[j].(string))
+		a.Index(i).Set(valueOrZero(elemType, s[j+1]))
+	}
+
+	if opts.convertToSlice || e.enc.jsonCompatible || e.enc.uniformSlice(v.Type()) {
 		e.setKeyValue(key, a.Slice(0, a.Len()).Interface())
 	} else {
 		e.setKeyValue(key, a.Interface())
 	}
 }
 
+// hasStructElem reports whether t is a struct, or has struct elements
+// through pointers, slices, arrays and maps, at any depth, so that the
+// collections of values of type t hold their encoded maps.
+func hasStructElem(t reflect.Type) bool {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// assignable reports whether the encoded value x can be stored
+// in a collection with element type t.
+func assignable(t reflect.Type, x any) bool {
+	if x == nil {
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+			return true
+		}
+		return false
+	}
+	return reflect.TypeOf(x).AssignableTo(t)
+}
+
+// allAssignable reports whether all the values of m are assignable to t.
+func allAssignable(t reflect.Type, m map[string]any) bool {
+	for _, x := range m {
+		if !assignable(t, x) {
+			return false
+		}
+	}
+	return true
+}
+
+// valueOrZero returns the Value of x, or the zero Value of t if x is nil.
+func valueOrZero(t reflect.Type, x any) reflect.Value {
+	if x == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(x)
+}
+
 func newArrayEncoder(t reflect.Type) encoderFunc {
 	enc := arrayEncoder{typeEncoder(t.Elem())}
 	return enc.encode
@@ -639,18 +1450,19 @@ type ptrEncoder struct {
 
 func (pe ptrEncoder) encode(e *encodeState, key string, v reflect.Value, opts encOpts) {
 	if v.IsNil() {
-		e.setKeyValue(key, v.Interface())
+		e.setNil(key, v)
 		return
 	}
-	if e.ptrLevel++; e.ptrLevel > startDetectingCyclesAfter {
-		// We're a large number of nested ptrEncoder.encode calls deep;
+	if e.ptrLevel++; e.detectCycles() {
+		// We're a large number of nested ptrEncoder.encode calls deep,
+		// or the CyclePolicy must cut the first repetition;
 		// start checking if we've run into a pointer cycle.
-		ptr := v.Interface()
-		if _, ok := e.ptrSeen[ptr]; ok {
-			e.error(&UnsupportedValueError{v, fmt.Sprintf("encountered a cycle via %s", v.Type())})
+		leave, ok := e.visit(key, v, v.Interface())
+		if !ok {
+			e.ptrLevel--
+			return
 		}
-		e.ptrSeen[ptr] = struct{}{}
-		defer delete(e.ptrSeen, ptr)
+		defer leave()
 	}
 	pe.elemEnc(e, key, v.Elem(), opts)
 	e.ptrLevel--
@@ -678,6 +1490,47 @@ func isValidTag(s string) bool {
 	return true
 }
 
+// optionValue returns the value of the option "name=value" in opts
+// and a boolean indicating if the option was found.
+func optionValue(opts structtag.TagOptions, name string) (string, bool) {
+	s := string(opts)
+	for s != "" {
+		var opt string
+		opt, s, _ = strings.Cut(s, ",")
+		if k, v, ok := strings.Cut(opt, "="); ok && k == name {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// parseGroups returns the non-empty group names listed in the "groups" option of opts.
+func parseGroups(opts structtag.TagOptions) []string {
+	v, _ := optionValue(opts, "groups")
+	var groups []string
+	for _, g := range strings.Split(v, "|") {
+		if g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// parseAliases returns the valid key names listed in the "alias" option of opts.
+func parseAliases(opts structtag.TagOptions) []string {
+	v, ok := optionValue(opts, "alias")
+	if !ok {
+		return nil
+	}
+	var aliases []string
+	for _, alias := range strings.Split(v, "|") {
+		if isValidTag(alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
 func typeByIndex(t reflect.Type, index []int) reflect.Type {
 	for _, i := range index {
 		if reflect.Pointer == t.Kind() {
@@ -698,8 +1551,94 @@ type field struct {
 	omitEmpty bool
 	quoted    bool
 	inline    bool
+	stringer  bool
+	asJSON    bool
+
+	// Whether the field is ignored by decoding or by encoding.
+	encodeOnly bool
+	decodeOnly bool
+
+	// Alternative key names accepted on decode.
+	aliases []string
+
+	// Value of the "default" option, used on decode by WithDefaults.
+	defaultValue string
+	hasDefault   bool
+
+	// Names of the Transformers run on the value.
+	transforms []string
+
+	// Values of the "since" and "until" options, used on encode by WithVersion.
+	since, until string
+
+	// Whether the slice or array is encoded as a map keyed by the index of
+	// its elements, or by their keyBy key if set.
+	indexMap bool
+	keyBy    string
+
+	// Key of the element naming the type of the value of an interface
+	// field, on decode.
+	typeKey string
+
+	// Values of the "groups" option, used on encode by WithGroups.
+	groups []string
+
+	// Value of the "precision" option of big.Float and big.Rat fields.
+	precision    int
+	hasPrecision bool
+
+	// Value of the "order" option, overriding the declaration order.
+	order    int
+	hasOrder bool
+
+	// Name of the method of the "method" option of a blank field,
+	// called for the value of the field.
+	method string
 
 	encoder encoderFunc
+
+	// value returns the field of a struct value,
+	// or false if a nil embedded pointer holds it.
+	value func(v reflect.Value) (reflect.Value, bool)
+
+	sf reflect.StructField
+}
+
+// fieldValue returns the function finding the nested field at index of
+// a struct value, specialized for fields of the struct itself.
+func fieldValue(index []int) func(reflect.Value) (reflect.Value, bool) {
+	if len(index) == 1 {
+		i := index[0]
+		return func(v reflect.Value) (reflect.Value, bool) {
+			return v.Field(i), true
+		}
+	}
+	return func(v reflect.Value) (reflect.Value, bool) {
+		for _, i := range index {
+			if reflect.Pointer == v.Kind() {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+			v = v.Field(i)
+		}
+		return v, true
+	}
+}
+
+// lookup returns the element of m for f, under its name or one of its
+// aliases, with the key it was found under.
+func (f *field) lookup(m map[string]any) (elem any, key string, ok bool) {
+	if elem, ok = m[f.name]; ok {
+		return elem, f.name, true
+	}
+	for _, alias := range f.aliases {
+		if elem, ok = m[alias]; ok {
+			return elem, alias, true
+		}
+	}
+	return nil, "", false
 }
 
 // byIndex sorts field by index sequence.
@@ -724,7 +1663,10 @@ func (x byIndex) Less(i, j int) bool {
 // typeFields returns a list of fields that the package should recognize for the given type.
 // The algorithm is breadth-first search over the set of structs to include - the top struct
 // and then any reachable anonymous structs.
-func typeFields(t reflect.Type) structFields {
+//
+// If unexported is set, the unexported fields are included as well.
+// If tagless is set, the structof tags are ignored, as if the fields had none.
+func typeFields(t reflect.Type, unexported, tagless bool) structFields {
 	// Anonymous fields to explore at the current level and the next.
 	current := []field{}
 	next := []field{{typ: t}}
@@ -758,20 +1700,23 @@ func typeFields(t reflect.Type) structFields {
 					if reflect.Pointer == ft.Kind() {
 						ft = ft.Elem()
 					}
-					if !sf.IsExported() && reflect.Struct != ft.Kind() {
+					if !sf.IsExported() && reflect.Struct != ft.Kind() && !unexported {
 						// Ignore embedded fields of unexported non-struct types.
 						continue
 					}
 
 					// Do not ignore embedded fields of unexported struct types
 					// since they may have exported fields.
-				} else if !sf.IsExported() {
+				} else if !sf.IsExported() && !unexported && (tagless || !isMethodField(sf)) {
 					// Ignore unexported non-embedded fields.
 					continue
 				}
 				hasExported = true
 
-				tag, _ := structtag.StructTag(sf.Tag).Lookup("structof")
+				var tag structtag.Tag
+				if !tagless {
+					tag, _ = structtag.StructTag(sf.Tag).Lookup("structof")
+				}
 				if tag.String() == `structof:"-"` {
 					continue
 				}
@@ -807,7 +1752,7 @@ func typeFields(t reflect.Type) structFields {
 
 				// Only structs can be inline.
 				inline := false
-				if opts.Contains("inline") {
+				if opts.Contains("inline") || opts.Contains("squash") {
 					switch ft.Kind() {
 					case reflect.Struct:
 						inline = true
@@ -829,6 +1774,36 @@ func typeFields(t reflect.Type) structFields {
 						omitEmpty: opts.Contains("omitempty"),
 						quoted:    quoted,
 						inline:    inline,
+						stringer:  opts.Contains("stringer"),
+						asJSON:    opts.Contains("json"),
+						aliases:   parseAliases(opts),
+					}
+					field.defaultValue, field.hasDefault = optionValue(opts, "default")
+					field.encodeOnly = opts.Contains("encodeonly")
+					field.decodeOnly = opts.Contains("decodeonly")
+					if sf.Name == "_" {
+						field.method, _ = optionValue(opts, "method")
+						field.encodeOnly = field.encodeOnly || field.method != ""
+					}
+					field.transforms = parseTransforms(opts)
+					field.since, _ = optionValue(opts, "since")
+					field.until, _ = optionValue(opts, "until")
+					field.groups = parseGroups(opts)
+					if v, ok := optionValue(opts, "precision"); ok {
+						precision, err := strconv.Atoi(v)
+						field.precision, field.hasPrecision = precision, err == nil && precision >= 0
+					}
+					if v, ok := optionValue(opts, "order"); ok {
+						order, err := strconv.Atoi(v)
+						field.order, field.hasOrder = order, err == nil
+					}
+					if reflect.Interface == ft.Kind() {
+						field.typeKey, _ = optionValue(opts, "typekey")
+					}
+					if reflect.Slice == ft.Kind() || reflect.Array == ft.Kind() {
+						// Only slices and arrays can be indexed.
+						field.keyBy, _ = optionValue(opts, "keyby")
+						field.indexMap = field.keyBy != "" || opts.Contains("indexmap")
 					}
 
 					fields = append(fields, field)
@@ -858,11 +1833,15 @@ func typeFields(t reflect.Type) structFields {
 						omitEmpty: opts.Contains("omitempty"),
 						quoted:    quoted,
 						inline:    inline,
+						aliases:   parseAliases(opts),
 					})
 				}
 			}
 
-			if !hasExported && f.name != "" && reflect.Struct == f.typ.Kind() {
+			// An embedded struct without fields to include is stored as is,
+			// unless unexported, its value being unreadable.
+			if !hasExported && f.name != "" && reflect.Struct == f.typ.Kind() &&
+				(unexported || t.FieldByIndex(f.index).IsExported()) {
 				field := f
 				fields = append(fields, field)
 				if count[f.typ] > 1 {
@@ -924,11 +1903,90 @@ func typeFields(t reflect.Type) structFields {
 	fields = out
 	sort.Sort(byIndex(fields))
 
-	for i := range fields {
-		f := &fields[i]
+	// The fields with an "order" option come first, by increasing weight.
+	sort.SliceStable(fields, func(i, j int) bool {
+		x := fields
+		if x[i].hasOrder != x[j].hasOrder {
+			return x[i].hasOrder
+		}
+		return x[i].order < x[j].order
+	})
+
+	out = fields[:0]
+	for _, f := range fields {
+		f.sf = t.FieldByIndex(f.index)
+		if f.method != "" {
+			// The methods not taking and returning one value are ignored.
+			if !methodField(t, &f) {
+				continue
+			}
+			out = append(out, f)
+			continue
+		}
 		f.encoder = typeEncoder(typeByIndex(t, f.index))
+		f.value = fieldValue(f.index)
+		if unexported {
+			f.value = readableValue(f.value)
+		}
+		out = append(out, f)
+	}
+	return structFields{out}
+}
+
+// isMethodField reports whether sf is a blank field with a "method" option.
+func isMethodField(sf reflect.StructField) bool {
+	if sf.Name != "_" {
+		return false
 	}
-	return structFields{fields}
+	tag, _ := structtag.StructTag(sf.Tag).Lookup("structof")
+	_, ok := optionValue(tag.Options, "method")
+	return ok
+}
+
+// methodField sets the type, encoder and value of the field f of the struct
+// type t with the "method" option to those of the result of the method of
+// the struct holding f, reporting whether it takes no argument and returns
+// one value.
+func methodField(t reflect.Type, f *field) bool {
+	parent := f.index[:len(f.index)-1]
+	pt := indirectType(typeByIndex(t, parent))
+	m, ok := pt.MethodByName(f.method)
+	pointer := !ok
+	if pointer {
+		m, ok = reflect.PointerTo(pt).MethodByName(f.method)
+	}
+	if !ok || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+		return false
+	}
+
+	f.typ = m.Type.Out(0)
+	f.encoder = typeEncoder(f.typ)
+	parentValue := func(v reflect.Value) (reflect.Value, bool) { return v, true }
+	if len(parent) > 0 {
+		parentValue = fieldValue(parent)
+	}
+	f.value = func(v reflect.Value) (reflect.Value, bool) {
+		pv, ok := parentValue(v)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		for reflect.Pointer == pv.Kind() {
+			if pv.IsNil() {
+				return reflect.Value{}, false
+			}
+			pv = pv.Elem()
+		}
+		if pointer {
+			if !pv.CanAddr() {
+				c := reflect.New(pv.Type()).Elem()
+				c.Set(pv)
+				pv = c
+			}
+			pv = pv.Addr()
+		}
+		return pv.Method(m.Index).Call(nil)[0], true
+	}
+	return true
 }
 
 // dominantField looks through the fields, all of which are known to
@@ -947,13 +2005,13 @@ func dominantField(fields []field) (field, bool) {
 	return fields[0], true
 }
 
-var fieldCache sync.Map // map[reflect.Type]structFields
+var fieldCache typeCache[structFields]
 
 // cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
 func cachedTypeFields(t reflect.Type) structFields {
 	if f, ok := fieldCache.Load(t); ok {
-		return f.(structFields)
+		return f
 	}
-	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
-	return f.(structFields)
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t, false, false))
+	return f
 }
@@ -2,6 +2,7 @@ package structof
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -175,6 +176,42 @@ func TestMakeMapNestedMapWithInterfaceElement(t *testing.T) {
 	}
 }
 
+func TestMakeMapNestedInterfaceContainers(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		ID int `structof:"id"`
+	}
+	type Doc map[string]any
+	type S struct {
+		M map[string]any
+		L []any
+		I any
+	}
+	s := S{
+		M: map[string]any{
+			"item": Item{1},
+			"list": []any{&Item{2}, map[string]any{"item": Item{3}}},
+			"doc":  Doc{"items": []Item{{4}}},
+		},
+		L: []any{[]any{Item{5}}, Doc{"item": Item{6}}},
+		I: map[string]any{"any": any(Item{7})},
+	}
+	m := MakeMap(s)
+	want := map[string]any{
+		"M": map[string]any{
+			"item": map[string]any{"id": 1},
+			"list": []any{map[string]any{"id": 2}, map[string]any{"item": map[string]any{"id": 3}}},
+			"doc":  map[string]any{"items": []any{map[string]any{"id": 4}}},
+		},
+		"L": []any{[]any{map[string]any{"id": 5}}, map[string]any{"item": map[string]any{"id": 6}}},
+		"I": map[string]any{"any": map[string]any{"id": 7}},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+}
+
 func TestMakeMapNestedMapWithIntSlice(t *testing.T) {
 	type S1 struct {
 		M map[string][]int
@@ -262,6 +299,46 @@ func TestMakeMapNestedSliceWithStructElement(t *testing.T) {
 	}
 }
 
+func TestMakeMapNestedSliceOfStructSlices(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		ID int `structof:"id"`
+	}
+	type Row struct {
+		Pos [2]int `structof:"pos"`
+	}
+	type S struct {
+		Grid  [][]Item            `structof:"grid"`
+		Empty [][]Item            `structof:"empty"`
+		Pages map[string][][]Item `structof:"pages"`
+		Cube  [1][2]*Item         `structof:"cube"`
+		Rows  []Row               `structof:"rows"`
+	}
+	s := S{
+		Grid:  [][]Item{{{1}, {2}}, nil, {{3}}},
+		Empty: [][]Item{nil},
+		Pages: map[string][][]Item{"a": {nil}},
+		Cube:  [1][2]*Item{{{4}, nil}},
+		Rows:  []Row{{[2]int{5, 6}}},
+	}
+	m := MakeMap(s)
+	want := map[string]any{
+		"grid": []any{
+			[]any{map[string]any{"id": 1}, map[string]any{"id": 2}},
+			[]Item(nil),
+			[]any{map[string]any{"id": 3}},
+		},
+		"empty": []any{[]Item(nil)},
+		"pages": map[string]any{"a": []any{[]Item(nil)}},
+		"cube":  [1]any{[2]any{map[string]any{"id": 4}, (*Item)(nil)}},
+		"rows":  []any{map[string]any{"pos": [2]int{5, 6}}},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+}
+
 func TestMakeMapNestedIntSlice(t *testing.T) {
 	t.Parallel()
 
@@ -404,6 +481,27 @@ func TestMakeSlice(t *testing.T) {
 	}
 }
 
+func TestMakeSliceOrder(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Payload string `structof:"payload"`
+		Nonce   int    `structof:"nonce,order=2"`
+		Sig     string `structof:"sig"`
+		ID      int    `structof:"id,order=1"`
+		Alg     string `structof:"alg,order=1"`
+	}
+	v := &S{"data", 7, "xyz", 42, "ed25519"}
+	s := MakeSlice(v)
+	want := []any{"id", 42, "alg", "ed25519", "nonce", 7, "payload", "data", "sig", "xyz"}
+	if !cmp.Equal(want, s) {
+		t.Error(cmp.Diff(want, s))
+	}
+	if keys, want := Keys(v), []string{"id", "alg", "nonce", "payload", "sig"}; !cmp.Equal(want, keys) {
+		t.Error(cmp.Diff(want, keys))
+	}
+}
+
 func TestMakeSliceOmitempty(t *testing.T) {
 	t.Parallel()
 
@@ -538,3 +636,321 @@ func TestPointer2Pointer(t *testing.T) {
 		t.Error(cmp.Diff(want, m))
 	}
 }
+
+type fullNamer interface{ FullName() string }
+
+type fullName struct {
+	First, Last string
+}
+
+func (n fullName) FullName() string { return n.First + " " + n.Last }
+
+func TestMakeMapEmbeddedInterface(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		fmt.Stringer
+		FullNamer fullNamer
+		Name      string
+	}
+
+	b := &strings.Builder{}
+	tests := []struct {
+		s    S
+		want map[string]any
+	}{
+		{
+			S{FullNamer: fullName{"foo", "bar"}, Name: "x"},
+			map[string]any{"FullNamer": map[string]any{"First": "foo", "Last": "bar"}, "Name": "x"},
+		},
+		{
+			S{FullNamer: &fullName{"foo", "bar"}, Name: "x"},
+			map[string]any{"FullNamer": map[string]any{"First": "foo", "Last": "bar"}, "Name": "x"},
+		},
+		{
+			S{Stringer: b, Name: "x"},
+			map[string]any{"Stringer": b, "Name": "x"},
+		},
+		{
+			S{Name: "x"},
+			map[string]any{"Name": "x"},
+		},
+	}
+	for _, tt := range tests {
+		m := MakeMap(tt.s)
+		if !cmp.Equal(tt.want, m, cmp.Comparer(func(x, y *strings.Builder) bool { return x == y })) {
+			t.Errorf("MakeMap(%+v) = %v, want %v", tt.s, m, tt.want)
+		}
+	}
+}
+
+func TestKeysValues(t *testing.T) {
+	t.Parallel()
+
+	type S1 struct {
+		C bool `structof:"c"`
+	}
+	type S2 struct {
+		A  int    `structof:"a"`
+		B  string `structof:"b,omitempty"`
+		S1 *S1    `structof:"s1"`
+		D  string `structof:"-"`
+	}
+
+	s := &S2{A: 23, S1: &S1{true}}
+	keys := Keys(s)
+	wantKeys := []string{"a", "s1"}
+	if !cmp.Equal(wantKeys, keys) {
+		t.Error(cmp.Diff(wantKeys, keys))
+	}
+
+	values := Values(s)
+	wantValues := []any{23, map[string]any{"c": true}}
+	if !cmp.Equal(wantValues, values) {
+		t.Error(cmp.Diff(wantValues, values))
+	}
+}
+
+func TestPrecompile(t *testing.T) {
+	t.Parallel()
+
+	type precompiledItem struct {
+		Name string
+	}
+	type precompiled struct {
+		Items []precompiledItem
+		Next  *precompiled
+	}
+
+	Precompile(precompiled{}, reflect.TypeOf(&precompiled{}))
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf(precompiled{}),
+		reflect.TypeOf([]precompiledItem(nil)),
+		reflect.TypeOf(precompiledItem{}),
+		reflect.TypeOf(&precompiled{}),
+	} {
+		if _, ok := encoderCache.Load(typ); !ok {
+			t.Errorf("encoder of %s not cached", typ)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Precompile of non-struct: should panic")
+		}
+	}()
+	NewEncoder().Precompile(42)
+}
+
+func TestMakeMapPrimitiveAllocs(t *testing.T) {
+	type One struct {
+		A bool
+	}
+	type Many struct {
+		A, B, C, D bool
+		E, F, G, H int
+		I, J       string
+		K          float64
+	}
+
+	// Fill the same maps over and over so that only the values allocate.
+	m1, m2 := make(map[string]any), make(map[string]any)
+	s1, s2 := &One{}, &Many{B: true, F: 42}
+	one := testing.AllocsPerRun(100, func() { FillMap(s1, &m1) })
+	many := testing.AllocsPerRun(100, func() { FillMap(s2, &m2) })
+	if many > one {
+		t.Errorf("FillMap allocations: %v for 1 field, %v for 11 fields", one, many)
+	}
+}
+
+func BenchmarkMakeMap(b *testing.B) {
+	type Wide struct {
+		A, B, C, D      int
+		E, F, G, H      bool
+		I, J, K, L      string
+		M, N, O, P      float64
+		Q, R, S, T      uint32
+		U, V, W, X, Y   int64
+		Z               time.Duration
+		Nested, Nested2 struct {
+			A int
+			B string
+		}
+	}
+	s := Wide{A: 1, B: 1000, I: "foo", M: 1.5, Z: time.Second}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MakeMap(s)
+		MakeMap(&s)
+	}
+}
+
+type stringerLevel int
+
+func (l stringerLevel) String() string {
+	return [...]string{"debug", "info", "warn"}[l]
+}
+
+type stringerPtr struct{ n int }
+
+func (p *stringerPtr) String() string { return fmt.Sprintf("ptr-%d", p.n) }
+
+func TestMakeMapStringer(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Level  stringerLevel  `structof:"level,stringer"`
+		Quoted stringerLevel  `structof:"quoted,string,stringer"`
+		Ptr    stringerPtr    `structof:"ptr,stringer"`
+		Nil    *stringerLevel `structof:"nil,stringer"`
+		Any    any            `structof:"any,stringer"`
+		Plain  int            `structof:"plain,stringer"`
+	}
+
+	s := &S{Level: 1, Quoted: 2, Ptr: stringerPtr{3}, Any: stringerLevel(0), Plain: 4}
+	m := MakeMap(s)
+	want := map[string]any{
+		"level":  "info",
+		"quoted": `"warn"`,
+		"ptr":    "ptr-3",
+		"nil":    (*stringerLevel)(nil),
+		"any":    "debug",
+		"plain":  4,
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	checkLazyMap(t, defaultEncoder, s)
+}
+
+func TestMakeMapJSON(t *testing.T) {
+	t.Parallel()
+
+	type Doc struct {
+		A int      `json:"a"`
+		B []string `json:"b,omitempty"`
+	}
+	type S struct {
+		Doc   Doc            `structof:"doc,json"`
+		Attrs map[string]int `structof:"attrs,json"`
+		Nil   *Doc           `structof:"nil,json"`
+	}
+
+	s := S{Doc: Doc{A: 1, B: []string{"x"}}, Attrs: map[string]int{"k": 2}}
+	m := MakeMap(s)
+	want := map[string]any{
+		"doc":   `{"a":1,"b":["x"]}`,
+		"attrs": `{"k":2}`,
+		"nil":   "null",
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	checkLazyMap(t, defaultEncoder, s)
+}
+
+func TestMakeMapIndexMap(t *testing.T) {
+	t.Parallel()
+
+	type User struct {
+		ID   int    `structof:"id"`
+		Name string `structof:"name"`
+	}
+	type S struct {
+		Tags   []string `structof:"tags,indexmap"`
+		Users  []User   `structof:"users,keyby=id"`
+		ByName *[]*User `structof:"by_name,keyby=name"`
+		Pair   [2]int   `structof:"pair,indexmap"`
+		None   []User   `structof:"none,keyby=id"`
+	}
+	users := []*User{{1, "alice"}, {2, "bob"}}
+	s := S{
+		Tags:   []string{"a", "b"},
+		Users:  []User{{1, "alice"}, {2, "bob"}},
+		ByName: &users,
+		Pair:   [2]int{3, 4},
+	}
+
+	m := MakeMap(s)
+	want := map[string]any{
+		"tags": map[string]any{"0": "a", "1": "b"},
+		"users": map[string]any{
+			"1": map[string]any{"id": 1, "name": "alice"},
+			"2": map[string]any{"id": 2, "name": "bob"},
+		},
+		"by_name": map[string]any{
+			"alice": map[string]any{"id": 1, "name": "alice"},
+			"bob":   map[string]any{"id": 2, "name": "bob"},
+		},
+		"pair": map[string]any{"0": 3, "1": 4},
+		"none": []User(nil),
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	checkLazyMap(t, defaultEncoder, s)
+
+	sl := MakeSlice(S{Users: []User{{1, "alice"}}})
+	if got, want := sl[3], map[string]any{"1": []any{"id", 1, "name", "alice"}}; !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	s.Users = append(s.Users, User{1, "carol"})
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if want := `structof: field "users": duplicate id "1"`; err == nil || want != err.Error() {
+				t.Errorf("duplicate key panicked with %v, want %s", err, want)
+			}
+		}()
+		MakeMap(s)
+	}()
+}
+
+type methodPerson struct {
+	First string `structof:"first"`
+	Last  string `structof:"last"`
+
+	_ struct{} `structof:"full_name,method=FullName"`
+	_ struct{} `structof:"initials,method=Initials"`
+	_ struct{} `structof:"bad,method=Rename"`
+}
+
+func (p methodPerson) FullName() string { return p.First + " " + p.Last }
+
+func (p *methodPerson) Initials() string { return p.First[:1] + p.Last[:1] }
+
+func (p *methodPerson) Rename(first string) { p.First = first }
+
+func TestMakeMapMethod(t *testing.T) {
+	t.Parallel()
+
+	type Wrapper struct {
+		Person methodPerson `structof:",inline"`
+		*methodPerson
+	}
+
+	p := methodPerson{First: "Ada", Last: "Lovelace"}
+	want := map[string]any{"first": "Ada", "last": "Lovelace", "full_name": "Ada Lovelace", "initials": "AL"}
+	for _, i := range []any{p, &p, Wrapper{Person: p}} {
+		if diff := cmp.Diff(want, MakeMap(i)); diff != "" {
+			t.Errorf("MakeMap(%T) mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+	if got, _ := MakeLazyMap(Wrapper{Person: p}).Get("initials"); got != "AL" {
+		t.Errorf("LazyMap initials = %v", got)
+	}
+
+	var got methodPerson
+	if err := FillStruct(map[string]any{"first": "Alan", "full_name": "x"}, &got); err != nil || got.First != "Alan" {
+		t.Errorf("FillStruct = %+v, %v", got, err)
+	}
+	if names := MakeStruct(&got).FieldNames(); !cmp.Equal([]string{"first", "last"}, names) {
+		t.Errorf("FieldNames = %q", names)
+	}
+	if errs := VetTags(methodPerson{}); len(errs) != 1 ||
+		errs[0].Error() != `structof: structof.methodPerson._: method "Rename" does not take no argument and return one value` {
+		t.Errorf("VetTags = %v", errs)
+	}
+}
@@ -0,0 +1,776 @@
+package structof
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An Encoder encodes structs into maps and slices like FillMap, MakeMap and
+// MakeSlice, with a configuration given by EncoderOptions.
+// An Encoder is safe for concurrent use by multiple goroutines.
+type Encoder struct {
+	jsonCompatible bool
+	jsonNumbers    bool
+	widenNumbers   bool
+	uniformMaps    bool
+	uniformSlices  bool
+	cyclePolicy    CyclePolicy
+	cycleRepeats   int
+	cycleThreshold uint
+	funcNames      bool
+	drainChannels  int
+	nilInterfaces  bool
+	complete       bool
+	untypedNils    bool
+	omitNils       bool
+	emptyStructs   bool
+	keyFunc        func(field reflect.StructField, name string) string
+	valueFunc      func(path string, v any) (any, bool)
+	fieldFilter    func(ctx context.Context, path string, field reflect.StructField) bool
+	ignoreTypes    map[reflect.Type]bool
+	omitFunc       func(path string, f Field) bool
+	version        string
+	groups         []string
+	typeKey        string
+	roundTrip      bool
+	unexported     bool
+	tagless        bool
+
+	// Go paths of the fields selected by WithFields, and of the struct
+	// fields containing them, and of the fields omitted by WithoutFields.
+	fields        map[string]bool
+	fieldParents  map[string]bool
+	withoutFields map[string]bool
+
+	// New keys by old key or key path, set by WithKeyRenames.
+	keyRenames map[string]string
+
+	// Whether the top-level fields are omitted if and only if they are
+	// zero, for Struct.NonZeroMap.
+	nonZero bool
+
+	// Context of the encodings, set by WithContext.
+	ctx context.Context
+
+	// Pool of the maps encoded into, set by NewMapPool.
+	maps *sync.Pool
+
+	// Whether the values of unsupported types are encoded by
+	// fallbackEncoder, for MarshalAny.
+	fallbacks bool
+}
+
+// An EncoderOption configures an Encoder.
+type EncoderOption func(*Encoder)
+
+// defaultEncoder is the Encoder used by the package functions.
+var defaultEncoder = NewEncoder()
+
+// NewEncoder returns a new Encoder configured by opts.
+func NewEncoder(opts ...EncoderOption) *Encoder {
+	enc := &Encoder{cycleThreshold: startDetectingCyclesAfter}
+	for _, opt := range opts {
+		opt(enc)
+	}
+	return enc
+}
+
+// WithJSONCompatible configures the Encoder to emit only the types of values
+// json.Unmarshal produces into an any: maps are map[string]any, slices and
+// arrays are []any, numbers are float64, and nil pointers, maps and slices
+// are an untyped nil. As in encoding/json, byte slices are emitted as base64
+// strings, time.Time values as RFC 3339 strings, and any other value without
+// fields to encode as its JSON encoding decoded back into an any.
+//
+// Encoding a NaN or infinite floating-point number
+// panics with an UnsupportedValueError.
+func WithJSONCompatible() EncoderOption {
+	return func(enc *Encoder) {
+		enc.jsonCompatible = true
+	}
+}
+
+// WithJSONNumbers is like WithJSONCompatible but emits numbers as
+// json.Number instead of float64, preserving the precision of large integers.
+func WithJSONNumbers() EncoderOption {
+	return func(enc *Encoder) {
+		enc.jsonCompatible = true
+		enc.jsonNumbers = true
+	}
+}
+
+// WithWidenNumbers configures the Encoder to emit integers as int64,
+// unsigned integers as uint64 and floating-point numbers as float64,
+// regardless of their declared types, so that the values of the map can be
+// type asserted without knowing the width of the fields. The element types
+// of the typed slices, arrays and maps emitted are widened likewise.
+func WithWidenNumbers() EncoderOption {
+	return func(enc *Encoder) {
+		enc.widenNumbers = true
+	}
+}
+
+// WithUniformMaps configures the Encoder to emit every map as a
+// map[string]any, at any depth, instead of keeping maps whose elements are
+// not structs typed, such as map[string]string. Nil maps are emitted as a
+// nil map[string]any.
+func WithUniformMaps() EncoderOption {
+	return func(enc *Encoder) {
+		enc.uniformMaps = true
+	}
+}
+
+// WithUniformSlices configures the Encoder to emit every slice and array
+// as a []any, at any depth, instead of keeping slices and arrays whose
+// elements are not structs typed, such as []int. Nil slices are emitted
+// as a nil []any. Byte slices and arrays are kept as they are.
+func WithUniformSlices() EncoderOption {
+	return func(enc *Encoder) {
+		enc.uniformSlices = true
+	}
+}
+
+// WithFuncNames configures the Encoder to emit functions as their names,
+// as reported by runtime.FuncForPC, such as "main.handleIndex", instead of
+// panicking with an UnsupportedTypeError, so that registries of handlers
+// can be inspected and logged. Function literals and method values have
+// the names the compiler gives them, and nil functions are emitted as nil
+// pointers are.
+func WithFuncNames() EncoderOption {
+	return func(enc *Encoder) {
+		enc.funcNames = true
+	}
+}
+
+// WithDrainChannels configures the Encoder to emit the channels as slices of
+// the values buffered in them, received without blocking, instead of
+// panicking with an UnsupportedTypeError, for diagnostic snapshots of
+// structs holding queues. At most n values are received from each channel,
+// or all the values buffered if n is negative; the values received are
+// removed from the channel. The values are encoded as the elements of a
+// slice are, nil channels are emitted as nil pointers are, and the
+// send-only channels still panic. WithDrainChannels(0) disables draining.
+func WithDrainChannels(n int) EncoderOption {
+	return func(enc *Encoder) {
+		enc.drainChannels = n
+	}
+}
+
+// WithNilInterfaces configures the Encoder to emit nil interface values as
+// an untyped nil under their key, instead of omitting the key, so that
+// consumers can tell an unset field from a field that does not exist.
+func WithNilInterfaces() EncoderOption {
+	return func(enc *Encoder) {
+		enc.nilInterfaces = true
+	}
+}
+
+// WithUntypedNils configures the Encoder to emit nil pointers, maps, slices
+// and functions as an untyped nil instead of the typed nil value, such as
+// (*T)(nil), so that the m[k] == nil checks of consumers hold. It takes
+// precedence over the nil maps and slices of WithUniformMaps and
+// WithUniformSlices.
+func WithUntypedNils() EncoderOption {
+	return func(enc *Encoder) {
+		enc.untypedNils = true
+	}
+}
+
+// WithOmitNils configures the Encoder to omit the keys of nil pointers, maps,
+// slices and functions, instead of emitting their typed nil values. It takes
+// precedence over WithUntypedNils and WithJSONCompatible.
+func WithOmitNils() EncoderOption {
+	return func(enc *Encoder) {
+		enc.omitNils = true
+	}
+}
+
+// typedNils reports whether nil pointers, maps, slices and functions
+// are emitted as typed nil values.
+func (enc *Encoder) typedNils() bool {
+	return !enc.jsonCompatible && !enc.untypedNils && !enc.omitNils
+}
+
+// WithEmptyStructMaps configures the Encoder to emit the nested structs
+// without fields to encode as an empty map[string]any, or an empty []any
+// with MakeSlice, instead of the struct value itself, keeping the output
+// uniformly made of maps. Structs implementing encoding.TextMarshaler or
+// json.Marshaler, such as time.Time, are values of their own and are still
+// emitted as they are.
+func WithEmptyStructMaps() EncoderOption {
+	return func(enc *Encoder) {
+		enc.emptyStructs = true
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// emptyStructMap reports whether the struct, or pointer to struct, type t,
+// without fields to encode, is emitted as an empty map by WithEmptyStructMaps.
+func (enc *Encoder) emptyStructMap(t reflect.Type) bool {
+	if !enc.emptyStructs {
+		return false
+	}
+	for reflect.Pointer == t.Kind() {
+		t = t.Elem()
+	}
+	pt := reflect.PointerTo(t)
+	return !pt.Implements(textMarshalerType) && !pt.Implements(jsonMarshalerType)
+}
+
+// WithKeyFunc configures the Encoder to emit the fields of structs under
+// the keys returned by fn, called with the field and the key it is emitted
+// under otherwise, applying naming policies without tags. The keys of the
+// fields of inline structs are passed to fn too, while the keys of maps
+// are kept as they are.
+func WithKeyFunc(fn func(field reflect.StructField, name string) string) EncoderOption {
+	return func(enc *Encoder) {
+		enc.keyFunc = fn
+	}
+}
+
+// WithValueFunc configures the Encoder to pass each leaf value to fn before
+// storing it, to truncate long strings, round numbers or drop values
+// globally. The leaf values are the values that are not encoded from their
+// own fields or elements: booleans, numbers, strings, nil values, and the
+// values without fields to encode, such as time.Time. The path is made of
+// the keys leading to the value from the top-level struct, separated by
+// dots, with the indexes of the elements of slices and arrays as keys, as in
+// "items.2.name". The value returned by fn is stored in place of v, a nil
+// one as an untyped nil, unless fn returns false, dropping the value.
+func WithValueFunc(fn func(path string, v any) (any, bool)) EncoderOption {
+	return func(enc *Encoder) {
+		enc.valueFunc = fn
+	}
+}
+
+// WithFieldFilter configures the Encoder to encode only the struct fields
+// for which fn returns true, fn being called with the Encoder's context, so
+// that the fields encoded depend on the role of the caller or on feature
+// flags carried by the context of a request. The path is made of the keys
+// leading to the field from the top-level struct, as for WithValueFunc.
+// The inline fields are not passed to fn, their own fields being.
+//
+// A server shares one Encoder configured by WithFieldFilter and encodes
+// each request with the Encoder returned by its WithContext method.
+func WithFieldFilter(fn func(ctx context.Context, path string, field reflect.StructField) bool) EncoderOption {
+	return func(enc *Encoder) {
+		enc.fieldFilter = fn
+	}
+}
+
+// WithOmitFunc configures the Encoder to omit the struct fields for which
+// fn returns true, for the conditions the "omitempty" option cannot express,
+// such as omitting a price without a currency. The Field f holds the value
+// of the field, and its Parent method the struct holding it, the outer
+// struct for the fields promoted from embedded structs. The path is as for
+// WithFieldFilter, and the inline fields are not passed to fn either.
+func WithOmitFunc(fn func(path string, f Field) bool) EncoderOption {
+	return func(enc *Encoder) {
+		enc.omitFunc = fn
+	}
+}
+
+// omitted reports whether the struct field f of the struct v, holding fv,
+// is omitted under path by the function of WithOmitFunc.
+func (enc *Encoder) omitted(path string, f *field, v, fv reflect.Value) bool {
+	return enc.omitFunc != nil && !f.inline && enc.omitFunc(path, Field{v: fv, sf: f.sf, parent: v})
+}
+
+// WithContext returns a shallow copy of enc with its context changed to ctx,
+// passed to the function of WithFieldFilter. The copy shares enc's
+// configuration and its MapPool, if any. WithContext panics if ctx is nil.
+func (enc *Encoder) WithContext(ctx context.Context) *Encoder {
+	if ctx == nil {
+		panic("nil context")
+	}
+	enc2 := *enc
+	enc2.ctx = ctx
+	return &enc2
+}
+
+// Context returns the Encoder's context, set by WithContext.
+// It is context.Background if none was set.
+func (enc *Encoder) Context() context.Context {
+	if enc.ctx == nil {
+		return context.Background()
+	}
+	return enc.ctx
+}
+
+// filtered reports whether the struct field f under path is omitted by the
+// function of WithFieldFilter.
+func (enc *Encoder) filtered(path string, f *field) bool {
+	return enc.fieldFilter != nil && !f.inline && !enc.fieldFilter(enc.Context(), path, f.sf)
+}
+
+// WithFields configures the Encoder to encode only the fields selected by
+// paths, for the projections of a struct. The paths are those of the
+// Decoder's WithOnlyFields: a sequence of Go field names separated by dots,
+// as in "Address.City", a path to a struct field selecting all its fields;
+// the fields of an inline struct are selected through the name of the
+// inline field, the elements of a slice, array or map field share the path
+// of the field, and the fields of an embedded struct are selected by their
+// own names.
+func WithFields(paths ...string) EncoderOption {
+	return func(enc *Encoder) {
+		if enc.fields == nil {
+			enc.fields = make(map[string]bool)
+			enc.fieldParents = make(map[string]bool)
+		}
+		for _, path := range paths {
+			enc.fields[path] = true
+			for i := strings.LastIndexByte(path, '.'); i >= 0; i = strings.LastIndexByte(path, '.') {
+				path = path[:i]
+				enc.fieldParents[path] = true
+			}
+		}
+	}
+}
+
+// WithoutFields configures the Encoder to omit the fields at paths,
+// as named by WithFields, such as "Password" or "User.Password", along with
+// all their fields. WithoutFields takes precedence over WithFields.
+func WithoutFields(paths ...string) EncoderOption {
+	return func(enc *Encoder) {
+		if enc.withoutFields == nil {
+			enc.withoutFields = make(map[string]bool)
+		}
+		for _, path := range paths {
+			enc.withoutFields[path] = true
+		}
+	}
+}
+
+// projected reports whether enc is configured by WithFields or WithoutFields.
+func (enc *Encoder) projected() bool {
+	return enc.fields != nil || enc.withoutFields != nil
+}
+
+// projectedField reports whether the field at e.goPath is encoded according
+// to WithFields and WithoutFields, and records whether all its fields are.
+func (e *encodeState) projectedField() bool {
+	switch {
+	case e.enc.withoutFields[e.goPath]:
+		return false
+	case e.enc.fields == nil || e.selected:
+		return true
+	case e.enc.fields[e.goPath]:
+		e.selected = true
+		return true
+	}
+	return e.enc.fieldParents[e.goPath]
+}
+
+// WithKeyRenames configures the Encoder to rename the keys of the struct
+// fields given by renames, mapping an old key or the dotted path of old
+// keys leading to one, as "address.city", to its new key, so that a map
+// matches the naming of an external schema without changing the tags.
+// A path renames the key at that path only, and takes precedence over the
+// key alone, which renames the key at any depth. The paths are made of the
+// keys before their renaming; the elements of a slice, array or map field
+// share the path of the field, and the fields of an inline struct the path
+// of the struct holding it. The keys of map fields are not renamed.
+//
+// The keys are renamed after WithKeyFunc changes them, and the paths given
+// to the functions of WithFieldFilter and WithValueFunc are made of the
+// new keys.
+func WithKeyRenames(renames map[string]string) EncoderOption {
+	return func(enc *Encoder) {
+		if enc.keyRenames == nil {
+			enc.keyRenames = make(map[string]string, len(renames))
+		}
+		for old, key := range renames {
+			enc.keyRenames[old] = key
+		}
+	}
+}
+
+// renamedKey returns the key of the field f, key before its renaming by
+// WithKeyRenames, under the old key path, and records the old key path of
+// the field.
+func (e *encodeState) renamedKey(path, key string, f *field) string {
+	if f.inline {
+		e.keyPath = path
+		return key
+	}
+	e.keyPath = joinKey(path, key)
+	if k, ok := e.enc.keyRenames[e.keyPath]; ok {
+		return k
+	}
+	if k, ok := e.enc.keyRenames[key]; ok {
+		return k
+	}
+	return key
+}
+
+// WithIgnoreTypes configures the Encoder to omit the struct fields of the
+// given types, or of pointers to them, such as context.Context, sync.Mutex
+// or the handles of a framework, instead of tagging each of them "-". The
+// fields promoted from an embedded field of one of the types are omitted
+// as well, and so is an inline field of one of them.
+//
+// Each of types may be a value of the type or its reflect.Type; an interface
+// type is given by a nil pointer to it, as (*context.Context)(nil).
+// WithIgnoreTypes panics if one of them is nil.
+func WithIgnoreTypes(types ...any) EncoderOption {
+	return func(enc *Encoder) {
+		if enc.ignoreTypes == nil {
+			enc.ignoreTypes = make(map[reflect.Type]bool, len(types))
+		}
+		for _, i := range types {
+			t, ok := i.(reflect.Type)
+			if !ok {
+				t = reflect.TypeOf(i)
+			}
+			if t == nil {
+				panic("nil type")
+			}
+			if !ok && reflect.Pointer == t.Kind() && reflect.Interface == t.Elem().Kind() {
+				t = t.Elem()
+			}
+			enc.ignoreTypes[t] = true
+		}
+	}
+}
+
+// ignoredField reports whether the field f of the struct type t is omitted
+// by WithIgnoreTypes, its type or the type of an embedded field it is
+// promoted from being ignored.
+func (enc *Encoder) ignoredField(t reflect.Type, f *field) bool {
+	if len(enc.ignoreTypes) == 0 {
+		return false
+	}
+	for _, i := range f.index {
+		ft := t.Field(i).Type
+		if enc.ignoreTypes[ft] || reflect.Pointer == ft.Kind() && enc.ignoreTypes[ft.Elem()] {
+			return true
+		}
+		t = indirectType(ft)
+	}
+	return false
+}
+
+// fieldKey returns the key the struct field f is emitted under.
+func (enc *Encoder) fieldKey(f *field) string {
+	if enc.keyFunc == nil || f.inline {
+		return f.name
+	}
+	return enc.keyFunc(f.sf, f.name)
+}
+
+// WithVersion configures the Encoder to encode only the fields active at
+// version v, according to their "since" and "until" options, so that a struct
+// serves the map shapes of several versions of an API. A field is active
+// from its "since" version, if any, up to but excluding its "until" version,
+// if any.
+//
+// Versions are compared by their components, separated by dots,
+// numerically if both are unsigned integers and lexically otherwise,
+// a missing component counting as 0: "1.10" is after "1.9", and "2" equals
+// "2.0". The empty v, the default, encodes the fields of all versions.
+func WithVersion(v string) EncoderOption {
+	return func(enc *Encoder) {
+		enc.version = v
+	}
+}
+
+// WithGroups configures the Encoder to encode the fields whose "groups"
+// option lists one of groups, so that a struct produces several projections,
+// as public, admin and internal views, without a type for each. The fields
+// without the option are always encoded, and the fields with it are omitted
+// unless the Encoder is configured with one of their groups.
+func WithGroups(groups ...string) EncoderOption {
+	return func(enc *Encoder) {
+		enc.groups = append(enc.groups[:len(enc.groups):len(enc.groups)], groups...)
+	}
+}
+
+// activeField reports whether the struct field f is active at the version
+// and in the groups the Encoder is configured with.
+func (enc *Encoder) activeField(f *field) bool {
+	if len(f.groups) > 0 && !enc.inGroups(f.groups) {
+		return false
+	}
+	if enc.version == "" {
+		return true
+	}
+	return (f.since == "" || compareVersions(f.since, enc.version) <= 0) &&
+		(f.until == "" || compareVersions(enc.version, f.until) < 0)
+}
+
+// inGroups reports whether one of groups is among the groups the Encoder is
+// configured with.
+func (enc *Encoder) inGroups(groups []string) bool {
+	for _, g := range groups {
+		for _, eg := range enc.groups {
+			if g == eg {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compareVersions returns -1, 0 or 1 if the version a is before, equal to
+// or after the version b.
+func compareVersions(a, b string) int {
+	for a != "" || b != "" {
+		var x, y string
+		x, a, _ = strings.Cut(a, ".")
+		y, b, _ = strings.Cut(b, ".")
+		if x == "" {
+			x = "0"
+		}
+		if y == "" {
+			y = "0"
+		}
+		m, errm := strconv.ParseUint(x, 10, 64)
+		n, errn := strconv.ParseUint(y, 10, 64)
+		switch {
+		case errm != nil || errn != nil:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		case m < n:
+			return -1
+		case m > n:
+			return 1
+		}
+	}
+	return 0
+}
+
+// WithTypeKey configures the Encoder to add key to the maps encoded from the
+// structs held by interface values, such as the fields of type any, with the
+// name of their type, as registered by RegisterType or qualified by its
+// package path, such as "example.com/orders.Order", so that a Decoder
+// configured by WithTypes restores the polymorphic values.
+// Pointers to structs are named by the type of the struct.
+func WithTypeKey(key string) EncoderOption {
+	return func(enc *Encoder) {
+		enc.typeKey = key
+	}
+}
+
+// WithRoundTrip configures the Encoder to emit maps that FillStruct decodes
+// back into a struct equal to the encoded one, for maps used as an internal
+// transport. The values held by interfaces are stored as is, keeping their
+// dynamic type, pointer or not, instead of being encoded into the maps that
+// FillStruct cannot tell the type of, and the options only applying to
+// encoding, "stringer", "indexmap", "keyby" and the Transformers, are
+// ignored.
+//
+// The fields with the "decodeonly" option, the unexported fields, and the
+// keys and values changed by WithKeyFunc and WithValueFunc are not restored.
+func WithRoundTrip() EncoderOption {
+	return func(enc *Encoder) {
+		enc.roundTrip = true
+	}
+}
+
+// WithUnexported configures the Encoder to emit the unexported fields of
+// structs too, under their Go name unless their tag names them, for
+// debugging and diagnostic dumps of values whose state is otherwise hidden.
+// The values are read-only copies read through package unsafe: FillStruct
+// ignores their keys, and changing them does not change the struct.
+// The structs implementing fmt.Stringer or encoding.TextMarshaler, such as
+// time.Time, are emitted as without the option.
+//
+// Unexported fields are subject to the rules of exported ones: the
+// unexported channels panic, and so do the functions unless enc is
+// configured by WithFuncNames.
+func WithUnexported() EncoderOption {
+	return func(enc *Encoder) {
+		enc.unexported = true
+	}
+}
+
+// WithTagless configures the Encoder to ignore the structof tags, emitting
+// every exported field under its Go name, as for debugging dumps and test
+// fixtures where the layout of the struct matters rather than its mapping:
+// the fields tagged "-" are emitted, the names and options of the tags are
+// ignored, and the fields of embedded structs are promoted by the Go rules.
+// It combines with WithUnexported to emit the unexported fields too.
+func WithTagless() EncoderOption {
+	return func(enc *Encoder) {
+		enc.tagless = true
+	}
+}
+
+// WithComplete configures the Encoder to emit a key for every field,
+// whatever its value, so that consumers can tell a field that is unset
+// from one that does not exist: it implies WithNilInterfaces, and overrides
+// the "omitempty" option and WithOmitNils. The fields tagged "-", and those
+// left out by options selecting fields, such as WithFieldFilter or
+// WithOmitFunc, are still omitted.
+func WithComplete() EncoderOption {
+	return func(enc *Encoder) {
+		enc.complete = true
+		enc.nilInterfaces = true
+	}
+}
+
+// uniformSlice reports whether the slice or array type t is emitted as []any
+// because of WithUniformSlices.
+func (enc *Encoder) uniformSlice(t reflect.Type) bool {
+	return enc.uniformSlices && reflect.Uint8 != t.Elem().Kind()
+}
+
+var (
+	int64Type   = reflect.TypeOf(int64(0))
+	uint64Type  = reflect.TypeOf(uint64(0))
+	float64Type = reflect.TypeOf(float64(0))
+)
+
+// widenType returns the type that values of type t are emitted as.
+func (enc *Encoder) widenType(t reflect.Type) reflect.Type {
+	if !enc.widenNumbers {
+		return t
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int64Type
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uint64Type
+	case reflect.Float32, reflect.Float64:
+		return float64Type
+	}
+	return t
+}
+
+// widenNumber returns the primitive v widened as by WithWidenNumbers.
+func widenNumber(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return v.Interface()
+}
+
+// jsonPrimitive returns the boolean, number or string v as a JSON-compatible value.
+func (e *encodeState) jsonPrimitive(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if e.enc.jsonNumbers {
+			return json.Number(strconv.FormatInt(v.Int(), 10))
+		}
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if e.enc.jsonNumbers {
+			return json.Number(strconv.FormatUint(v.Uint(), 10))
+		}
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			e.error(&UnsupportedValueError{v, strconv.FormatFloat(f, 'g', -1, v.Type().Bits())})
+		}
+		if e.enc.jsonNumbers {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, v.Type().Bits()))
+		}
+		return f
+	}
+	panic("unreachable")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonOpaque returns v, a value without fields to encode,
+// as a JSON-compatible value.
+func (e *encodeState) jsonOpaque(v reflect.Value) any {
+	for reflect.Pointer == v.Kind() {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if timeType == v.Type() {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		e.error(fmt.Errorf("structof: encode %s as JSON: %w", v.Type(), err))
+	}
+	var x any
+	if e.enc.jsonNumbers {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		err = d.Decode(&x)
+	} else {
+		err = json.Unmarshal(b, &x)
+	}
+	if err != nil {
+		e.error(fmt.Errorf("structof: decode %s from JSON: %w", v.Type(), err))
+	}
+	return x
+}
+
+// A CyclePolicy tells an Encoder what to do with a pointer, map or slice
+// that refers back to a value containing it, which would otherwise be
+// encoded endlessly.
+type CyclePolicy int
+
+const (
+	// CyclePanic panics with an UnsupportedValueError. It is the default.
+	CyclePanic CyclePolicy = iota
+
+	// CycleNil encodes the repeated value as an untyped nil.
+	CycleNil
+
+	// CycleRef encodes the repeated value as a map[string]any with the key
+	// "$ref" holding the path of the first occurrence of the value, made of
+	// the keys leading to it from the top-level struct, separated by dots,
+	// with the indexes of slice and array elements as keys.
+	CycleRef
+)
+
+// WithCyclePolicy configures the Encoder to handle cycles with policy.
+// Except for CyclePanic, whose detection only starts past a depth that
+// legitimate data rarely reaches, as set by WithCycleThreshold, cycles are
+// detected from the top-level struct, so that the encoding is cut at the
+// first repeated value.
+func WithCyclePolicy(policy CyclePolicy) EncoderOption {
+	return func(enc *Encoder) {
+		enc.cyclePolicy = policy
+	}
+}
+
+// WithCycleThreshold configures the Encoder to start checking for cycles
+// with CyclePanic past n nested pointers, maps and slices, instead of 1000.
+// A lower n detects the cycles of shallow data sooner, and a higher n saves
+// the bookkeeping for deep acyclic data. Zero checks every pointer, map and
+// slice.
+func WithCycleThreshold(n uint) EncoderOption {
+	return func(enc *Encoder) {
+		enc.cycleThreshold = n
+	}
+}
+
+// WithCycleRepeats configures the Encoder to repeat the encoding of a value
+// n times along a cycle before applying its CyclePolicy, unrolling a
+// graph-shaped value a limited number of times: the value is encoded n+1
+// times along the path, the first time and n repetitions. By default, n is 0
+// and the policy applies at the first repetition.
+func WithCycleRepeats(n int) EncoderOption {
+	return func(enc *Encoder) {
+		enc.cycleRepeats = n
+	}
+}
@@ -0,0 +1,1041 @@
+package structof
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type jsonOpaque struct{ n int }
+
+func (o jsonOpaque) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int{"n": o.n})
+}
+
+func TestEncoderJSONCompatible(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Inner struct {
+			A int8 `structof:"a"`
+		}
+		S struct {
+			Int    int               `structof:"int"`
+			Uint   uint16            `structof:"uint"`
+			Float  float32           `structof:"float"`
+			Bytes  []byte            `structof:"bytes"`
+			Time   time.Time         `structof:"time"`
+			Ptr    *Inner            `structof:"ptr"`
+			Nil    *Inner            `structof:"nil"`
+			NilMap map[string]int    `structof:"nilmap"`
+			Ints   []int             `structof:"ints"`
+			Array  [2]string         `structof:"array"`
+			Labels map[string]string `structof:"labels"`
+			Opaque jsonOpaque        `structof:"opaque"`
+		}
+	)
+
+	at := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	s := S{
+		Int:    23,
+		Uint:   46,
+		Float:  1.5,
+		Bytes:  []byte("hello"),
+		Time:   at,
+		Ptr:    &Inner{1},
+		Ints:   []int{1, 2},
+		Array:  [2]string{"a", "b"},
+		Labels: map[string]string{"foo": "bar"},
+		Opaque: jsonOpaque{7},
+	}
+
+	m := NewEncoder(WithJSONCompatible()).MakeMap(s)
+	want := map[string]any{
+		"int":    23.0,
+		"uint":   46.0,
+		"float":  1.5,
+		"bytes":  "aGVsbG8=",
+		"time":   "2009-11-10T23:00:00Z",
+		"ptr":    map[string]any{"a": 1.0},
+		"nil":    nil,
+		"nilmap": nil,
+		"ints":   []any{1.0, 2.0},
+		"array":  []any{"a", "b"},
+		"labels": map[string]any{"foo": "bar"},
+		"opaque": map[string]any{"n": 7.0},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	// The result survives a JSON round trip unchanged.
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back map[string]any
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(m, back) {
+		t.Error(cmp.Diff(m, back))
+	}
+
+	m = NewEncoder(WithJSONNumbers()).MakeMap(s)
+	if m["int"] != json.Number("23") || m["float"] != json.Number("1.5") {
+		t.Errorf("WithJSONNumbers numbers got %#v, %#v", m["int"], m["float"])
+	}
+	if n := m["opaque"].(map[string]any)["n"]; n != json.Number("7") {
+		t.Errorf("WithJSONNumbers opaque got %#v", n)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("encoding NaN should panic")
+		}
+	}()
+	NewEncoder(WithJSONCompatible()).MakeMap(struct{ F float64 }{math.NaN()})
+}
+
+func TestEncoderWidenNumbers(t *testing.T) {
+	t.Parallel()
+
+	type Level uint8
+	type S struct {
+		Count  int32            `structof:"count"`
+		Level  Level            `structof:"level"`
+		Ratio  float32          `structof:"ratio"`
+		Name   string           `structof:"name"`
+		Ints   []int16          `structof:"ints"`
+		Matrix [][]int8         `structof:"matrix"`
+		Scores map[string]int32 `structof:"scores"`
+	}
+
+	m := NewEncoder(WithWidenNumbers()).MakeMap(S{
+		Count:  23,
+		Level:  2,
+		Ratio:  0.5,
+		Name:   "foobar",
+		Ints:   []int16{1, 2},
+		Matrix: [][]int8{{1}, {2, 3}},
+		Scores: map[string]int32{"a": 1},
+	})
+	want := map[string]any{
+		"count":  int64(23),
+		"level":  uint64(2),
+		"ratio":  0.5,
+		"name":   "foobar",
+		"ints":   []int64{1, 2},
+		"matrix": []any{[]int64{1}, []int64{2, 3}},
+		"scores": map[string]int64{"a": 1},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	if _, ok := m["count"].(int64); !ok {
+		t.Errorf("count got %T, want int64", m["count"])
+	}
+}
+
+func TestEncoderUniformMaps(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Labels map[string]string            `structof:"labels"`
+		Nested map[string]map[string]int    `structof:"nested"`
+		Lists  map[string][]map[string]bool `structof:"lists"`
+		Nil    map[string]int               `structof:"nil"`
+	}
+
+	m := NewEncoder(WithUniformMaps()).MakeMap(S{
+		Labels: map[string]string{"foo": "bar"},
+		Nested: map[string]map[string]int{"a": {"b": 1}},
+		Lists:  map[string][]map[string]bool{"x": {{"y": true}}},
+	})
+	want := map[string]any{
+		"labels": map[string]any{"foo": "bar"},
+		"nested": map[string]any{"a": map[string]any{"b": 1}},
+		"lists":  map[string]any{"x": []any{map[string]any{"y": true}}},
+		"nil":    map[string]any(nil),
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+}
+
+func TestEncoderUniformSlices(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Ints   []int             `structof:"ints"`
+		Array  [2]string         `structof:"array"`
+		Matrix [][]int           `structof:"matrix"`
+		Lists  map[string][]bool `structof:"lists"`
+		Bytes  []byte            `structof:"bytes"`
+		Nil    []int             `structof:"nil"`
+	}
+
+	m := NewEncoder(WithUniformSlices()).MakeMap(S{
+		Ints:   []int{1, 2},
+		Array:  [2]string{"a", "b"},
+		Matrix: [][]int{{1}, {2, 3}},
+		Lists:  map[string][]bool{"x": {true}},
+		Bytes:  []byte("hi"),
+	})
+	want := map[string]any{
+		"ints":   []any{1, 2},
+		"array":  []any{"a", "b"},
+		"matrix": []any{[]any{1}, []any{2, 3}},
+		"lists":  map[string]any{"x": []any{true}},
+		"bytes":  []byte("hi"),
+		"nil":    []any(nil),
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+}
+
+type cycleNode struct {
+	Name     string       `structof:"name"`
+	Next     *cycleNode   `structof:"next"`
+	Children []*cycleNode `structof:"children,omitempty"`
+}
+
+func TestEncoderCyclePolicy(t *testing.T) {
+	t.Parallel()
+
+	a := &cycleNode{Name: "a"}
+	b := &cycleNode{Name: "b", Next: a}
+	a.Next = b
+	c := &cycleNode{Name: "c"}
+	c.Children = []*cycleNode{c}
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*UnsupportedValueError); !ok {
+				t.Error("MakeMap of cycle should panic with UnsupportedValueError")
+			}
+		}()
+		MakeMap(a)
+	}()
+
+	m := NewEncoder(WithCyclePolicy(CycleNil)).MakeMap(a)
+	want := map[string]any{
+		"name": "a",
+		"next": map[string]any{"name": "b", "next": nil},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	m = NewEncoder(WithCyclePolicy(CycleRef)).MakeMap(b)
+	want = map[string]any{
+		"name": "b",
+		"next": map[string]any{"name": "a", "next": map[string]any{"$ref": ""}},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	m = NewEncoder(WithCyclePolicy(CycleRef)).MakeMap(struct {
+		Root *cycleNode `structof:"root"`
+	}{c})
+	want = map[string]any{
+		"root": map[string]any{
+			"name":     "c",
+			"next":     (*cycleNode)(nil),
+			"children": []any{map[string]any{"$ref": "root"}},
+		},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	m = NewEncoder(WithCyclePolicy(CycleNil), WithCycleRepeats(1)).MakeMap(a)
+	want = map[string]any{
+		"name": "a",
+		"next": map[string]any{
+			"name": "b",
+			"next": map[string]any{
+				"name": "a",
+				"next": map[string]any{"name": "b", "next": nil},
+			},
+		},
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+}
+
+func TestEncoderCycleRepeats(t *testing.T) {
+	t.Parallel()
+
+	a := &cycleNode{Name: "a"}
+	a.Next = a
+
+	for _, n := range []int{0, 1, 2, 5} {
+		m := NewEncoder(WithCyclePolicy(CycleNil), WithCycleRepeats(n)).MakeMap(a)
+		count := 0
+		for ; m != nil; m, _ = m["next"].(map[string]any) {
+			count++
+		}
+		if n+1 != count {
+			t.Errorf("WithCycleRepeats(%d) encoded the value %d times, want %d", n, count, n+1)
+		}
+	}
+}
+
+func TestEncoderCycleThreshold(t *testing.T) {
+	t.Parallel()
+
+	a := &cycleNode{Name: "a"}
+	a.Next = a
+	shared := &cycleNode{Name: "shared"}
+	dag := &cycleNode{Name: "dag", Next: shared, Children: []*cycleNode{shared, shared}}
+
+	for _, n := range []uint{0, 1, 10} {
+		enc := NewEncoder(WithCycleThreshold(n))
+		func() {
+			defer func() {
+				if _, ok := recover().(*UnsupportedValueError); !ok {
+					t.Errorf("WithCycleThreshold(%d): MakeMap of cycle should panic with UnsupportedValueError", n)
+				}
+			}()
+			enc.MakeMap(a)
+		}()
+
+		if m := enc.MakeMap(dag); len(m["children"].([]any)) != 2 {
+			t.Errorf("WithCycleThreshold(%d): MakeMap of shared pointers = %v", n, m)
+		}
+	}
+}
+
+func encoderTestHandler() {}
+
+func TestEncoderFuncNames(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Handler func()            `structof:"handler"`
+		Nil     func()            `structof:"nil"`
+		Hooks   map[string]func() `structof:"hooks"`
+	}
+	s := S{
+		Handler: encoderTestHandler,
+		Hooks:   map[string]func(){"h": encoderTestHandler},
+	}
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*UnsupportedTypeError); !ok {
+				t.Error("MakeMap of func field should panic with UnsupportedTypeError")
+			}
+		}()
+		MakeMap(s)
+	}()
+
+	const name = "github.com/weiwenchen2022/structof.encoderTestHandler"
+	m := NewEncoder(WithFuncNames()).MakeMap(s)
+	want := map[string]any{
+		"handler": name,
+		"nil":     (func())(nil),
+		"hooks":   map[string]any{"h": name},
+	}
+	if !cmp.Equal(want, m, cmp.Comparer(func(x, y func()) bool { return x == nil && y == nil })) {
+		t.Errorf("MakeMap = %#v, want %#v", m, want)
+	}
+
+	m = NewEncoder(WithFuncNames(), WithJSONCompatible()).MakeMap(s)
+	if v, ok := m["nil"]; !ok || v != nil {
+		t.Errorf(`MakeMap with WithJSONCompatible: "nil" = %#v, %t`, v, ok)
+	}
+}
+
+func TestEncoderNilInterfaces(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Value any   `structof:"value"`
+		Error error `structof:"error"`
+		List  []any `structof:"list"`
+		Set   any   `structof:"set"`
+	}
+	s := S{List: []any{1, nil}, Set: 2}
+
+	if m := MakeMap(s); len(m) != 2 {
+		t.Errorf("MakeMap = %#v, want nil interfaces omitted", m)
+	}
+
+	want := map[string]any{
+		"value": nil,
+		"error": nil,
+		"list":  []any{1, nil},
+		"set":   2,
+	}
+	for _, opt := range []EncoderOption{WithNilInterfaces(), WithComplete()} {
+		enc := NewEncoder(opt)
+		if m := enc.MakeMap(s); !cmp.Equal(want, m) {
+			t.Error(cmp.Diff(want, m))
+		}
+		if v, ok := enc.MakeLazyMap(s).Get("error"); !ok || v != nil {
+			t.Errorf(`LazyMap Get("error") = %v, %t`, v, ok)
+		}
+	}
+}
+
+func TestEncoderComplete(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Name  string         `structof:"name,omitempty"`
+		Ptr   *int           `structof:"ptr"`
+		Tags  []string       `structof:"tags,omitempty"`
+		Value any            `structof:"value"`
+		Attrs map[string]int `structof:"attrs"`
+		Skip  int            `structof:"-"`
+	}
+
+	want := map[string]any{
+		"name":  "",
+		"ptr":   (*int)(nil),
+		"tags":  []string(nil),
+		"value": nil,
+		"attrs": map[string]int(nil),
+	}
+	for _, enc := range []*Encoder{
+		NewEncoder(WithComplete()),
+		NewEncoder(WithOmitNils(), WithComplete()),
+		NewEncoder(WithComplete(), WithOmitNils()),
+	} {
+		if diff := cmp.Diff(want, enc.MakeMap(S{})); diff != "" {
+			t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+		}
+		if n := enc.MakeLazyMap(S{}).Len(); n != len(want) {
+			t.Errorf("LazyMap Len = %d, want %d", n, len(want))
+		}
+	}
+}
+
+func TestEncoderNils(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct{ A int }
+	type S struct {
+		Ptr   *Inner         `structof:"ptr"`
+		Map   map[string]int `structof:"map"`
+		Slice []int          `structof:"slice"`
+		Set   *int           `structof:"set"`
+	}
+	n := 1
+	s := S{Set: &n}
+
+	m := NewEncoder(WithUntypedNils(), WithUniformMaps()).MakeMap(s)
+	want := map[string]any{"ptr": nil, "map": nil, "slice": nil, "set": 1}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	for k, v := range m {
+		if k != "set" && v != nil {
+			t.Errorf("%s = %#v, want untyped nil", k, v)
+		}
+	}
+
+	enc := NewEncoder(WithOmitNils())
+	m = enc.MakeMap(s)
+	if want := map[string]any{"set": 1}; !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	if n := enc.MakeLazyMap(s).Len(); n != 1 {
+		t.Errorf("LazyMap Len = %d, want 1", n)
+	}
+}
+
+func TestEncoderEmptyStructMaps(t *testing.T) {
+	t.Parallel()
+
+	type Empty struct{ hidden int }
+	type S struct {
+		Empty Empty     `structof:"empty"`
+		Ptr   *Empty    `structof:"ptr"`
+		Any   any       `structof:"any"`
+		Time  time.Time `structof:"time"`
+	}
+	s := S{Ptr: &Empty{}, Any: Empty{}, Time: time.Unix(0, 0)}
+
+	m := NewEncoder(WithEmptyStructMaps()).MakeMap(s)
+	want := map[string]any{
+		"empty": map[string]any{},
+		"ptr":   map[string]any{},
+		"any":   map[string]any{},
+		"time":  time.Unix(0, 0),
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	a := NewEncoder(WithEmptyStructMaps()).MakeSlice(S{})
+	if !cmp.Equal([]any{}, a[1]) {
+		t.Errorf("MakeSlice empty = %#v, want []any{}", a[1])
+	}
+
+	if m := MakeMap(s); !cmp.Equal(Empty{}, m["empty"], cmp.AllowUnexported(Empty{})) {
+		t.Errorf("MakeMap empty = %#v, want Empty{}", m["empty"])
+	}
+}
+
+func TestEncoderKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	type Meta struct {
+		Version int
+	}
+	type S struct {
+		Name   string         `structof:"name"`
+		Labels map[string]int `structof:"labels"`
+		Meta   `structof:",inline"`
+	}
+	enc := NewEncoder(WithKeyFunc(func(f reflect.StructField, name string) string {
+		return strings.ToUpper(name) + "_" + f.Type.Kind().String()
+	}))
+	s := S{Name: "foo", Labels: map[string]int{"a": 1}, Meta: Meta{Version: 2}}
+
+	m := enc.MakeMap(s)
+	want := map[string]any{
+		"NAME_string": "foo",
+		"LABELS_map":  map[string]int{"a": 1},
+		"VERSION_int": 2,
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	if v, ok := enc.MakeLazyMap(s).Get("VERSION_int"); !ok || v != 2 {
+		t.Errorf(`LazyMap Get("VERSION_int") = %v, %t`, v, ok)
+	}
+}
+
+func TestEncoderValueFunc(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		Name  string  `structof:"name"`
+		Price float64 `structof:"price"`
+	}
+	type S struct {
+		Title  string   `structof:"title"`
+		Secret string   `structof:"secret"`
+		Items  []Item   `structof:"items"`
+		Tags   []string `structof:"tags"`
+		Next   *Item    `structof:"next"`
+	}
+	s := S{
+		Title:  "a very long title",
+		Secret: "hunter2",
+		Items:  []Item{{Name: "foo", Price: 1.2345}},
+		Tags:   []string{"abcdefgh", "x"},
+	}
+
+	var paths []string
+	enc := NewEncoder(WithValueFunc(func(path string, v any) (any, bool) {
+		paths = append(paths, path)
+		switch v := v.(type) {
+		case string:
+			if path == "secret" {
+				return nil, false
+			}
+			if len(v) > 6 {
+				return v[:6], true
+			}
+		case float64:
+			return math.Round(v*100) / 100, true
+		case *Item:
+			return nil, true
+		}
+		return v, true
+	}))
+	m := enc.MakeMap(s)
+	want := map[string]any{
+		"title": "a very",
+		"items": []any{map[string]any{"name": "foo", "price": 1.23}},
+		"tags":  []string{"abcdef", "x"},
+		"next":  nil,
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	wantPaths := []string{"title", "secret", "items.0.name", "items.0.price", "tags.0", "tags.1", "next"}
+	if !cmp.Equal(wantPaths, paths) {
+		t.Error(cmp.Diff(wantPaths, paths))
+	}
+}
+
+func TestEncoderVersion(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		ID     int    `structof:"id"`
+		Name   string `structof:"name,until=2"`
+		Full   string `structof:"full_name,since=2"`
+		Email  string `structof:"email,since=1.10,until=3"`
+		Legacy bool   `structof:"legacy,until=1.9"`
+	}
+	s := S{1, "foo", "foo bar", "foo@example.com", true}
+
+	tests := []struct {
+		version string
+		want    map[string]any
+	}{
+		{"", map[string]any{"id": 1, "name": "foo", "full_name": "foo bar", "email": "foo@example.com", "legacy": true}},
+		{"1", map[string]any{"id": 1, "name": "foo", "legacy": true}},
+		{"1.9", map[string]any{"id": 1, "name": "foo"}},
+		{"1.10", map[string]any{"id": 1, "name": "foo", "email": "foo@example.com"}},
+		{"2.0", map[string]any{"id": 1, "full_name": "foo bar", "email": "foo@example.com"}},
+		{"3", map[string]any{"id": 1, "full_name": "foo bar"}},
+	}
+	for _, tt := range tests {
+		enc := NewEncoder(WithVersion(tt.version))
+		if m := enc.MakeMap(s); !cmp.Equal(tt.want, m) {
+			t.Errorf("version %q: %s", tt.version, cmp.Diff(tt.want, m))
+		}
+		if n := enc.MakeLazyMap(s).Len(); len(tt.want) != n {
+			t.Errorf("version %q: LazyMap.Len() = %d, want %d", tt.version, n, len(tt.want))
+		}
+	}
+}
+
+func TestEncoderGroups(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		ID    int    `structof:"id"`
+		Email string `structof:"email,groups=admin|internal"`
+		Notes string `structof:"notes,groups=internal"`
+	}
+	s := S{1, "foo@example.com", "vip"}
+
+	tests := []struct {
+		groups []string
+		want   map[string]any
+	}{
+		{nil, map[string]any{"id": 1}},
+		{[]string{"admin"}, map[string]any{"id": 1, "email": "foo@example.com"}},
+		{[]string{"internal"}, map[string]any{"id": 1, "email": "foo@example.com", "notes": "vip"}},
+		{[]string{"public", "admin"}, map[string]any{"id": 1, "email": "foo@example.com"}},
+	}
+	for _, tt := range tests {
+		enc := NewEncoder(WithGroups(tt.groups...))
+		if m := enc.MakeMap(s); !cmp.Equal(tt.want, m) {
+			t.Errorf("groups %q: %s", tt.groups, cmp.Diff(tt.want, m))
+		}
+		if n := enc.MakeLazyMap(s).Len(); len(tt.want) != n {
+			t.Errorf("groups %q: LazyMap.Len() = %d, want %d", tt.groups, n, len(tt.want))
+		}
+	}
+}
+
+func TestEncoderFieldFilter(t *testing.T) {
+	t.Parallel()
+
+	type roleKey struct{}
+	type Account struct {
+		Balance int `structof:"balance" role:"admin"`
+		Owner   string
+	}
+	type S struct {
+		ID      int
+		Token   string `role:"admin"`
+		Account Account
+	}
+	s := S{1, "secret", Account{100, "foo"}}
+
+	var paths []string
+	enc := NewEncoder(WithFieldFilter(func(ctx context.Context, path string, field reflect.StructField) bool {
+		paths = append(paths, path)
+		role := field.Tag.Get("role")
+		return role == "" || role == ctx.Value(roleKey{})
+	}))
+
+	want := map[string]any{"ID": 1, "Account": map[string]any{"Owner": "foo"}}
+	if m := enc.MakeMap(s); !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	if want := []string{"ID", "Token", "Account", "Account.balance", "Account.Owner"}; !cmp.Equal(want, paths) {
+		t.Error(cmp.Diff(want, paths))
+	}
+
+	admin := enc.WithContext(context.WithValue(context.Background(), roleKey{}, "admin"))
+	want = map[string]any{"ID": 1, "Token": "secret", "Account": map[string]any{"balance": 100, "Owner": "foo"}}
+	if m := admin.MakeMap(s); !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	if _, ok := enc.MakeLazyMap(s).Get("Token"); ok {
+		t.Error("LazyMap.Get(Token) present, want omitted")
+	}
+	if _, ok := admin.MakeLazyMap(s).Get("Token"); !ok {
+		t.Error("LazyMap.Get(Token) omitted, want present")
+	}
+	if enc.Context() != context.Background() {
+		t.Error("Context() is not context.Background()")
+	}
+}
+
+func TestEncoderOmitFunc(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		Name     string `structof:"name"`
+		Price    int    `structof:"price"`
+		Currency string `structof:"currency"`
+	}
+	type S struct {
+		Items []Item `structof:"items"`
+	}
+
+	var paths []string
+	enc := NewEncoder(WithOmitFunc(func(path string, f Field) bool {
+		paths = append(paths, path)
+		if f.Name() != "Price" {
+			return false
+		}
+		currency, err := f.Parent().FieldByName("Currency")
+		return err != nil || currency.IsZero()
+	}))
+
+	s := S{[]Item{{"foo", 3, "EUR"}, {"bar", 4, ""}}}
+	want := map[string]any{"items": []any{
+		map[string]any{"name": "foo", "price": 3, "currency": "EUR"},
+		map[string]any{"name": "bar", "currency": ""},
+	}}
+	if m := enc.MakeMap(s); !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	wantPaths := []string{
+		"items",
+		"items.0.name", "items.0.price", "items.0.currency",
+		"items.1.name", "items.1.price", "items.1.currency",
+	}
+	if !cmp.Equal(wantPaths, paths) {
+		t.Error(cmp.Diff(wantPaths, paths))
+	}
+
+	lm := enc.MakeLazyMap(&s.Items[1])
+	if _, ok := lm.Get("price"); ok {
+		t.Error("LazyMap.Get(price) present, want omitted")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1", "1", 0},
+		{"2", "2.0.0", 0},
+		{"1.9", "1.10", -1},
+		{"1.10", "1.9", 1},
+		{"1.0-beta", "1.0-rc", -1},
+		{"v2", "v1", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); tt.want != got {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+type unexportedInner struct {
+	id    int
+	label string `structof:"lbl"`
+}
+
+type unexportedCounter struct {
+	n int
+}
+
+func TestEncoderUnexported(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Name    string
+		secret  string
+		inner   unexportedInner
+		ptr     *unexportedInner
+		items   []unexportedInner
+		any     any
+		when    time.Time
+		handler func()
+		unexportedCounter
+	}
+	when := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := S{
+		Name:              "foo",
+		secret:            "hunter2",
+		inner:             unexportedInner{1, "a"},
+		ptr:               &unexportedInner{2, "b"},
+		items:             []unexportedInner{{3, "c"}},
+		any:               unexportedInner{4, "d"},
+		when:              when,
+		unexportedCounter: unexportedCounter{5},
+	}
+
+	want := map[string]any{
+		"Name":    "foo",
+		"secret":  "hunter2",
+		"inner":   map[string]any{"id": 1, "lbl": "a"},
+		"ptr":     map[string]any{"id": 2, "lbl": "b"},
+		"items":   []any{map[string]any{"id": 3, "lbl": "c"}},
+		"any":     map[string]any{"id": 4, "lbl": "d"},
+		"when":    when,
+		"handler": nil,
+		"n":       5,
+	}
+	enc := NewEncoder(WithUnexported(), WithFuncNames(), WithUntypedNils())
+	for _, i := range []any{s, &s} {
+		m := enc.MakeMap(i)
+		if !cmp.Equal(want, m) {
+			t.Errorf("%T: %s", i, cmp.Diff(want, m))
+		}
+	}
+
+	if m := MakeMap(s); !cmp.Equal(map[string]any{"Name": "foo"}, m) {
+		t.Errorf("MakeMap without WithUnexported = %v", m)
+	}
+}
+
+func TestEncoderTagless(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		City string `structof:"city"`
+	}
+	type Embedded struct {
+		Level int `structof:"level,omitempty"`
+	}
+	type S struct {
+		Name     string `structof:"name" json:"full_name"`
+		Password string `structof:"-"`
+		Count    int    `structof:"count,omitempty,string"`
+		Inner    Inner  `structof:",inline"`
+		Ptr      *Inner `structof:"ptr,omitempty"`
+		Embedded
+		secret string
+	}
+	s := S{Name: "foo", Password: "hunter2", Inner: Inner{"Paris"}, secret: "x"}
+
+	want := map[string]any{
+		"Name":     "foo",
+		"Password": "hunter2",
+		"Count":    0,
+		"Inner":    map[string]any{"City": "Paris"},
+		"Ptr":      (*Inner)(nil),
+		"Level":    0,
+	}
+	if diff := cmp.Diff(want, NewEncoder(WithTagless()).MakeMap(s)); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+	checkLazyMap(t, NewEncoder(WithTagless()), s)
+
+	want["secret"] = "x"
+	if diff := cmp.Diff(want, NewEncoder(WithTagless(), WithUnexported()).MakeMap(&s)); diff != "" {
+		t.Errorf("MakeMap with WithUnexported mismatch (-want +got):\n%s", diff)
+	}
+	checkLazyMap(t, NewEncoder(WithTagless(), WithUnexported()), &s)
+
+	if m := MakeMap(s); !cmp.Equal(map[string]any{"name": "foo", "city": "Paris"}, m) {
+		t.Errorf("MakeMap without WithTagless = %v", m)
+	}
+}
+
+func TestEncoderIgnoreTypes(t *testing.T) {
+	t.Parallel()
+
+	type Handle struct {
+		ID int
+	}
+	type Base struct {
+		Handle
+		Kind string
+	}
+	type S struct {
+		Name    string
+		Ctx     context.Context
+		Mu      sync.Mutex
+		Handle  *Handle
+		Handles []Handle
+		Base    `structof:",inline"`
+	}
+	s := &S{Name: "foo", Ctx: context.Background(), Handle: &Handle{1}, Handles: []Handle{{2}}, Base: Base{Handle{3}, "k"}}
+
+	enc := NewEncoder(WithIgnoreTypes((*context.Context)(nil), sync.Mutex{}, reflect.TypeOf(Handle{})))
+	want := map[string]any{
+		"Name":    "foo",
+		"Handles": []any{map[string]any{"ID": 2}},
+		"Kind":    "k",
+	}
+	if diff := cmp.Diff(want, enc.MakeMap(s)); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+
+	enc = NewEncoder(WithIgnoreTypes(Base{}))
+	if m := enc.MakeMap(s); m["Kind"] != nil || m["ID"] != nil || m["Name"] != "foo" {
+		t.Errorf("MakeMap ignoring the inline type = %v", m)
+	}
+}
+
+func TestEncoderFields(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `structof:"city"`
+		Zip  string `structof:"zip"`
+	}
+	type Meta struct {
+		Source string `structof:"source"`
+	}
+	type Item struct {
+		SKU   string `structof:"sku"`
+		Price int    `structof:"price"`
+	}
+	type User struct {
+		Name     string  `structof:"name"`
+		Email    string  `structof:"email"`
+		Password string  `structof:"password"`
+		Address  Address `structof:"address"`
+		Meta     Meta    `structof:",inline"`
+		Items    []Item  `structof:"items"`
+	}
+	u := User{
+		Name: "foo", Email: "foo@example.com", Password: "hunter2",
+		Address: Address{"Paris", "75001"}, Meta: Meta{"web"},
+		Items: []Item{{"a", 1}},
+	}
+
+	tests := []struct {
+		opts []EncoderOption
+		want map[string]any
+	}{
+		{
+			[]EncoderOption{WithFields("Name", "Email")},
+			map[string]any{"name": "foo", "email": "foo@example.com"},
+		},
+		{
+			[]EncoderOption{WithFields("Address.City", "Meta", "Items.SKU")},
+			map[string]any{
+				"address": map[string]any{"city": "Paris"},
+				"source":  "web",
+				"items":   []any{map[string]any{"sku": "a"}},
+			},
+		},
+		{
+			[]EncoderOption{WithoutFields("Password", "Address.Zip", "Items")},
+			map[string]any{
+				"name": "foo", "email": "foo@example.com",
+				"address": map[string]any{"city": "Paris"},
+				"source":  "web",
+			},
+		},
+		{
+			[]EncoderOption{WithFields("Name", "Address"), WithoutFields("Address.Zip")},
+			map[string]any{"name": "foo", "address": map[string]any{"city": "Paris"}},
+		},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, NewEncoder(tt.opts...).MakeMap(&u)); diff != "" {
+			t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestEncoderKeyRenames(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `structof:"city"`
+		Name string `structof:"name"`
+	}
+	type Meta struct {
+		Source string `structof:"source"`
+	}
+	type S struct {
+		Name    string    `structof:"name"`
+		Address Address   `structof:"address"`
+		Prev    []Address `structof:"prev"`
+		Meta    Meta      `structof:",inline"`
+	}
+	s := S{Name: "foo", Address: Address{"Paris", "home"}, Prev: []Address{{"Rome", "old"}}, Meta: Meta{"web"}}
+
+	enc := NewEncoder(WithKeyRenames(map[string]string{
+		"name":         "label",
+		"address":      "addr",
+		"address.city": "town",
+		"prev.city":    "prev_town",
+		"source":       "origin",
+	}))
+	want := map[string]any{
+		"label":  "foo",
+		"addr":   map[string]any{"town": "Paris", "label": "home"},
+		"prev":   []any{map[string]any{"prev_town": "Rome", "label": "old"}},
+		"origin": "web",
+	}
+	if diff := cmp.Diff(want, enc.MakeMap(s)); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoderDrainChannels(t *testing.T) {
+	t.Parallel()
+
+	type Job struct {
+		ID int `structof:"id"`
+	}
+	type Queue struct {
+		Jobs    chan Job     `structof:"jobs"`
+		Events  <-chan int   `structof:"events"`
+		Nil     chan string  `structof:"nil"`
+		Unbuf   chan float64 `structof:"unbuf"`
+		Workers int          `structof:"workers"`
+	}
+	jobs, events := make(chan Job, 4), make(chan int, 4)
+	for i := 1; i <= 3; i++ {
+		jobs <- Job{i}
+		events <- i
+	}
+	close(events)
+	q := Queue{Jobs: jobs, Events: events, Unbuf: make(chan float64), Workers: 2}
+
+	want := map[string]any{
+		"jobs":    []any{map[string]any{"id": 1}, map[string]any{"id": 2}},
+		"events":  []int{1, 2},
+		"nil":     (chan string)(nil),
+		"unbuf":   []float64{},
+		"workers": 2,
+	}
+	if diff := cmp.Diff(want, NewEncoder(WithDrainChannels(2)).MakeMap(q)); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+	if n := len(jobs); n != 1 {
+		t.Errorf("len(jobs) = %d after draining, want 1", n)
+	}
+
+	m := NewEncoder(WithDrainChannels(-1)).MakeMap(q)
+	if diff := cmp.Diff([]any{map[string]any{"id": 3}}, m["jobs"]); diff != "" {
+		t.Errorf("jobs mismatch (-want +got):\n%s", diff)
+	}
+
+	defer func() {
+		if _, ok := recover().(*UnsupportedTypeError); !ok {
+			t.Error("MakeMap of a channel without WithDrainChannels should panic with an UnsupportedTypeError")
+		}
+	}()
+	MakeMap(q)
+}
@@ -0,0 +1,69 @@
+package structof
+
+import "reflect"
+
+// A SliceExporter is a container type, such as a ring buffer or a set,
+// that encodes as the elements its ExportSlice method returns rather than
+// as its own fields. The elements are encoded as those of a []any.
+type SliceExporter interface {
+	ExportSlice() []any
+}
+
+// A MapExporter is a container type, such as an ordered map, that encodes
+// as the map its ExportMap method returns rather than as its own fields.
+// The values are encoded as those of a map[string]any.
+type MapExporter interface {
+	ExportMap() map[string]any
+}
+
+var (
+	sliceExporterType = reflect.TypeOf((*SliceExporter)(nil)).Elem()
+	mapExporterType   = reflect.TypeOf((*MapExporter)(nil)).Elem()
+)
+
+// exportFunc returns the func exporting the values of type t if t implements
+// MapExporter, or else SliceExporter, or nil.
+func exportFunc(t reflect.Type) func(reflect.Value) any {
+	switch {
+	case t.Implements(mapExporterType):
+		return func(v reflect.Value) any { return v.Interface().(MapExporter).ExportMap() }
+	case t.Implements(sliceExporterType):
+		return func(v reflect.Value) any { return v.Interface().(SliceExporter).ExportSlice() }
+	}
+	return nil
+}
+
+// newExportEncoder returns the encoder of the type t if t, or a pointer to t,
+// implements MapExporter or SliceExporter, or nil. The values encoded at the
+// top level or inline, and those whose methods cannot be called, such as the
+// unaddressable values of pointer receivers, encode as if t did not.
+func newExportEncoder(t reflect.Type) encoderFunc {
+	if reflect.Interface == t.Kind() {
+		// The dynamic value is encoded by its own type.
+		return nil
+	}
+	export, addr := exportFunc(t), false
+	if export == nil && reflect.Pointer != t.Kind() {
+		export, addr = exportFunc(reflect.PointerTo(t)), true
+	}
+	if export == nil {
+		return nil
+	}
+
+	dflt := newDefaultEncoder(t)
+	return func(e *encodeState, key string, v reflect.Value, opts encOpts) {
+		if key == "" || opts.inline || !v.CanInterface() || addr && !v.CanAddr() {
+			dflt(e, key, v, opts)
+			return
+		}
+		if reflect.Pointer == v.Kind() && v.IsNil() {
+			e.setNil(key, v)
+			return
+		}
+		if addr {
+			v = v.Addr()
+		}
+		x := reflect.ValueOf(export(v))
+		typeEncoder(x.Type())(e, key, x, encOpts{})
+	}
+}
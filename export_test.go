@@ -0,0 +1,105 @@
+package structof
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type ring struct {
+	buf   [4]int
+	start int
+	n     int
+}
+
+func (r *ring) push(x int) {
+	r.buf[(r.start+r.n)%len(r.buf)] = x
+	if r.n < len(r.buf) {
+		r.n++
+	} else {
+		r.start = (r.start + 1) % len(r.buf)
+	}
+}
+
+func (r ring) ExportSlice() []any {
+	s := make([]any, r.n)
+	for i := range s {
+		s[i] = r.buf[(r.start+i)%len(r.buf)]
+	}
+	return s
+}
+
+type orderedMap struct {
+	Keys   []string
+	Values map[string]any
+}
+
+func (m *orderedMap) ExportMap() map[string]any {
+	return m.Values
+}
+
+type stringSet map[string]struct{}
+
+func (s stringSet) ExportSlice() []any {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	elems := make([]any, len(keys))
+	for i, k := range keys {
+		elems[i] = k
+	}
+	return elems
+}
+
+func TestExporters(t *testing.T) {
+	t.Parallel()
+
+	type Point struct{ X, Y int }
+	type Window struct {
+		Recent  ring
+		Labels  *orderedMap
+		Tags    stringSet
+		Nil     *ring
+		Options orderedMap
+	}
+
+	var r ring
+	for i := 1; i <= 6; i++ {
+		r.push(i)
+	}
+	w := &Window{
+		Recent: r,
+		Labels: &orderedMap{Keys: []string{"origin"}, Values: map[string]any{"origin": Point{1, 2}}},
+		Tags:   stringSet{"b": {}, "a": {}},
+		Options: orderedMap{
+			Keys:   []string{"debug"},
+			Values: map[string]any{"debug": true},
+		},
+	}
+
+	want := map[string]any{
+		"Recent":  []any{3, 4, 5, 6},
+		"Labels":  map[string]any{"origin": map[string]any{"X": 1, "Y": 2}},
+		"Tags":    []any{"a", "b"},
+		"Nil":     (*ring)(nil),
+		"Options": map[string]any{"debug": true},
+	}
+	if diff := cmp.Diff(want, MakeMap(w)); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+
+	// The pointer receiver of Options cannot be called on a copy.
+	m := MakeMap(*w)
+	if diff := cmp.Diff(map[string]any{"Keys": []string{"debug"}, "Values": map[string]any{"debug": true}}, m["Options"]); diff != "" {
+		t.Errorf("Options mismatch (-want +got):\n%s", diff)
+	}
+
+	// At the top level, the struct encodes as its fields.
+	want = map[string]any{"Keys": []string{"origin"}, "Values": map[string]any{"origin": map[string]any{"X": 1, "Y": 2}}}
+	if diff := cmp.Diff(want, MakeMap(w.Labels)); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+}
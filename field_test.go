@@ -1,8 +1,12 @@
 package structof
 
 import (
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/weiwenchen2022/structtag"
 )
 
@@ -146,6 +150,65 @@ func TestField_SetZero(t *testing.T) {
 	}
 }
 
+func TestField_SetFromString(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Port    int
+		Ratio   float64
+		Debug   bool
+		Timeout time.Duration
+		Start   time.Time
+		Tags    []string
+		Ports   []uint16
+		Data    []byte
+		Name    *string
+	}
+
+	var v S
+	s := MakeStruct(&v)
+	for name, str := range map[string]string{
+		"Port":    "8080",
+		"Ratio":   "0.5",
+		"Debug":   "true",
+		"Timeout": "1m30s",
+		"Start":   "2023-01-02T03:04:05Z",
+		"Tags":    "a, b,c",
+		"Ports":   "80,443",
+		"Data":    "raw",
+		"Name":    "foo",
+	} {
+		f, err := s.FieldByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.SetFromString(str); err != nil {
+			t.Errorf("SetFromString(%q) on %s: %v", str, name, err)
+		}
+	}
+
+	name := "foo"
+	want := S{
+		Port:    8080,
+		Ratio:   0.5,
+		Debug:   true,
+		Timeout: 90 * time.Second,
+		Start:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tags:    []string{"a", "b", "c"},
+		Ports:   []uint16{80, 443},
+		Data:    []byte("raw"),
+		Name:    &name,
+	}
+	if !cmp.Equal(want, v) {
+		t.Error(cmp.Diff(want, v))
+	}
+
+	f, _ := s.FieldByName("Ports")
+	if err := f.SetFromString("80,http"); err == nil {
+		t.Error("SetFromString with invalid element: got nil error")
+	}
+}
+
 func TestNonExistsField(t *testing.T) {
 	t.Parallel()
 
@@ -293,3 +356,404 @@ func TestNestField(t *testing.T) {
 		t.Errorf(`The value of the field 'B' inside 'S2' struct got %s want "foobar"`, b)
 	}
 }
+
+type Namer interface{ FullName() string }
+
+type person struct {
+	First, Last string
+}
+
+func (p person) FullName() string { return p.First + " " + p.Last }
+
+func TestFieldByNameEmbeddedInterface(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Namer
+		Name string
+	}
+
+	p := &person{"foo", "bar"}
+	s := MakeStruct(&S{Namer: p})
+	f, err := s.FieldByName("Namer.First")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Interface() != "foo" {
+		t.Errorf("Field Namer.First got %v want %q", f.Interface(), "foo")
+	}
+	f.Set("baz")
+	if p.First != "baz" {
+		t.Errorf("Field set through interface got %q want %q", p.First, "baz")
+	}
+
+	// A struct value held by the interface is readable but not settable.
+	s = MakeStruct(&S{Namer: person{"foo", "bar"}})
+	if f, err = s.FieldByName("Namer.Last"); err != nil {
+		t.Fatal(err)
+	}
+	if f.Interface() != "bar" {
+		t.Errorf("Field Namer.Last got %v want %q", f.Interface(), "bar")
+	}
+
+	if _, err := MakeStruct(&S{}).FieldByName("Namer.First"); err == nil {
+		t.Error("FieldByName through a nil interface should return error")
+	}
+}
+
+func TestStructUnexported(t *testing.T) {
+	t.Parallel()
+
+	type inner struct {
+		count int
+	}
+	type S struct {
+		Name   string
+		secret string
+		inner  *inner
+		holder any
+	}
+	s := MakeStruct(&S{"foo", "hunter2", &inner{3}, inner{4}})
+
+	if _, err := s.FieldByName("secret"); err == nil {
+		t.Error("FieldByName of an unexported field should return error")
+	}
+
+	u := s.Unexported()
+	f, err := u.FieldByName("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Interface() != "hunter2" || f.IsZero() || f.Kind() != reflect.String {
+		t.Errorf("Field secret got %v, IsZero %t, Kind %s", f.Interface(), f.IsZero(), f.Kind())
+	}
+	if f, err = u.FieldByName("inner.count"); err != nil {
+		t.Fatal(err)
+	}
+	if f.Interface() != 3 {
+		t.Errorf("Field inner.count got %v want 3", f.Interface())
+	}
+	if _, err := u.FieldByName("holder.count"); err == nil {
+		t.Error("FieldByName of an unexported field of a struct value held by an interface should return error")
+	}
+
+	for name, fn := range map[string]func(Field){
+		"Set":           func(f Field) { f.Set(4) },
+		"SetZero":       func(f Field) { f.SetZero() },
+		"SetFromString": func(f Field) { f.SetFromString("4") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s of a read-only field did not panic", name)
+				}
+			}()
+			fn(f)
+		}()
+	}
+
+	var names []string
+	for _, f := range u.Fields() {
+		names = append(names, f.Name())
+		_ = f.Interface()
+	}
+	if want := []string{"Name", "secret", "inner", "holder"}; !cmp.Equal(want, names) {
+		t.Error(cmp.Diff(want, names))
+	}
+}
+
+func TestField_Elem(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		P   *int
+		I   any
+		N   *int
+		A   int
+		PP  **string
+		Err error
+	}
+	n, str := 1, "foo"
+	ps := &str
+	v := S{P: &n, I: 2.5, PP: &ps}
+	s := MakeStruct(&v)
+
+	f, _ := s.FieldByName("P")
+	e, err := f.Elem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name() != "P" || e.Kind() != reflect.Int || e.Interface() != 1 {
+		t.Errorf("Elem of P = %s %s %v", e.Name(), e.Kind(), e.Interface())
+	}
+	e.Set(3)
+	if n != 3 {
+		t.Errorf("Set through Elem: n = %d, want 3", n)
+	}
+
+	f, _ = s.FieldByName("I")
+	if e, err := f.Elem(); err != nil || e.Type() != reflect.TypeOf(0.0) || e.Interface() != 2.5 {
+		t.Errorf("Elem of I = %v, %v", e, err)
+	}
+
+	f, _ = s.FieldByName("PP")
+	if e, err := f.Elem(); err != nil {
+		t.Error(err)
+	} else if e, err := e.Elem(); err != nil || e.Interface() != "foo" {
+		t.Errorf("Elem of Elem of PP = %v, %v", e, err)
+	}
+
+	for _, name := range []string{"N", "A", "Err"} {
+		f, _ := s.FieldByName(name)
+		if _, err := f.Elem(); err == nil {
+			t.Errorf("Elem of %s should return error", name)
+		}
+	}
+}
+
+func TestField_Map(t *testing.T) {
+	t.Parallel()
+
+	type Key string
+	type S struct {
+		M map[Key]int
+		A int
+	}
+	v := S{}
+	s := MakeStruct(&v)
+	f, _ := s.FieldByName("M")
+
+	if _, ok := f.MapIndex("a"); ok {
+		t.Error("MapIndex of nil map: found")
+	}
+	f.DeleteMapIndex("a")
+	f.SetMapIndex("a", 1)
+	f.SetMapIndex(Key("b"), int8(2))
+	if want := map[Key]int{"a": 1, "b": 2}; !cmp.Equal(want, v.M) {
+		t.Error(cmp.Diff(want, v.M))
+	}
+
+	if e, ok := f.MapIndex("b"); !ok || e.Interface() != 2 || e.Name() != "M" {
+		t.Errorf("MapIndex(b) = %v, %t", e.Interface(), ok)
+	}
+	keys := f.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].(Key) < keys[j].(Key) })
+	if want := []any{Key("a"), Key("b")}; !cmp.Equal(want, keys) {
+		t.Error(cmp.Diff(want, keys))
+	}
+
+	f.DeleteMapIndex("a")
+	if want := map[Key]int{"b": 2}; !cmp.Equal(want, v.M) {
+		t.Error(cmp.Diff(want, v.M))
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("MapKeys of non-map field should panic")
+		}
+	}()
+	f, _ = s.FieldByName("A")
+	f.MapKeys()
+}
+
+func TestField_Len(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		S  string
+		L  []int
+		A  [3]bool
+		M  map[string]int
+		C  chan int
+		NL []int
+		I  int
+		P  *[]int
+	}
+	v := S{S: "héllo", L: []int{1, 2}, M: map[string]int{"a": 1}, C: make(chan int, 2)}
+	v.C <- 1
+	s := MakeStruct(&v)
+
+	for name, want := range map[string]int{"S": 6, "L": 2, "A": 3, "M": 1, "C": 1, "NL": 0} {
+		f, _ := s.FieldByName(name)
+		if n, err := f.Len(); err != nil || n != want {
+			t.Errorf("Len of %s = %d, %v, want %d", name, n, err, want)
+		}
+	}
+	for _, name := range []string{"I", "P"} {
+		f, _ := s.FieldByName(name)
+		if _, err := f.Len(); err == nil {
+			t.Errorf("Len of %s should return error", name)
+		}
+	}
+}
+
+func TestField_IsNil(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		P  *int
+		I  any
+		M  map[string]int
+		L  []int
+		F  func()
+		C  chan int
+		N  int
+		St struct{}
+		EL []int
+	}
+	s := MakeStruct(&S{EL: []int{}})
+
+	for name, want := range map[string]bool{"P": true, "I": true, "M": true, "L": true, "F": true, "C": true, "N": false, "St": false, "EL": false} {
+		f, _ := s.FieldByName(name)
+		if got := f.IsNil(); got != want {
+			t.Errorf("IsNil of %s = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestField_KindPredicates(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct{}
+	type S struct {
+		St  Inner
+		PSt **Inner
+		L   []int
+		PL  *[]int
+		M   map[string]int
+		N   int
+		PN  *float64
+		Str string
+		T   time.Time
+		I   any
+		A   [2]int
+		C   complex128
+	}
+	s := MakeStruct(&S{})
+
+	type kinds struct{ isStruct, isSlice, isMap, isPointer, isPrimitive bool }
+	for name, want := range map[string]kinds{
+		"St":  {isStruct: true},
+		"PSt": {isStruct: true, isPointer: true},
+		"L":   {isSlice: true},
+		"PL":  {isSlice: true, isPointer: true},
+		"M":   {isMap: true},
+		"N":   {isPrimitive: true},
+		"PN":  {isPrimitive: true, isPointer: true},
+		"Str": {isPrimitive: true},
+		"T":   {isStruct: true},
+		"I":   {},
+		"A":   {},
+		"C":   {},
+	} {
+		f, _ := s.FieldByName(name)
+		got := kinds{f.IsStruct(), f.IsSlice(), f.IsMap(), f.IsPointer(), f.IsPrimitive()}
+		if want != got {
+			t.Errorf("predicates of %s = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestField_Struct(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `structof:"city"`
+	}
+	type S struct {
+		Address Address
+		Ptr     *Address
+		Nil     *Address
+		N       int
+	}
+	v := S{Address: Address{"Paris"}, Ptr: &Address{"Rome"}}
+	s := MakeStruct(&v)
+
+	f, _ := s.FieldByName("Address")
+	a, err := f.Struct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := a.MakeMap(); !cmp.Equal(map[string]any{"city": "Paris"}, m) {
+		t.Errorf("MakeMap of Address = %v", m)
+	}
+	city, _ := a.FieldByName("City")
+	city.Set("Lyon")
+	if v.Address.City != "Lyon" {
+		t.Errorf("City = %q, want Lyon", v.Address.City)
+	}
+
+	f, _ = s.FieldByName("Ptr")
+	if p, err := f.Struct(); err != nil || p.Addr() != any(v.Ptr) {
+		t.Errorf("Struct of Ptr = %v, %v", p.Addr(), err)
+	}
+
+	for _, name := range []string{"Nil", "N"} {
+		f, _ := s.FieldByName(name)
+		if _, err := f.Struct(); err == nil {
+			t.Errorf("Struct of %s should return error", name)
+		}
+	}
+}
+
+func TestField_Value(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		N int
+	}
+	type S struct {
+		A      []int
+		I      any
+		hidden int
+	}
+	v := S{A: []int{1}, I: Inner{2}, hidden: 3}
+	s := MakeStruct(&v)
+
+	f, _ := s.FieldByName("A")
+	rv := f.Value()
+	if !rv.CanSet() {
+		t.Fatal("Value of A not settable")
+	}
+	rv.Set(reflect.Append(rv, reflect.ValueOf(2)))
+	if !cmp.Equal([]int{1, 2}, v.A) {
+		t.Errorf("A = %v", v.A)
+	}
+
+	f, _ = s.FieldByName("I.N")
+	if rv := f.Value(); rv.CanSet() || rv.Int() != 2 {
+		t.Errorf("Value of I.N = %v, settable %t", rv, rv.CanSet())
+	}
+
+	f, _ = s.Unexported().FieldByName("hidden")
+	if rv := f.Value(); rv.CanInterface() || rv.Int() != 3 {
+		t.Errorf("Value of hidden = %v, CanInterface %t", rv, rv.CanInterface())
+	}
+}
+
+func TestField_Named(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		ID      int    `json:"id" db:"user_id"`
+		Name    string `json:"name,omitempty"`
+		Secret  string `json:"-" db:"secret"`
+		Created time.Time
+	}
+	var jsonKeys, columns []string
+	for _, f := range Fields(&S{}) {
+		if name := f.Named("json").Name(); name != "-" {
+			jsonKeys = append(jsonKeys, name)
+		}
+		columns = append(columns, f.Named("db").Name())
+		if f.Named("db").Type() != f.Type() {
+			t.Errorf("Named changed the type of %s", f.Name())
+		}
+	}
+	if want := []string{"id", "name", "Created"}; !cmp.Equal(want, jsonKeys) {
+		t.Error(cmp.Diff(want, jsonKeys))
+	}
+	if want := []string{"user_id", "Name", "secret", "Created"}; !cmp.Equal(want, columns) {
+		t.Error(cmp.Diff(want, columns))
+	}
+}
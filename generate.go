@@ -0,0 +1,265 @@
+package structof
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateGo returns Go source declaring a struct type named typeName
+// whose structof tags reproduce the keys of m.
+//
+// Field names are derived from the keys by converting them to exported
+// camel case identifiers, and field types are inferred from the dynamic
+// types of the elements. Nested map[string]any elements, and []any elements
+// holding such maps, become separate struct types named after the enclosing
+// type and the field. Nil elements and slices of mixed types are declared as
+// any and []any respectively.
+//
+// The returned source has no package clause, only import declarations
+// followed by the type declarations, and is formatted with go/format.
+// A nested type whose name is already declared is suffixed by a number.
+// GenerateGo returns an error if typeName is not a valid exported identifier,
+// if a key is not a valid structof tag name, if two keys map to the same
+// field name, or if the elements are of types of two packages of the same
+// name.
+func GenerateGo(m map[string]any, typeName string) (string, error) {
+	if !token.IsIdentifier(typeName) || !token.IsExported(typeName) {
+		return "", fmt.Errorf("structof: invalid type name %q", typeName)
+	}
+
+	g := &generator{imports: make(map[string]string), names: map[string]bool{typeName: true}}
+	if err := g.genStruct(typeName, m); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		buf.WriteString("import (\n")
+		for _, path := range paths {
+			// The types are qualified by the package name, which may
+			// differ from the last element of the path, as for
+			// gopkg.in/yaml.v3.
+			if name := g.imports[path]; name != path[strings.LastIndex(path, "/")+1:] {
+				fmt.Fprintf(&buf, "\t%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&buf, "\t%q\n", path)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	for i, decl := range g.decls {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(decl)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("structof: format generated source: %w", err)
+	}
+	return string(src), nil
+}
+
+// A generator accumulates the type declarations and imports of GenerateGo.
+type generator struct {
+	decls []string
+
+	// Names of the packages imported by path.
+	imports map[string]string
+
+	// Names of the types declared.
+	names map[string]bool
+}
+
+func (g *generator) genStruct(typeName string, m map[string]any) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Reserve the slot so that the outer type is declared before nested ones.
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type %s struct {\n", typeName)
+
+	seen := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if !isValidTag(k) {
+			return fmt.Errorf("structof: key %q is not a valid tag name", k)
+		}
+		name := exportedName(k)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("structof: keys %q and %q both map to field %s", other, k, name)
+		}
+		seen[name] = k
+
+		typ, err := g.goType(typeName+name, m[k])
+		if err != nil {
+			return err
+		}
+		tag := k
+		if tag == "-" {
+			// The tag "-" omits the field.
+			tag = "-,"
+		}
+		fmt.Fprintf(&buf, "\t%s %s `structof:%q`\n", name, typ, tag)
+	}
+	buf.WriteString("}\n")
+
+	g.decls[slot] = buf.String()
+	return nil
+}
+
+// goType returns the Go type expression for v.
+// Nested structs are declared under name.
+func (g *generator) goType(name string, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "any", nil
+	case map[string]any:
+		name = g.typeName(name)
+		if err := g.genStruct(name, v); err != nil {
+			return "", err
+		}
+		return name, nil
+	case []any:
+		return g.sliceType(name, v)
+	}
+
+	t := reflect.TypeOf(v)
+	if err := g.addImports(t); err != nil {
+		return "", err
+	}
+	return t.String(), nil
+}
+
+// typeName returns name, suffixed by a number if a type of that name is
+// already declared, as "UserABC" is both for the field A holding a key
+// "b_c" and for the field AB holding a key "c".
+func (g *generator) typeName(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+// sliceType returns the Go type expression for a []any whose elements share
+// one type, or []any otherwise. Maps among the elements are merged into a
+// single struct type declared under name.
+func (g *generator) sliceType(name string, s []any) (string, error) {
+	var (
+		elem   reflect.Type
+		merged map[string]any
+		mixed  bool
+	)
+	for _, e := range s {
+		if e == nil {
+			mixed = true
+			continue
+		}
+		t := reflect.TypeOf(e)
+		if elem == nil {
+			elem = t
+		} else if elem != t {
+			mixed = true
+		}
+		if m, ok := e.(map[string]any); ok {
+			if merged == nil {
+				merged = make(map[string]any)
+			}
+			for k, v := range m {
+				if _, ok := merged[k]; !ok {
+					merged[k] = v
+				}
+			}
+		}
+	}
+	if elem == nil || mixed {
+		return "[]any", nil
+	}
+
+	var (
+		typ string
+		err error
+	)
+	if merged != nil {
+		typ, err = g.goType(name, merged)
+	} else {
+		typ, err = g.goType(name, s[0])
+	}
+	if err != nil {
+		return "", err
+	}
+	return "[]" + typ, nil
+}
+
+// addImports records the package paths and names of the named types
+// referenced by t. It returns an error if two packages of the same name
+// are referenced, their types being qualified alike by t.String.
+func (g *generator) addImports(t reflect.Type) error {
+	if t.Name() != "" {
+		path := t.PkgPath()
+		if path == "" {
+			return nil
+		}
+		name, _, _ := strings.Cut(t.String(), ".")
+		for other, otherName := range g.imports {
+			if otherName == name && other != path {
+				return fmt.Errorf("structof: packages %q and %q are both named %s", other, path, name)
+			}
+		}
+		g.imports[path] = name
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Chan, reflect.Pointer, reflect.Slice:
+		return g.addImports(t.Elem())
+	case reflect.Map:
+		if err := g.addImports(t.Key()); err != nil {
+			return err
+		}
+		return g.addImports(t.Elem())
+	}
+	return nil
+}
+
+// exportedName converts a key such as "user_id" or "createdAt"
+// into an exported Go identifier such as "UserId" or "CreatedAt".
+func exportedName(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, c := range key {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			upper = true
+			continue
+		}
+		if upper {
+			c = unicode.ToUpper(c)
+			upper = false
+		}
+		b.WriteRune(c)
+	}
+	if name := b.String(); token.IsExported(name) {
+		return name
+	}
+	return "F" + b.String()
+}
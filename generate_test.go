@@ -0,0 +1,141 @@
+package structof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/weiwenchen2022/structof/testdata/genpkg"
+	genpkgv2 "github.com/weiwenchen2022/structof/testdata/genpkg/v2"
+)
+
+func TestGenerateGo(t *testing.T) {
+	t.Parallel()
+
+	m := map[string]any{
+		"user_id":   23,
+		"name":      "foobar",
+		"createdAt": time.Time{},
+		"extra":     nil,
+		"address":   map[string]any{"city": "Paris"},
+		"tags":      []any{"a", "b"},
+		"items":     []any{map[string]any{"sku": "x"}, map[string]any{"qty": 2}},
+	}
+	got, err := GenerateGo(m, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `import (
+	"time"
+)
+
+type User struct {
+	Address   UserAddress ` + "`structof:\"address\"`" + `
+	CreatedAt time.Time   ` + "`structof:\"createdAt\"`" + `
+	Extra     any         ` + "`structof:\"extra\"`" + `
+	Items     []UserItems ` + "`structof:\"items\"`" + `
+	Name      string      ` + "`structof:\"name\"`" + `
+	Tags      []string    ` + "`structof:\"tags\"`" + `
+	UserId    int         ` + "`structof:\"user_id\"`" + `
+}
+
+type UserAddress struct {
+	City string ` + "`structof:\"city\"`" + `
+}
+
+type UserItems struct {
+	Qty int    ` + "`structof:\"qty\"`" + `
+	Sku string ` + "`structof:\"sku\"`" + `
+}
+`
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGenerateGoNames(t *testing.T) {
+	t.Parallel()
+
+	m := map[string]any{
+		"a":     map[string]any{"b_c": map[string]any{"x": 1}},
+		"a_b":   map[string]any{"c": map[string]any{"y": 2}},
+		"point": genpkgv2.Point{},
+	}
+	got, err := GenerateGo(m, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `import (
+	genpkg "github.com/weiwenchen2022/structof/testdata/genpkg/v2"
+)
+
+type User struct {
+	A     UserA        ` + "`structof:\"a\"`" + `
+	AB    UserAB       ` + "`structof:\"a_b\"`" + `
+	Point genpkg.Point ` + "`structof:\"point\"`" + `
+}
+
+type UserA struct {
+	BC UserABC ` + "`structof:\"b_c\"`" + `
+}
+
+type UserABC struct {
+	X int ` + "`structof:\"x\"`" + `
+}
+
+type UserAB struct {
+	C UserABC2 ` + "`structof:\"c\"`" + `
+}
+
+type UserABC2 struct {
+	Y int ` + "`structof:\"y\"`" + `
+}
+`
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGenerateGoErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		m        map[string]any
+		typeName string
+	}{
+		{map[string]any{}, "lower"},
+		{map[string]any{}, "Not Ident"},
+		{map[string]any{`a"b`: 1}, "T"},
+		{map[string]any{"user_id": 1, "userId": 2}, "T"},
+		{map[string]any{"p": genpkg.Point{}, "q": genpkgv2.Point{}}, "T"},
+	}
+	for _, tt := range tests {
+		if _, err := GenerateGo(tt.m, tt.typeName); err == nil {
+			t.Errorf("GenerateGo(%v, %q) should return error", tt.m, tt.typeName)
+		}
+	}
+}
+
+func TestGenerateGoDash(t *testing.T) {
+	t.Parallel()
+
+	got, err := GenerateGo(map[string]any{"-": 1}, "T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "type T struct {\n\tF int `structof:\"-,\"`\n}\n"
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	// The generated type decodes the key back.
+	var s struct {
+		F int `structof:"-,"`
+	}
+	if err := FillStruct(map[string]any{"-": 1}, &s); err != nil || s.F != 1 {
+		t.Errorf("FillStruct = %+v, %v", s, err)
+	}
+}
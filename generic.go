@@ -0,0 +1,88 @@
+package structof
+
+import "reflect"
+
+// A typedEncoder is the encoder of the pointer to a static type T of
+// MakeMapOf, resolved at the first use of T.
+type typedEncoder struct {
+	enc encoderFunc
+	// depth is the number of pointers T has to its struct type.
+	depth int
+}
+
+var typedEncoderCache typeCache[typedEncoder]
+
+// typedEncoderOf returns the typedEncoder of T, building it on first use.
+// It panics if T is not a struct or pointer to struct.
+func typedEncoderOf[T any]() typedEncoder {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if te, ok := typedEncoderCache.Load(t); ok {
+		return te
+	}
+
+	st, depth := t, 0
+	for reflect.Pointer == st.Kind() {
+		st, depth = st.Elem(), depth+1
+	}
+	if reflect.Struct != st.Kind() {
+		panic("not struct or pointer to struct")
+	}
+	te := typedEncoder{enc: typeEncoder(reflect.PointerTo(t)), depth: depth}
+	te, _ = typedEncoderCache.LoadOrStore(t, te)
+	return te
+}
+
+// encode encodes the value at p with e. It panics if one of the pointers
+// of p's value to its struct is nil.
+func (te typedEncoder) encode(e *encodeState, p reflect.Value, opts encOpts) {
+	for i, v := 0, p.Elem(); i < te.depth; i, v = i+1, v.Elem() {
+		if v.IsNil() {
+			panic("not struct or pointer to struct")
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if se, ok := r.(structofError); ok {
+				r = se.error
+			}
+			panic(r)
+		}
+	}()
+	te.enc(e, "", p, opts)
+}
+
+// MakeMapOf is like MakeMap but takes the struct with its static type.
+// The encoder of T is resolved once, at the first call for T, and v is
+// encoded through a pointer to it rather than through an interface.
+// T is not constrained: it panics if T is not a struct or pointer to
+// struct, or if the pointers of v are nil.
+// See FillMap function's documentation for more information.
+func MakeMapOf[T any](v T) map[string]any {
+	te := typedEncoderOf[T]()
+	m := make(map[string]any)
+	e, put := newEncodeState(defaultEncoder, m)
+	defer put()
+	te.encode(e, reflect.ValueOf(&v), encOpts{})
+	return m
+}
+
+// MakeSliceOf is like MakeSlice but takes the struct with its static type.
+// See MakeMapOf function's documentation for more information.
+func MakeSliceOf[T any](v T) []any {
+	te := typedEncoderOf[T]()
+	var a []any
+	e, put := newEncodeState(defaultEncoder, a)
+	defer put()
+	te.encode(e, reflect.ValueOf(&v), encOpts{structConvertToSlice: true})
+	return e.Interface().([]any)
+}
+
+// FieldsOf is like Fields but takes a pointer to the struct with its static type.
+// It panics if v is nil or T is not a struct.
+func FieldsOf[T any](v *T) []Field {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if v == nil || reflect.Struct != t.Kind() {
+		panic("not non-nil pointer to struct")
+	}
+	return fieldsOf(reflect.ValueOf(v).Elem(), cachedTypeFields(t))
+}
@@ -0,0 +1,111 @@
+package structof
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMakeMapOf(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A int    `structof:"a"`
+		B string `structof:"b"`
+	}
+
+	m := MakeMapOf(T{23, "foobar"})
+	want := map[string]any{"a": 23, "b": "foobar"}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	m = MakeMapOf(&T{23, "foobar"})
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	s := MakeSliceOf(T{23, "foobar"})
+	wantSlice := []any{"a", 23, "b", "foobar"}
+	if !cmp.Equal(wantSlice, s) {
+		t.Error(cmp.Diff(wantSlice, s))
+	}
+}
+
+func TestFieldsOf(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A int
+		B string
+	}
+
+	fields := FieldsOf(&T{23, "foobar"})
+	names := make([]string, len(fields))
+	for i := range fields {
+		names[i] = fields[i].Name()
+	}
+	want := []string{"A", "B"}
+	if !cmp.Equal(want, names) {
+		t.Error(cmp.Diff(want, names))
+	}
+}
+
+func TestMakeMapOfPointers(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A int        `structof:"a"`
+		P *T         `structof:"p,omitempty"`
+		I any        `structof:"i,omitempty"`
+		M [2]float64 `structof:"m"`
+	}
+
+	v := &T{A: 1, P: &T{A: 2}, I: T{A: 3}}
+	pv := &v
+	for i := 0; i < 2; i++ {
+		// The second calls use the encoders resolved by the first.
+		want := MakeMap(v)
+		if m := MakeMapOf(pv); !cmp.Equal(want, m) {
+			t.Error(cmp.Diff(want, m))
+		}
+		if m := MakeMapOf(*v); !cmp.Equal(want, m) {
+			t.Error(cmp.Diff(want, m))
+		}
+		wantSlice := MakeSlice(v)
+		if s := MakeSliceOf(pv); !cmp.Equal(wantSlice, s) {
+			t.Error(cmp.Diff(wantSlice, s))
+		}
+	}
+}
+
+func TestMakeMapOfPanics(t *testing.T) {
+	t.Parallel()
+
+	type T struct{ A int }
+
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"non struct", func() { MakeMapOf("") }},
+		{"non struct pointer", func() { MakeSliceOf(new(int)) }},
+		{"nil pointer", func() { MakeMapOf((*T)(nil)) }},
+		{"nil pointer to pointer", func() { MakeMapOf(new(*T)) }},
+		{"fields of nil", func() { FieldsOf((*T)(nil)) }},
+		{"fields of non struct", func() { FieldsOf(new(int)) }},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("should panic")
+				}
+			}()
+			tt.f()
+		})
+	}
+}
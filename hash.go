@@ -0,0 +1,230 @@
+package structof
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Hash writes a canonical representation of the struct s into h
+// and returns the resulting h.Sum64().
+//
+// The representation is computed over the map that MakeCanonicalMap
+// produces, so it honors the structof tags: omitted fields do not contribute
+// and renamed fields contribute under their keys. Map entries are written in
+// sorted key order, and since the values are canonicalized, two structs with
+// the same encoded content hash equally even if their field types differ
+// in width.
+//
+// Hash panics in the same cases as MakeMap.
+func Hash(s any, h hash.Hash64) uint64 {
+	writeCanonical(h, MakeCanonicalMap(s))
+	return h.Sum64()
+}
+
+// Fingerprint returns the 64-bit FNV-1a Hash of the struct s,
+// for use as a cache key or for change detection.
+func Fingerprint(s any) uint64 {
+	return Hash(s, fnv.New64a())
+}
+
+// CanonicalBytes returns the canonical representation of the struct s that
+// Hash computes its result over. Two structs with equal MakeCanonicalMap
+// results have byte-identical representations, making it suitable for ETags
+// and signatures.
+//
+// CanonicalBytes panics in the same cases as MakeMap.
+func CanonicalBytes(s any) []byte {
+	var buf bytes.Buffer
+	writeCanonical(&buf, MakeCanonicalMap(s))
+	return buf.Bytes()
+}
+
+// MakeCanonicalMap is like MakeMap but returns the map in canonical form,
+// as by Canonicalize.
+func MakeCanonicalMap(s any) map[string]any {
+	return Canonicalize(MakeMap(s)).(map[string]any)
+}
+
+// Canonicalize returns a copy of v with normalized value types, so that
+// values with the same content compare equal regardless of their declared
+// types. Integers become int64, unsigned integers uint64, floating-point
+// numbers float64, and booleans, strings and byte slices their unnamed types.
+// Other slices and arrays become []any and maps become map[string]any, with
+// their elements canonicalized recursively and non-string keys formatted with
+// fmt.Sprint. Pointers and interfaces are replaced by the canonical
+// pointed-to value, or nil, and values implementing encoding.TextMarshaler,
+// such as time.Time, by their text. Any other value is returned as is.
+func Canonicalize(v any) any {
+	return canonicalize(reflect.ValueOf(v))
+}
+
+func canonicalize(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		if reflect.Pointer == v.Kind() && v.IsNil() {
+			return nil
+		}
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return canonicalize(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if reflect.Slice == v.Kind() && v.IsNil() {
+			return nil
+		}
+		if reflect.Uint8 == v.Type().Elem().Kind() {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return b
+		}
+		s := make([]any, v.Len())
+		for i := range s {
+			s[i] = canonicalize(v.Index(i))
+		}
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			m[fmt.Sprint(it.Key())] = canonicalize(it.Value())
+		}
+		return m
+	}
+	return v.Interface()
+}
+
+// Tags of the canonical representation.
+const (
+	canonNil    = 'n'
+	canonFalse  = 'f'
+	canonTrue   = 't'
+	canonInt    = 'i'
+	canonUint   = 'u'
+	canonFloat  = 'd'
+	canonString = 's'
+	canonBytes  = 'y'
+	canonList   = 'l'
+	canonMap    = 'm'
+	canonText   = 'x'
+)
+
+// writeCanonical writes the canonical representation of v into w.
+func writeCanonical(w io.Writer, v any) {
+	c := canonicalWriter{w: w}
+	c.value(reflect.ValueOf(v))
+}
+
+type canonicalWriter struct {
+	w   io.Writer
+	buf [9]byte
+}
+
+func (c *canonicalWriter) tag(b byte) {
+	c.buf[0] = b
+	c.w.Write(c.buf[:1])
+}
+
+func (c *canonicalWriter) uint64(b byte, n uint64) {
+	c.buf[0] = b
+	binary.BigEndian.PutUint64(c.buf[1:], n)
+	c.w.Write(c.buf[:])
+}
+
+func (c *canonicalWriter) bytes(b byte, p []byte) {
+	c.uint64(b, uint64(len(p)))
+	c.w.Write(p)
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func (c *canonicalWriter) value(v reflect.Value) {
+	if !v.IsValid() {
+		c.tag(canonNil)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			c.tag(canonTrue)
+		} else {
+			c.tag(canonFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.uint64(canonInt, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		c.uint64(canonUint, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		c.uint64(canonFloat, math.Float64bits(v.Float()))
+	case reflect.String:
+		c.bytes(canonString, []byte(v.String()))
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			c.tag(canonNil)
+			return
+		}
+		c.value(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if reflect.Slice == v.Kind() && v.IsNil() {
+			c.tag(canonNil)
+			return
+		}
+		if reflect.Uint8 == v.Type().Elem().Kind() {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			c.bytes(canonBytes, b)
+			return
+		}
+		c.uint64(canonList, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			c.value(v.Index(i))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			c.tag(canonNil)
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		c.uint64(canonMap, uint64(len(keys)))
+		for _, k := range keys {
+			c.value(k)
+			c.value(v.MapIndex(k))
+		}
+	default:
+		// Opaque values without a canonical form.
+		c.bytes(canonText, []byte(fmt.Sprint(v)))
+	}
+}
@@ -0,0 +1,118 @@
+package structof
+
+import (
+	"bytes"
+	"hash/fnv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			City string `structof:"city"`
+		}
+		T1 struct {
+			ID      int32             `structof:"id"`
+			Name    string            `structof:"name"`
+			Secret  string            `structof:"-"`
+			At      time.Time         `structof:"at"`
+			Labels  map[string]string `structof:"labels"`
+			Address *Address          `structof:"address"`
+		}
+		T2 struct {
+			Name    string            `structof:"name"`
+			ID      int64             `structof:"id"`
+			At      time.Time         `structof:"at"`
+			Labels  map[string]string `structof:"labels"`
+			Address *Address          `structof:"address"`
+		}
+	)
+
+	at := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	labels := map[string]string{"a": "1", "b": "2", "c": "3"}
+	t1 := T1{23, "foobar", "secret", at, labels, &Address{"Paris"}}
+	t2 := T2{"foobar", 23, at, labels, &Address{"Paris"}}
+
+	fp := Fingerprint(t1)
+	if got := Fingerprint(t2); fp != got {
+		t.Errorf("Fingerprint of equally encoded structs differ: %x != %x", fp, got)
+	}
+	if got := Hash(&t1, fnv.New64a()); fp != got {
+		t.Errorf("Hash(fnv) = %x, want Fingerprint %x", got, fp)
+	}
+
+	t1.Secret = "changed"
+	if got := Fingerprint(t1); fp != got {
+		t.Errorf("Fingerprint changed with an omitted field: %x != %x", fp, got)
+	}
+
+	for _, change := range []func(*T1){
+		func(t *T1) { t.ID++ },
+		func(t *T1) { t.At = t.At.Add(time.Second) },
+		func(t *T1) { t.Labels = map[string]string{"a": "1"} },
+		func(t *T1) { t.Address = nil },
+		func(t *T1) { t.Address = &Address{"London"} },
+	} {
+		t1 := t1
+		change(&t1)
+		if got := Fingerprint(t1); fp == got {
+			t.Errorf("Fingerprint(%+v) should differ from %x", t1, fp)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	t.Parallel()
+
+	type (
+		T1 struct {
+			N  int8              `structof:"n"`
+			F  float32           `structof:"f"`
+			At time.Time         `structof:"at"`
+			M  map[string]string `structof:"m"`
+			S  []uint16          `structof:"s"`
+			P  *string           `structof:"p"`
+		}
+		T2 struct {
+			M  map[string]any `structof:"m"`
+			S  []uint         `structof:"s"`
+			P  string         `structof:"p"`
+			At time.Time      `structof:"at"`
+			F  float64        `structof:"f"`
+			N  int            `structof:"n"`
+		}
+	)
+
+	at := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	p := "foobar"
+	t1 := T1{23, 1.5, at, map[string]string{"a": "1", "b": "2"}, []uint16{1, 2}, &p}
+	t2 := T2{map[string]any{"b": "2", "a": "1"}, []uint{1, 2}, "foobar", at, 1.5, 23}
+
+	m := MakeCanonicalMap(t1)
+	want := map[string]any{
+		"n":  int64(23),
+		"f":  1.5,
+		"at": "2009-11-10T23:00:00Z",
+		"m":  map[string]any{"a": "1", "b": "2"},
+		"s":  []any{uint64(1), uint64(2)},
+		"p":  "foobar",
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	if m2 := MakeCanonicalMap(t2); !cmp.Equal(m, m2) {
+		t.Error(cmp.Diff(m, m2))
+	}
+
+	b := CanonicalBytes(t1)
+	for i := 0; i < 10; i++ {
+		if got := CanonicalBytes(t2); !bytes.Equal(b, got) {
+			t.Fatalf("CanonicalBytes differ:\n%q\n%q", b, got)
+		}
+	}
+}
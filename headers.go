@@ -0,0 +1,106 @@
+package structof
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// A Header is a key/value pair of message metadata,
+// as the headers of Kafka records.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// MakeByteHeaders converts the struct s to a map[string][]byte, as the
+// headers of AMQP messages and other message metadata, with the keys of
+// MakeMap. The values are serialized as follows:
+//
+//   - strings and byte slices as their bytes,
+//   - booleans and numbers as formatted by the strconv package,
+//   - values implementing encoding.TextMarshaler, such as time.Time, as the
+//     text they marshal to,
+//   - other values, such as nested structs, maps and slices, as their JSON
+//     encoding.
+//
+// The nil values are omitted. The tag options apply as for MakeMap, so that
+// the "stringer" option serializes an enum as its name, and the "json"
+// option a field as its JSON encoding.
+//
+// MakeByteHeaders panics in the same cases as MakeMap, and if a value
+// cannot be serialized.
+func MakeByteHeaders(s any) map[string][]byte {
+	return defaultEncoder.MakeByteHeaders(s)
+}
+
+// MakeHeaders is like MakeByteHeaders but returns the headers as a list
+// of key/value pairs, in the order of MakeSlice, as Kafka record headers.
+func MakeHeaders(s any) []Header {
+	return defaultEncoder.MakeHeaders(s)
+}
+
+// MakeByteHeaders is like the MakeByteHeaders function but encodes with enc's configuration.
+func (enc *Encoder) MakeByteHeaders(s any) map[string][]byte {
+	headers := enc.MakeHeaders(s)
+	m := make(map[string][]byte, len(headers))
+	for _, h := range headers {
+		m[h.Key] = h.Value
+	}
+	return m
+}
+
+// MakeHeaders is like the MakeHeaders function but encodes with enc's configuration.
+func (enc *Encoder) MakeHeaders(s any) []Header {
+	keys, values := enc.keysValues(s)
+	headers := make([]Header, 0, len(keys))
+	for i, key := range keys {
+		b, err := headerValue(values[i])
+		if err != nil {
+			panic(fmt.Errorf("structof: field %q: %w", key, err))
+		}
+		if b != nil {
+			headers = append(headers, Header{key, b})
+		}
+	}
+	return headers
+}
+
+// headerValue serializes the encoded value v as a header value,
+// nil if v is nil.
+func headerValue(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+	}
+
+	switch x := v.(type) {
+	case string:
+		return []byte(x), nil
+	case []byte:
+		return x, nil
+	case encoding.TextMarshaler:
+		return x.MarshalText()
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.AppendBool(nil, rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(nil, rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(nil, rv.Float(), 'g', -1, rv.Type().Bits()), nil
+	case reflect.String:
+		return []byte(rv.String()), nil
+	}
+	return json.Marshal(v)
+}
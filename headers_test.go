@@ -0,0 +1,58 @@
+package structof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMakeHeaders(t *testing.T) {
+	t.Parallel()
+
+	type Source struct {
+		Host string `json:"host"`
+	}
+	type Meta struct {
+		EventID  string        `structof:"event-id"`
+		Attempt  int           `structof:"attempt"`
+		Ratio    float32       `structof:"ratio"`
+		Replay   bool          `structof:"replay"`
+		At       time.Time     `structof:"at"`
+		Raw      []byte        `structof:"raw"`
+		Source   Source        `structof:"source"`
+		Level    stringerLevel `structof:"level,stringer"`
+		Doc      Source        `structof:"doc,json"`
+		Deadline *time.Time    `structof:"deadline"`
+		Tags     []string      `structof:"tags"`
+		Skipped  string        `structof:"-"`
+	}
+	at := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	m := Meta{
+		EventID: "e1", Attempt: 3, Ratio: 0.5, Replay: true, At: at,
+		Raw: []byte{1, 2}, Source: Source{"a"}, Level: 2, Doc: Source{"b"},
+	}
+
+	want := []Header{
+		{"event-id", []byte("e1")},
+		{"attempt", []byte("3")},
+		{"ratio", []byte("0.5")},
+		{"replay", []byte("true")},
+		{"at", []byte("2009-11-10T23:00:00Z")},
+		{"raw", []byte{1, 2}},
+		{"source", []byte(`{"Host":"a"}`)},
+		{"level", []byte("warn")},
+		{"doc", []byte(`{"host":"b"}`)},
+	}
+	if got := MakeHeaders(&m); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	wantMap := make(map[string][]byte, len(want))
+	for _, h := range want {
+		wantMap[h.Key] = h.Value
+	}
+	if got := MakeByteHeaders(m); !cmp.Equal(wantMap, got) {
+		t.Error(cmp.Diff(wantMap, got))
+	}
+}
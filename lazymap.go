@@ -0,0 +1,133 @@
+package structof
+
+import "reflect"
+
+// A LazyMap is a read-only view of a struct as the map MakeMap would return,
+// encoding the value of a key only when it is requested. Read-mostly
+// consumers looking up a few keys of a large struct avoid the cost of
+// encoding and copying all its fields.
+//
+// A LazyMap of a pointer to struct reflects the later changes to the struct.
+// Its keys and values are those of MakeMap, each field being encoded as
+// MakeMap encodes it, with all the options of its tag and of the Encoder.
+// The fields of inline structs, whose keys are only known once encoded,
+// are encoded together when one of their keys is requested. A key claimed
+// by several fields has the value of the last one present, as it
+// overwrites the others in the map of MakeMap.
+type LazyMap struct {
+	enc    *Encoder
+	v      reflect.Value
+	fields structFields
+
+	// Keys of the top-level fields, by index, as given by the tags and
+	// WithKeyFunc and WithKeyRenames; the inline fields have none.
+	keys []string
+}
+
+// MakeLazyMap returns a LazyMap of the struct s.
+// It panics if s is not a struct or pointer to struct.
+func MakeLazyMap(s any) LazyMap {
+	return defaultEncoder.MakeLazyMap(s)
+}
+
+// MakeLazyMap is like the MakeLazyMap function but encodes with enc's configuration.
+func (enc *Encoder) MakeLazyMap(s any) LazyMap {
+	v := reflect.ValueOf(s)
+	for reflect.Pointer == v.Kind() && !v.IsNil() {
+		v = v.Elem()
+	}
+	if reflect.Struct != v.Kind() {
+		panic("not struct or pointer to struct")
+	}
+	v, fields := enc.fieldsToEncode(v, cachedTypeFields(v.Type()))
+
+	keys := make([]string, len(fields.list))
+	e := &encodeState{enc: enc}
+	for i := range fields.list {
+		if f := &fields.list[i]; !f.inline {
+			keys[i] = enc.fieldKey(f)
+			if enc.keyRenames != nil {
+				keys[i] = e.renamedKey("", keys[i], f)
+			}
+		}
+	}
+	return LazyMap{enc: enc, v: v, fields: fields, keys: keys}
+}
+
+// Get returns the encoded value of key, and whether key is present.
+func (lm LazyMap) Get(key string) (any, bool) {
+	for i := len(lm.keys) - 1; i >= 0; i-- {
+		if lm.keys[i] != key && !lm.fields.list[i].inline {
+			continue
+		}
+		if value, ok := lastValue(lm.encode(i), key); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Range calls fn for each key and encoded value present, in the order of
+// MakeSlice. If fn returns false, Range stops the iteration.
+func (lm LazyMap) Range(fn func(key string, value any) bool) {
+	encoded := make([][]any, len(lm.keys))
+	pairs := func(i int) []any {
+		if encoded[i] == nil {
+			encoded[i] = lm.encode(i)
+		}
+		return encoded[i]
+	}
+
+	for i := range lm.keys {
+		p := pairs(i)
+	next:
+		for j := 0; j+1 < len(p); j += 2 {
+			key := p[j].(string)
+			if _, ok := lastValue(p[j+2:], key); ok {
+				continue
+			}
+			for k := i + 1; k < len(lm.keys); k++ {
+				if lm.keys[k] != key && !lm.fields.list[k].inline {
+					continue
+				}
+				if _, ok := lastValue(pairs(k), key); ok {
+					// Overwritten by a later field.
+					continue next
+				}
+			}
+			if !fn(key, p[j+1]) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of keys present, encoding the values as Range does,
+// as the options of the fields decide which keys are present.
+func (lm LazyMap) Len() int {
+	n := 0
+	lm.Range(func(string, any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// encode returns the key/value pairs the i-th field encodes into, as those
+// of MakeSlice: none if it is omitted, several if it is inline.
+func (lm LazyMap) encode(i int) []any {
+	e, put := newEncodeState(lm.enc, make([]any, 0, 2))
+	defer put()
+	e.encodeField(lm.v, &lm.fields.list[i], "", false, "", encOpts{})
+	return e.s
+}
+
+// lastValue returns the value of the last pair of key in the key/value pairs.
+func lastValue(pairs []any, key string) (any, bool) {
+	for j := len(pairs) - 2; j >= 0; j -= 2 {
+		if pairs[j] == key {
+			return pairs[j+1], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,221 @@
+package structof
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLazyMap(t *testing.T) {
+	t.Parallel()
+
+	type Meta struct {
+		Version int `structof:"version"`
+	}
+	type Inner struct {
+		A int `structof:"a"`
+	}
+	type S struct {
+		Name  string `structof:"name"`
+		Note  string `structof:"note,omitempty"`
+		Inner Inner  `structof:"inner"`
+		Any   any    `structof:"any"`
+		Meta  Meta   `structof:",inline"`
+	}
+
+	s := &S{Name: "foo", Inner: Inner{A: 1}, Meta: Meta{Version: 2}}
+	lm := MakeLazyMap(s)
+
+	if v, ok := lm.Get("inner"); !ok || !cmp.Equal(map[string]any{"a": 1}, v) {
+		t.Errorf(`Get("inner") = %v, %t`, v, ok)
+	}
+	if v, ok := lm.Get("version"); !ok || v != 2 {
+		t.Errorf(`Get("version") = %v, %t`, v, ok)
+	}
+	for _, key := range []string{"note", "any", "missing"} {
+		if v, ok := lm.Get(key); ok {
+			t.Errorf("Get(%q) = %v, want not present", key, v)
+		}
+	}
+
+	m := make(map[string]any)
+	lm.Range(func(key string, value any) bool {
+		m[key] = value
+		return true
+	})
+	if want := MakeMap(s); !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	if n := lm.Len(); n != len(m) {
+		t.Errorf("Len = %d, want %d", n, len(m))
+	}
+
+	var keys []string
+	lm.Range(func(key string, _ any) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	if want := []string{"name", "inner"}; !cmp.Equal(want, keys) {
+		t.Error(cmp.Diff(want, keys))
+	}
+
+	s.Note = "bar"
+	if v, ok := lm.Get("note"); !ok || v != "bar" {
+		t.Errorf(`Get("note") after change = %v, %t`, v, ok)
+	}
+
+	if v, _ := NewEncoder(WithJSONCompatible()).MakeLazyMap(s).Get("version"); v != 2.0 {
+		t.Errorf(`Get("version") with WithJSONCompatible = %#v, want 2.0`, v)
+	}
+}
+
+func TestLazyMapRecursiveInline(t *testing.T) {
+	t.Parallel()
+
+	type R struct {
+		V    int
+		Next *R `structof:",inline"`
+	}
+	r := R{V: 1}
+	want := MakeMap(r)
+	got := make(map[string]any)
+	MakeLazyMap(r).Range(func(key string, value any) bool {
+		got[key] = value
+		return true
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Range mismatch (-MakeMap +LazyMap):\n%s", diff)
+	}
+}
+
+func TestLazyMapInlineClash(t *testing.T) {
+	t.Parallel()
+
+	type A struct{ X, Y int }
+	type B struct{ X int }
+	type T struct {
+		A A `structof:",inline"`
+		B B `structof:",inline"`
+	}
+	s := T{A{1, 3}, B{2}}
+	want := MakeMap(s)
+
+	lm := MakeLazyMap(s)
+	got := make(map[string]any)
+	lm.Range(func(key string, value any) bool {
+		if _, dup := got[key]; dup {
+			t.Errorf("Range yields %q twice", key)
+		}
+		got[key] = value
+		return true
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Range mismatch (-MakeMap +LazyMap):\n%s", diff)
+	}
+	if x, _ := lm.Get("X"); x != want["X"] {
+		t.Errorf("Get(%q) = %v, want %v", "X", x, want["X"])
+	}
+	if n := lm.Len(); n != len(want) {
+		t.Errorf("Len = %d, want %d", n, len(want))
+	}
+}
+
+// checkLazyMap reports the differences between the LazyMap of s and the map
+// MakeMap returns, through Range, Get and Len.
+func checkLazyMap(t *testing.T, enc *Encoder, s any) {
+	t.Helper()
+
+	want := enc.MakeMap(s)
+	lm := enc.MakeLazyMap(s)
+	got := make(map[string]any)
+	lm.Range(func(key string, value any) bool {
+		if _, dup := got[key]; dup {
+			t.Errorf("Range yields %q twice", key)
+		}
+		got[key] = value
+		return true
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Range mismatch (-MakeMap +LazyMap):\n%s", diff)
+	}
+	for key, value := range want {
+		if v, ok := lm.Get(key); !ok || !cmp.Equal(value, v) {
+			t.Errorf("Get(%q) = %v, %t, want %v", key, v, ok, value)
+		}
+	}
+	if n := lm.Len(); n != len(want) {
+		t.Errorf("Len = %d, want %d", n, len(want))
+	}
+}
+
+type lazyLevel int
+
+func (l lazyLevel) String() string { return [...]string{"low", "high"}[l] }
+
+func TestLazyMapParity(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		ID   string `structof:"id"`
+		Name string `structof:"name"`
+	}
+	type Address struct {
+		City string `structof:"city"`
+		Zip  string `structof:"zip"`
+	}
+	type S struct {
+		Level   lazyLevel         `structof:"level,stringer"`
+		Raw     map[string]int    `structof:"raw,json"`
+		Items   []Item            `structof:"items,indexmap,keyby=id"`
+		List    []string          `structof:"list,indexmap"`
+		Email   string            `structof:"email,trim,lowercase"`
+		Ratio   *big.Rat          `structof:"ratio,precision=2"`
+		Count   int               `structof:"count,string"`
+		Address Address           `structof:"address"`
+		Inline  Address           `structof:",inline"`
+		Note    string            `structof:"note,omitempty"`
+		Any     any               `structof:"any"`
+		Tags    map[string]string `structof:"tags"`
+		secret  string
+	}
+	s := &S{
+		Level:   1,
+		Raw:     map[string]int{"a": 1},
+		Items:   []Item{{"x", "X"}, {"y", "Y"}},
+		List:    []string{"a", "b"},
+		Email:   " Foo@Example.com ",
+		Ratio:   big.NewRat(1, 3),
+		Count:   3,
+		Address: Address{"Paris", "75001"},
+		Inline:  Address{"Rome", "00100"},
+		secret:  "s",
+	}
+
+	for name, enc := range map[string]*Encoder{
+		"default":       NewEncoder(),
+		"renames":       NewEncoder(WithKeyRenames(map[string]string{"city": "town", "address.zip": "postcode", "level": "lvl"})),
+		"fields":        NewEncoder(WithFields("Address.City", "Items", "Inline.Zip")),
+		"withoutFields": NewEncoder(WithoutFields("Address.Zip", "Email")),
+		"tagless":       NewEncoder(WithTagless()),
+		"unexported":    NewEncoder(WithUnexported()),
+		"roundTrip":     NewEncoder(WithRoundTrip()),
+		"complete":      NewEncoder(WithComplete()),
+		"omitNils":      NewEncoder(WithOmitNils()),
+		"valueFunc": NewEncoder(WithValueFunc(func(path string, v any) (any, bool) {
+			return v, path != "address.city"
+		})),
+		"keyFunc": NewEncoder(WithKeyFunc(func(sf reflect.StructField, name string) string {
+			if sf.Name == "Level" || sf.Name == "City" {
+				return "k_" + name
+			}
+			return name
+		})),
+	} {
+		t.Run(name, func(t *testing.T) {
+			checkLazyMap(t, enc, s)
+			checkLazyMap(t, enc, *s)
+		})
+	}
+}
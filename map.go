@@ -0,0 +1,73 @@
+package structof
+
+import "sort"
+
+// Map encapsulates a map[string]any, such as a decoded document or the result
+// of MakeMap, to provide high level functions around it similar to Struct's.
+type Map struct {
+	m map[string]any
+}
+
+// MapOf returns a Map with the map m. Changes through the Map are visible in m.
+// If m is nil, MapOf allocates a new map.
+func MapOf(m map[string]any) Map {
+	if m == nil {
+		m = make(map[string]any)
+	}
+	return Map{m: m}
+}
+
+// Map returns the underlying map.
+func (m Map) Map() map[string]any {
+	return m.m
+}
+
+// Len returns the number of keys of m.
+func (m Map) Len() int {
+	return len(m.m)
+}
+
+// Keys returns the sorted keys of m.
+func (m Map) Keys() []string {
+	keys := make([]string, 0, len(m.m))
+	for k := range m.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the element of m with key
+// and a boolean indicating if the key was found.
+func (m Map) Get(key string) (any, bool) {
+	v, ok := m.m[key]
+	return v, ok
+}
+
+// Set sets the element of m with key to v.
+func (m Map) Set(key string, v any) {
+	m.m[key] = v
+}
+
+// Delete deletes the element of m with key.
+func (m Map) Delete(key string) {
+	delete(m.m, key)
+}
+
+// FieldByPath returns the value found by following path from m.
+// See GetPath function's documentation for the path syntax.
+func (m Map) FieldByPath(path string) (any, error) {
+	return GetPath(m.m, path)
+}
+
+// SetPath sets the value found by following path from m to v.
+// See SetPath function's documentation for more information.
+func (m Map) SetPath(path string, v any) error {
+	return SetPath(&m.m, path, v)
+}
+
+// FillStruct fills the struct pointed to by s with the elements of m.
+// See FillStruct function's documentation for more information.
+func (m Map) FillStruct(s any) error {
+	return FillStruct(m.m, s)
+}
@@ -0,0 +1,59 @@
+package structof
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			City string `structof:"city"`
+		}
+		User struct {
+			Name    string   `structof:"name"`
+			Address *Address `structof:"address"`
+			Tags    []string `structof:"tags"`
+		}
+	)
+
+	m := MapOf(MakeMap(User{"foobar", &Address{"Paris"}, []string{"a", "b"}}))
+
+	keys := m.Keys()
+	wantKeys := []string{"address", "name", "tags"}
+	if !cmp.Equal(wantKeys, keys) {
+		t.Error(cmp.Diff(wantKeys, keys))
+	}
+
+	if v, ok := m.Get("name"); !ok || v != "foobar" {
+		t.Errorf("Get(name) = %v, %t", v, ok)
+	}
+	if v, err := m.FieldByPath("address.city"); err != nil || v != "Paris" {
+		t.Errorf("FieldByPath(address.city) = %v, %v", v, err)
+	}
+	if v, err := m.FieldByPath("tags[1]"); err != nil || v != "b" {
+		t.Errorf("FieldByPath(tags[1]) = %v, %v", v, err)
+	}
+
+	m.Set("name", "baz")
+	if err := m.SetPath("address.city", "London"); err != nil {
+		t.Fatal(err)
+	}
+	m.Delete("tags")
+
+	var u User
+	if err := m.FillStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	want := User{Name: "baz", Address: &Address{"London"}}
+	if !cmp.Equal(want, u) {
+		t.Error(cmp.Diff(want, u))
+	}
+
+	if m := MapOf(nil); m.Len() != 0 || m.Map() == nil {
+		t.Errorf("MapOf(nil) = %#v", m)
+	}
+}
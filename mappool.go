@@ -0,0 +1,84 @@
+package structof
+
+import "sync"
+
+// A MapPool encodes structs into maps recycled from the maps released to it,
+// for services encoding so many structs that allocating the maps of their
+// nested structs dominates. The maps returned by MakeMap, including the maps
+// of nested structs and of map fields, are taken from the pool, and are
+// given back by Release once the caller is done with them.
+//
+// A MapPool is safe for concurrent use by multiple goroutines.
+type MapPool struct {
+	enc Encoder
+}
+
+// NewMapPool returns a new MapPool encoding the structs with enc's
+// configuration, or with the configuration of the package functions
+// if enc is nil.
+//
+// It panics if enc is configured by WithRoundTrip or WithValueFunc, which
+// store values of the caller as they are, as the map[string]any among them
+// would be cleared by Release with the maps of the pool.
+func NewMapPool(enc *Encoder) *MapPool {
+	if enc == nil {
+		enc = defaultEncoder
+	}
+	if enc.roundTrip || enc.valueFunc != nil {
+		panic("structof: NewMapPool with WithRoundTrip or WithValueFunc")
+	}
+	p := &MapPool{enc: *enc}
+	p.enc.maps = new(sync.Pool)
+	return p
+}
+
+// MakeMap is like the MakeMap function, but the maps it returns are taken
+// from the pool.
+func (p *MapPool) MakeMap(s any) map[string]any {
+	m := p.enc.newMap(0)
+	p.enc.FillMap(s, &m)
+	return m
+}
+
+// Release clears m and gives it back to the pool, along with the maps
+// held by it at any depth, in its values and in the slices of its values.
+// Neither m nor any of these maps may be used after Release.
+// Only the maps returned by MakeMap may be released, since all the
+// map[string]any values they hold are released with them. These are all
+// built by the encoding: the maps returned by transformers are copied.
+func (p *MapPool) Release(m map[string]any) {
+	for _, v := range m {
+		p.release(v)
+	}
+	p.enc.putMap(m)
+}
+
+func (p *MapPool) release(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		p.Release(v)
+	case []any:
+		for _, e := range v {
+			p.release(e)
+		}
+	}
+}
+
+// newMap returns an empty map, taken from the pool of enc if it has one.
+func (enc *Encoder) newMap(size int) map[string]any {
+	if enc.maps != nil {
+		if m, ok := enc.maps.Get().(map[string]any); ok {
+			return m
+		}
+	}
+	return make(map[string]any, size)
+}
+
+// putMap clears m and gives it back to the pool of enc, if it has one.
+func (enc *Encoder) putMap(m map[string]any) {
+	if enc.maps == nil || m == nil {
+		return
+	}
+	clear(m)
+	enc.maps.Put(m)
+}
@@ -0,0 +1,114 @@
+package structof
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMapPool(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		A int `structof:"a"`
+	}
+	type S struct {
+		Name   string           `structof:"name"`
+		Inner  Inner            `structof:"inner"`
+		Items  []Inner          `structof:"items"`
+		Labels map[string]Inner `structof:"labels"`
+		Counts map[string]int   `structof:"counts"`
+	}
+	s := &S{
+		Name:   "foo",
+		Inner:  Inner{A: 1},
+		Items:  []Inner{{A: 2}, {A: 3}},
+		Labels: map[string]Inner{"x": {A: 4}},
+		Counts: map[string]int{"y": 5},
+	}
+
+	p := NewMapPool(nil)
+	for i := 0; i < 3; i++ {
+		m := p.MakeMap(s)
+		if want := MakeMap(s); !cmp.Equal(want, m) {
+			t.Fatal(cmp.Diff(want, m))
+		}
+		p.Release(m)
+	}
+
+	m := NewMapPool(NewEncoder(WithWidenNumbers())).MakeMap(s)
+	if v := m["inner"].(map[string]any)["a"]; v != int64(1) {
+		t.Errorf("inner.a = %#v, want int64(1)", v)
+	}
+}
+
+var mapPoolMeta = map[string]any{"owner": "admin"}
+
+func init() {
+	RegisterTransformer("test_meta", func(any) (any, error) { return mapPoolMeta, nil })
+}
+
+func TestMapPoolReleaseUserMaps(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Meta int `structof:"meta,test_meta"`
+	}
+	p := NewMapPool(nil)
+	m := p.MakeMap(S{})
+	if diff := cmp.Diff(map[string]any{"meta": map[string]any{"owner": "admin"}}, m); diff != "" {
+		t.Fatalf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+	p.Release(m)
+	if len(mapPoolMeta) != 1 {
+		t.Errorf("Release cleared the map returned by a transformer: %v", mapPoolMeta)
+	}
+
+	for _, opt := range []EncoderOption{WithRoundTrip(), WithValueFunc(func(_ string, v any) (any, bool) { return v, true })} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("NewMapPool storing the values of the caller should panic")
+				}
+			}()
+			NewMapPool(NewEncoder(opt))
+		}()
+	}
+}
+
+func BenchmarkMapPool(b *testing.B) {
+	type Inner struct {
+		A int    `structof:"a"`
+		B string `structof:"b"`
+	}
+	type S struct {
+		Name   string           `structof:"name"`
+		Inner  Inner            `structof:"inner"`
+		Items  []Inner          `structof:"items"`
+		Labels map[string]Inner `structof:"labels"`
+	}
+	s := &S{
+		Name:   "foo",
+		Inner:  Inner{1, "x"},
+		Items:  []Inner{{2, "y"}, {3, "z"}},
+		Labels: map[string]Inner{"k": {4, "w"}},
+	}
+
+	b.Run("MakeMap", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				MakeMap(s)
+			}
+		})
+	})
+	b.Run("MapPool", func(b *testing.B) {
+		p := NewMapPool(nil)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				p.Release(p.MakeMap(s))
+			}
+		})
+	})
+}
@@ -0,0 +1,83 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// anyEncoder is the Encoder used by MarshalAny.
+var anyEncoder = func() *Encoder {
+	enc := NewEncoder(WithFuncNames(), WithCyclePolicy(CycleRef))
+	enc.fallbacks = true
+	return enc
+}()
+
+// MarshalAny encodes any value i as FillMap encodes the values of fields,
+// structs and pointers to structs into map[string]any, and returns it.
+// Unlike MakeMap, it never panics, whatever i is, so that it is suitable
+// for logging arbitrary values provided by users.
+//
+// The values FillMap cannot encode have fallbacks instead:
+//
+//   - functions encode as their names, as with WithFuncNames;
+//   - cycles encode as references, as with CycleRef;
+//   - complex numbers encode as strings, such as "(1+2i)";
+//   - maps with keys of other kinds than string encode as map[string]any,
+//     the keys formatted by fmt.Sprint;
+//   - channels and unsafe pointers encode as the name of their type.
+//
+// A nil i encodes as nil, and the structs without exported fields, such as
+// time.Time, as themselves. The errors of the encoding, such as those of a
+// MarshalText method, and the panics of the methods of i, are returned.
+func MarshalAny(i any) (x any, err error) {
+	v := reflect.ValueOf(i)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if anyEncoder.isOpaqueStruct(v.Type()) && !isBigType(indirectType(v.Type())) {
+		return i, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			x = nil
+			if se, ok := r.(structofError); ok {
+				err = se.error
+			} else {
+				err = fmt.Errorf("structof: marshal %T: panic: %v", i, r)
+			}
+		}
+	}()
+
+	m := make(map[string]any)
+	e, put := newEncodeState(anyEncoder, m)
+	defer put()
+	e.reflectValue(v, encOpts{})
+	// The structs encode into m itself, whose keys are never empty, and
+	// the other values under the empty key.
+	if x, ok := m[""]; ok {
+		return x, nil
+	}
+	return m, nil
+}
+
+// fallbackEncoder encodes the value v of an unsupported type for MarshalAny.
+func fallbackEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
+	switch v.Kind() {
+	case reflect.Complex64, reflect.Complex128:
+		e.setLeaf(key, strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()))
+	case reflect.Map:
+		if v.IsNil() {
+			e.setNil(key, v)
+			return
+		}
+		m := reflect.MakeMapWithSize(mapType, v.Len())
+		for mi := v.MapRange(); mi.Next(); {
+			m.SetMapIndex(reflect.ValueOf(fmt.Sprint(mi.Key())), mi.Value())
+		}
+		typeEncoder(mapType)(e, key, m, opts)
+	default:
+		e.setLeaf(key, v.Type().String())
+	}
+}
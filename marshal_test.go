@@ -0,0 +1,210 @@
+package structof
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type panicExporter struct{}
+
+func (panicExporter) ExportSlice() []any { panic("boom") }
+
+type failingText []byte
+
+func (failingText) MarshalText() ([]byte, error) { return nil, errors.New("cannot marshal") }
+
+func TestMarshalAny(t *testing.T) {
+	t.Parallel()
+
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	type Event struct {
+		Name    string
+		Handler func()
+		Done    chan struct{}
+		Phase   complex128
+		Counts  map[int]string
+		Ptr     unsafe.Pointer
+		At      time.Time
+		Options map[string]any
+	}
+
+	at := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	loop := &Node{Name: "a"}
+	loop.Next = &Node{Name: "b", Next: loop}
+
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{"nil", nil, nil},
+		{"int", 42, 42},
+		{"string", "hi", "hi"},
+		{"complex", 1 + 2i, "(1+2i)"},
+		{"slice", []int{1, 2}, []int{1, 2}},
+		{"struct slice", []Node{{Name: "a"}}, []any{map[string]any{"Name": "a", "Next": (*Node)(nil)}}},
+		{"int keys", map[int]Node{1: {Name: "a"}}, map[string]any{"1": map[string]any{"Name": "a", "Next": (*Node)(nil)}}},
+		{"chan", make(chan int), "chan int"},
+		{"time", at, at},
+		{"big", big.NewInt(7), "7"},
+		{"nil pointer", (*Node)(nil), (*Node)(nil)},
+		{"cycle", loop, map[string]any{
+			"Name": "a",
+			"Next": map[string]any{"Name": "b", "Next": map[string]any{"$ref": ""}},
+		}},
+		{"struct", Event{
+			Name:    "start",
+			Handler: (func())(nil),
+			Done:    make(chan struct{}),
+			Phase:   2i,
+			Counts:  map[int]string{3: "c"},
+			At:      at,
+			Options: map[string]any{"phase": 1i},
+		}, map[string]any{
+			"Name":    "start",
+			"Handler": (func())(nil),
+			"Done":    "chan struct {}",
+			"Phase":   "(0+2i)",
+			"Counts":  map[string]any{"3": "c"},
+			"Ptr":     "unsafe.Pointer",
+			"At":      at,
+			"Options": map[string]any{"phase": "(0+1i)"},
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := MarshalAny(tt.in)
+			if err != nil {
+				t.Fatalf("MarshalAny error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.Comparer(func(x, y func()) bool { return x == nil && y == nil })); diff != "" {
+				t.Errorf("MarshalAny mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarshalAnyErrors(t *testing.T) {
+	t.Parallel()
+
+	type Labels struct {
+		Text failingText
+	}
+	type Queue struct {
+		Jobs panicExporter
+	}
+	if x, err := MarshalAny(Labels{Text: failingText("x")}); err == nil {
+		t.Errorf("MarshalAny = %v, want error", x)
+	}
+	if x, err := MarshalAny(Queue{}); err == nil {
+		t.Errorf("MarshalAny = %v, want error", x)
+	}
+}
+
+// fuzzValue builds from data a value nesting values of arbitrary kinds,
+// unsupported ones included, up to depth levels deep.
+type fuzzValue struct {
+	data []byte
+}
+
+func (fv *fuzzValue) byte() byte {
+	if len(fv.data) == 0 {
+		return 0
+	}
+	b := fv.data[0]
+	fv.data = fv.data[1:]
+	return b
+}
+
+type fuzzStruct struct {
+	A any
+	B *fuzzStruct `structof:",inline"`
+	C []any       `structof:"c,omitempty"`
+	D map[int]any `structof:"d,indexmap"`
+	E any         `structof:"e,string"`
+	f any
+}
+
+func (fv *fuzzValue) value(depth int) any {
+	b := fv.byte()
+	if depth <= 0 {
+		b %= 12
+	}
+	switch b % 20 {
+	case 0:
+		return nil
+	case 1:
+		return int(b)
+	case 2:
+		return string(fv.data)
+	case 3:
+		return math.NaN()
+	case 4:
+		return complex(float64(b), 1)
+	case 5:
+		return make(chan int, 1)
+	case 6:
+		return func() {}
+	case 7:
+		return unsafe.Pointer(&b)
+	case 8:
+		return time.Unix(int64(b), 0)
+	case 9:
+		return big.NewFloat(float64(b))
+	case 10:
+		return failingText("x")
+	case 11:
+		return struct{}{}
+	case 12:
+		return []any{fv.value(depth - 1), fv.value(depth - 1)}
+	case 13:
+		return map[string]any{"a": fv.value(depth - 1), "b": fv.value(depth - 1)}
+	case 14:
+		return map[int]any{1: fv.value(depth - 1)}
+	case 15:
+		x := fv.value(depth - 1)
+		return &x
+	case 16:
+		s := &fuzzStruct{A: fv.value(depth - 1), E: fv.value(depth - 1), f: 1}
+		if fv.byte()%2 == 0 {
+			s.B = s
+		}
+		return s
+	case 17:
+		return fuzzStruct{C: []any{fv.value(depth - 1)}, D: map[int]any{2: fv.value(depth - 1)}}
+	case 18:
+		s := []any{nil}
+		s[0] = s
+		return s
+	default:
+		return [2]any{fv.value(depth - 1), panicExporter{}}
+	}
+}
+
+func FuzzMarshalAny(f *testing.F) {
+	for _, seed := range []string{"", "\x00", "\x10\x01\x02", "\x0c\x0d\x0e\x0f", "\x10\x00\x11\x12\x13"} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fv := &fuzzValue{data}
+		v := fv.value(4)
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("MarshalAny(%T) panicked: %v", v, r)
+			}
+		}()
+		MarshalAny(v)
+	})
+}
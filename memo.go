@@ -0,0 +1,81 @@
+package structof
+
+import (
+	"reflect"
+	"sync"
+)
+
+// A MemoCache memoizes the maps encoded from structs that do not change
+// once built, such as a static configuration encoded on every health check.
+// Maps are cached by the identity of the pointer to the struct, not by its
+// content: after modifying a struct whose map is cached, call Invalidate.
+//
+// The maps returned are shared by all the callers, and must not be modified.
+// The cache keeps the structs it holds maps for reachable until they are
+// invalidated.
+//
+// A MemoCache is safe for concurrent use by multiple goroutines.
+type MemoCache struct {
+	enc *Encoder
+
+	mu sync.RWMutex
+	m  map[any]map[string]any
+}
+
+// NewMemoCache returns a new MemoCache encoding the structs with enc,
+// or with the configuration of the package functions if enc is nil.
+func NewMemoCache(enc *Encoder) *MemoCache {
+	if enc == nil {
+		enc = defaultEncoder
+	}
+	return &MemoCache{enc: enc, m: make(map[any]map[string]any)}
+}
+
+// MakeMap returns the map encoded from the struct pointed to by p, encoding
+// it as by MakeMap only if no map is cached for p.
+// It panics if p is not a non-nil pointer to struct.
+func (c *MemoCache) MakeMap(p any) map[string]any {
+	v := reflect.ValueOf(p)
+	if reflect.Pointer != v.Kind() || v.IsNil() || reflect.Struct != v.Type().Elem().Kind() {
+		panic("not non-nil pointer to struct")
+	}
+
+	c.mu.RLock()
+	m, ok := c.m[p]
+	c.mu.RUnlock()
+	if ok {
+		return m
+	}
+
+	m = c.enc.MakeMap(p)
+	c.mu.Lock()
+	if cached, ok := c.m[p]; ok {
+		m = cached
+	} else {
+		c.m[p] = m
+	}
+	c.mu.Unlock()
+	return m
+}
+
+// Invalidate removes the map cached for p, if any,
+// so that the next MakeMap encodes the struct again.
+func (c *MemoCache) Invalidate(p any) {
+	c.mu.Lock()
+	delete(c.m, p)
+	c.mu.Unlock()
+}
+
+// Reset removes all the cached maps.
+func (c *MemoCache) Reset() {
+	c.mu.Lock()
+	clear(c.m)
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached maps.
+func (c *MemoCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.m)
+}
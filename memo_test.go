@@ -0,0 +1,57 @@
+package structof
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoCache(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Name string `structof:"name"`
+	}
+
+	c := NewMemoCache(nil)
+	cfg := &Config{Name: "foo"}
+	m1 := c.MakeMap(cfg)
+	if want := map[string]any{"name": "foo"}; !cmp.Equal(want, m1) {
+		t.Error(cmp.Diff(want, m1))
+	}
+
+	cfg.Name = "bar"
+	if m := c.MakeMap(cfg); m["name"] != "foo" {
+		t.Errorf("MakeMap after change = %v, want cached map", m)
+	}
+	if n := c.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+
+	c.Invalidate(cfg)
+	if m := c.MakeMap(cfg); m["name"] != "bar" {
+		t.Errorf("MakeMap after Invalidate = %v, want re-encoded map", m)
+	}
+
+	other := &Config{Name: "bar"}
+	c.MakeMap(other)
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+	c.Reset()
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len after Reset = %d, want 0", n)
+	}
+
+	m := NewMemoCache(NewEncoder(WithWidenNumbers())).MakeMap(&struct{ N int8 }{1})
+	if want := map[string]any{"N": int64(1)}; !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MakeMap of non-pointer: should panic")
+		}
+	}()
+	c.MakeMap(Config{})
+}
@@ -0,0 +1,232 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GetPath returns the value found by following path from i.
+//
+// The path is a sequence of steps separated by dots, each optionally followed
+// by bracketed indexes, as in "Users[admin].Addresses[2].City".
+// A step selects an exported struct field by its Go name, a map element by
+// its key, or a slice or array element by its index; bracketed and dotted
+// steps are interchangeable, so "Items.2" is the same as "Items[2]".
+// Pointers and interfaces are followed transparently.
+//
+// GetPath returns an error if a step does not resolve, including when a nil
+// pointer, map or interface is encountered before the end of the path.
+func GetPath(i any, path string) (any, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(i)
+	for n, step := range steps {
+		v = indirect(v)
+		if !v.IsValid() {
+			return nil, fmt.Errorf("structof: path %q: nil value at %q", path, joinPath(steps[:n]))
+		}
+		if v, err = pathStep(v, step); err != nil {
+			return nil, fmt.Errorf("structof: path %q: %w", path, err)
+		}
+	}
+	return v.Interface(), nil
+}
+
+// SetPath sets the value found by following path from i to value.
+// See GetPath for the path syntax.
+//
+// The i must be a non-nil pointer so that the value can be modified.
+// Nil pointers and maps along the path are allocated, and map elements are
+// written back after their contents are modified. The value must be
+// assignable, or convertible without changing kind, to the destination type;
+// a nil value sets the destination to its zero value.
+func SetPath(i any, path string, value any) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(i)
+	if reflect.Pointer != v.Kind() || v.IsNil() {
+		return fmt.Errorf("structof: SetPath of non-pointer %T", i)
+	}
+	if err := setPath(v.Elem(), steps, value); err != nil {
+		return fmt.Errorf("structof: path %q: %w", path, err)
+	}
+	return nil
+}
+
+func setPath(v reflect.Value, steps []string, value any) error {
+	for reflect.Pointer == v.Kind() || reflect.Interface == v.Kind() {
+		if v.IsNil() {
+			if reflect.Interface == v.Kind() {
+				return fmt.Errorf("nil interface before %q", steps[0])
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if reflect.Map == v.Kind() {
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key, err := mapKey(v.Type().Key(), steps[0])
+		if err != nil {
+			return err
+		}
+
+		// Map elements are not addressable; modify a copy and store it back.
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if e := v.MapIndex(key); e.IsValid() {
+			elem.Set(e)
+		}
+		if len(steps) == 1 {
+			if err := assignValue(elem, value); err != nil {
+				return err
+			}
+		} else if err := setPath(elem, steps[1:], value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+
+	fv, err := pathStep(v, steps[0])
+	if err != nil {
+		return err
+	}
+	if len(steps) == 1 {
+		return assignValue(fv, value)
+	}
+	return setPath(fv, steps[1:], value)
+}
+
+// pathStep returns the field, element or map element of v selected by step.
+func pathStep(v reflect.Value, step string) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		sf, ok := v.Type().FieldByName(step)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("field %q not found", step)
+		}
+		if !sf.IsExported() {
+			return reflect.Value{}, fmt.Errorf("field %q not exported", step)
+		}
+		fv, err := v.FieldByIndexErr(sf.Index)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %q: %w", step, err)
+		}
+		return fv, nil
+	case reflect.Map:
+		key, err := mapKey(v.Type().Key(), step)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		e := v.MapIndex(key)
+		if !e.IsValid() {
+			return reflect.Value{}, fmt.Errorf("key %q not found", step)
+		}
+		return e, nil
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(step)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid index %q", step)
+		}
+		if i < 0 || i >= v.Len() {
+			return reflect.Value{}, fmt.Errorf("index %d out of range [0:%d]", i, v.Len())
+		}
+		return v.Index(i), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot step into %s with %q", v.Type(), step)
+	}
+}
+
+// mapKey converts step into a key of type t.
+func mapKey(t reflect.Type, step string) (reflect.Value, error) {
+	key := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		key.SetString(step)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(step, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid key %q for %s", step, t)
+		}
+		key.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(step, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid key %q for %s", step, t)
+		}
+		key.SetUint(n)
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported map key type %s", t)
+	}
+	return key, nil
+}
+
+// assignValue assigns i to dst the way Field.Set does,
+// returning an error instead of panicking.
+func assignValue(dst reflect.Value, i any) error {
+	if !dst.CanSet() {
+		return fmt.Errorf("cannot set value of type %s", dst.Type())
+	}
+	if i == nil {
+		dst.SetZero()
+		return nil
+	}
+	v := reflect.ValueOf(i)
+	switch {
+	case v.Type().AssignableTo(dst.Type()):
+		dst.Set(v)
+	case v.Kind() == dst.Kind() && v.Type().ConvertibleTo(dst.Type()):
+		dst.Set(v.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("cannot assign %s to %s", v.Type(), dst.Type())
+	}
+	return nil
+}
+
+// indirect follows pointers and interfaces from v,
+// returning the zero Value if it meets a nil one.
+func indirect(v reflect.Value) reflect.Value {
+	for reflect.Pointer == v.Kind() || reflect.Interface == v.Kind() {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// parsePath splits path into its steps.
+func parsePath(path string) ([]string, error) {
+	var steps []string
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name != "" {
+			steps = append(steps, name)
+		} else if rest == "" {
+			return nil, fmt.Errorf("structof: invalid path %q", path)
+		}
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			if !ok || index == "" || after != "" && after[0] != '[' {
+				return nil, fmt.Errorf("structof: invalid path %q", path)
+			}
+			steps = append(steps, index)
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return steps, nil
+}
+
+func joinPath(steps []string) string {
+	return strings.Join(steps, ".")
+}
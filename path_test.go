@@ -0,0 +1,116 @@
+package structof
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetPath(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			City string
+		}
+		User struct {
+			Name      string
+			Addresses []*Address
+			Meta      map[string]any
+		}
+		Group struct {
+			Users map[string]User
+		}
+	)
+
+	g := Group{Users: map[string]User{
+		"admin": {
+			Name:      "foobar",
+			Addresses: []*Address{{"Paris"}, {"London"}},
+			Meta:      map[string]any{"tags": []string{"a", "b"}},
+		},
+	}}
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"Users[admin].Name", "foobar"},
+		{"Users.admin.Addresses[1].City", "London"},
+		{"Users[admin].Addresses.0.City", "Paris"},
+		{"Users[admin].Meta[tags][1]", "b"},
+	}
+	for _, tt := range tests {
+		got, err := GetPath(&g, tt.path)
+		if err != nil {
+			t.Errorf("GetPath(%q): %v", tt.path, err)
+			continue
+		}
+		if !cmp.Equal(tt.want, got) {
+			t.Errorf("GetPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{
+		"",
+		"Users[admin",
+		"Users[root].Name",
+		"Users[admin].Addresses[2]",
+		"Users[admin].Age",
+		"Users[admin].Name.X",
+	} {
+		if _, err := GetPath(g, path); err == nil {
+			t.Errorf("GetPath(%q) should return error", path)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			City string
+		}
+		User struct {
+			Name    string
+			Age     int64
+			Address *Address
+		}
+		Group struct {
+			Users map[string]User
+			IDs   []int
+		}
+	)
+
+	var g Group
+	g.IDs = make([]int, 2)
+	for _, tt := range []struct {
+		path  string
+		value any
+	}{
+		{"Users[admin].Name", "foobar"},
+		{"Users[admin].Age", int64(23)},
+		{"Users[admin].Address.City", "Paris"},
+		{"IDs[1]", 46},
+	} {
+		if err := SetPath(&g, tt.path, tt.value); err != nil {
+			t.Errorf("SetPath(%q): %v", tt.path, err)
+		}
+	}
+
+	want := Group{
+		Users: map[string]User{"admin": {"foobar", 23, &Address{"Paris"}}},
+		IDs:   []int{0, 46},
+	}
+	if !cmp.Equal(want, g) {
+		t.Error(cmp.Diff(want, g))
+	}
+
+	if err := SetPath(&g, "Users[admin].Name", 23); err == nil {
+		t.Error("SetPath with a mismatched type should return error")
+	}
+	if err := SetPath(g, "IDs[0]", 1); err == nil {
+		t.Error("SetPath on a non-pointer should return error")
+	}
+}
@@ -0,0 +1,96 @@
+package structof
+
+import (
+	"reflect"
+	"time"
+)
+
+// A protoKind tells the well-known protobuf message types handled specially.
+type protoKind int
+
+const (
+	protoNone      protoKind = iota
+	protoWrapper             // wrapperspb.StringValue, Int64Value, ...
+	protoTimestamp           // timestamppb.Timestamp
+)
+
+var protoKinds typeCache[protoKind]
+
+// protoKindOf returns the protoKind of the struct type t. The types are
+// recognized by the methods and fields of the generated code, so that the
+// package does not depend on the protobuf module: the messages have a
+// ProtoReflect method, the wrappers a GetValue method and a Value field, and
+// the timestamps an AsTime method and Seconds and Nanos fields.
+func protoKindOf(t reflect.Type) protoKind {
+	if k, ok := protoKinds.Load(t); ok {
+		return k
+	}
+	k, _ := protoKinds.LoadOrStore(t, newProtoKind(t))
+	return k
+}
+
+func newProtoKind(t reflect.Type) protoKind {
+	if reflect.Struct != t.Kind() {
+		return protoNone
+	}
+	pt := reflect.PointerTo(t)
+	if m, ok := pt.MethodByName("ProtoReflect"); !ok || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+		return protoNone
+	}
+	if m, ok := pt.MethodByName("GetValue"); ok && m.Type.NumIn() == 1 && m.Type.NumOut() == 1 {
+		if f, ok := t.FieldByName("Value"); ok && f.IsExported() && f.Type == m.Type.Out(0) {
+			return protoWrapper
+		}
+	}
+	if m, ok := pt.MethodByName("AsTime"); ok && m.Type.NumIn() == 1 && m.Type.NumOut() == 1 && timeType == m.Type.Out(0) {
+		s, sok := t.FieldByName("Seconds")
+		n, nok := t.FieldByName("Nanos")
+		if sok && nok && reflect.Int64 == s.Type.Kind() && reflect.Int32 == n.Type.Kind() {
+			return protoTimestamp
+		}
+	}
+	return protoNone
+}
+
+// newProtoEncoder returns the encoder of the protobuf message type t
+// handled specially, or nil.
+func newProtoEncoder(t reflect.Type) encoderFunc {
+	switch protoKindOf(t) {
+	case protoWrapper:
+		f, _ := t.FieldByName("Value")
+		valueEncoder := typeEncoder(f.Type)
+		return func(e *encodeState, key string, v reflect.Value, opts encOpts) {
+			valueEncoder(e, key, v.FieldByIndex(f.Index), opts)
+		}
+	case protoTimestamp:
+		timeEncoder := typeEncoder(timeType)
+		return func(e *encodeState, key string, v reflect.Value, opts encOpts) {
+			timeEncoder(e, key, reflect.ValueOf(protoTime(v)), opts)
+		}
+	}
+	return nil
+}
+
+// protoTime returns the time of the timestamp message v.
+func protoTime(v reflect.Value) time.Time {
+	return time.Unix(v.FieldByName("Seconds").Int(), v.FieldByName("Nanos").Int()).UTC()
+}
+
+// decodeProto stores src into the protobuf message v handled specially,
+// reporting whether v is one: src is stored into the Value field of a
+// wrapper, and parsed as a time.Time into a timestamp.
+func (d *decodeState) decodeProto(path string, src any, v reflect.Value) (bool, error) {
+	switch protoKindOf(v.Type()) {
+	case protoWrapper:
+		return true, d.decodeValue(path, src, v.FieldByName("Value"), false)
+	case protoTimestamp:
+		var t time.Time
+		if err := d.decodeValue(path, src, reflect.ValueOf(&t).Elem(), false); err != nil {
+			return true, err
+		}
+		v.FieldByName("Seconds").SetInt(t.Unix())
+		v.FieldByName("Nanos").SetInt(int64(t.Nanosecond()))
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,74 @@
+package structof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// The types below mimic the code generated for the well-known protobuf
+// types, without the protobuf runtime.
+
+type pbMessageState struct{ _ [0]func() }
+
+type pbStringValue struct {
+	state pbMessageState
+	Value string
+}
+
+func (x *pbStringValue) ProtoReflect() any { return x }
+func (x *pbStringValue) GetValue() string  { return x.Value }
+
+type pbInt64Value struct {
+	state pbMessageState
+	Value int64
+}
+
+func (x *pbInt64Value) ProtoReflect() any { return x }
+func (x *pbInt64Value) GetValue() int64   { return x.Value }
+
+type pbTimestamp struct {
+	state   pbMessageState
+	Seconds int64
+	Nanos   int32
+}
+
+func (x *pbTimestamp) ProtoReflect() any { return x }
+func (x *pbTimestamp) AsTime() time.Time {
+	return time.Unix(x.Seconds, int64(x.Nanos)).UTC()
+}
+
+func TestProtobufWellKnownTypes(t *testing.T) {
+	t.Parallel()
+
+	type User struct {
+		Name    *pbStringValue `structof:"name"`
+		Age     *pbInt64Value  `structof:"age"`
+		Created *pbTimestamp   `structof:"created"`
+		Nick    *pbStringValue `structof:"nick"`
+	}
+	at := time.Date(2009, time.November, 10, 23, 0, 0, 5, time.UTC)
+	u := User{
+		Name:    &pbStringValue{Value: "foo"},
+		Age:     &pbInt64Value{Value: 23},
+		Created: &pbTimestamp{Seconds: at.Unix(), Nanos: 5},
+	}
+
+	m := MakeMap(&u)
+	want := map[string]any{"name": "foo", "age": int64(23), "created": at, "nick": (*pbStringValue)(nil)}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+
+	var got User
+	if err := FillStruct(map[string]any{"name": "foo", "age": 23, "created": "2009-11-10T23:00:00.000000005Z", "nick": nil}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name.GetValue() != "foo" || got.Age.GetValue() != 23 || !got.Created.AsTime().Equal(at) || got.Nick != nil {
+		t.Errorf("FillStruct = %+v", got)
+	}
+	if err := FillStruct(map[string]any{"age": "old"}, &got); err == nil {
+		t.Error("FillStruct of a string into an Int64Value should return error")
+	}
+}
@@ -0,0 +1,61 @@
+package structof
+
+import (
+	"reflect"
+	"sync"
+)
+
+var (
+	registeredTypes sync.Map // map[string]reflect.Type
+	registeredNames sync.Map // map[reflect.Type]string
+)
+
+// RegisterType makes the struct type of i known under name to the
+// polymorphic decoding of interfaces, so that a Decoder instantiates the
+// type for the maps naming it by the key of WithTypes, or by the element of
+// a "typekey" option, and to the Encoders configured by WithTypeKey, which
+// name the type by name instead of its package qualified name. Stable names
+// such as "order.created" decouple the encoded maps from the Go package
+// layout:
+//
+//	func init() {
+//		structof.RegisterType("order.created", OrderCreated{})
+//	}
+//
+// The i may be a struct, a pointer to struct, or the reflect.Type of either.
+// RegisterType panics if it is not, if name is empty, or if name or the type
+// is already registered. It is meant to be called from init functions.
+func RegisterType(name string, i any) {
+	t := structType(i)
+	if name == "" {
+		panic("structof: RegisterType name is empty")
+	}
+	if _, dup := registeredTypes.LoadOrStore(name, t); dup {
+		panic("structof: RegisterType called twice for " + name)
+	}
+	if _, dup := registeredNames.LoadOrStore(t, name); dup {
+		registeredTypes.Delete(name)
+		panic("structof: RegisterType called twice for type " + t.String())
+	}
+}
+
+// typeName returns the name of the struct type t, as registered by
+// RegisterType or qualified by its package path, as by FullTypeName,
+// so that the types of the same name in distinct packages are told apart.
+func typeName(t reflect.Type) string {
+	if name, ok := registeredNames.Load(t); ok {
+		return name.(string)
+	}
+	return fullTypeName(t)
+}
+
+// lookupType returns the struct type named name by WithTypes or RegisterType.
+func (dec *Decoder) lookupType(name string) (reflect.Type, bool) {
+	if t, ok := dec.types[name]; ok {
+		return t, true
+	}
+	if t, ok := registeredTypes.Load(name); ok {
+		return t.(reflect.Type), true
+	}
+	return nil, false
+}
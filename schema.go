@@ -0,0 +1,166 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A FieldInfo describes a field of a struct type as resolved by the encoder
+// from its structof tag and the embedding rules.
+type FieldInfo struct {
+	Name      string       // Go name of the field
+	Key       string       // key of the field in the map
+	Index     []int        // index sequence for reflect.Value.FieldByIndex
+	Type      reflect.Type // field type
+	OmitEmpty bool         // whether the "omitempty" option is set
+	Quoted    bool         // whether the "string" option is set and applies
+	Inline    bool         // whether the fields of the field are flattened
+}
+
+// TypeSchema returns the fields of the struct type of i that MakeMap encodes,
+// in index sequence order, so that packages built on structof resolve
+// the fields the same way it does. Fields omitted by the "-" tag or
+// hidden by the embedding rules are not present; the fields of an inline
+// struct are described by the TypeSchema of its type.
+//
+// The i may be a struct, a pointer to struct, or the reflect.Type of either.
+// TypeSchema panics if it is not.
+func TypeSchema(i any) []FieldInfo {
+	fields := cachedTypeFields(structType(i)).list
+	infos := make([]FieldInfo, len(fields))
+	for i, f := range fields {
+		infos[i] = FieldInfo{
+			Name:      f.sf.Name,
+			Key:       f.name,
+			Index:     append([]int(nil), f.index...),
+			Type:      f.typ,
+			OmitEmpty: f.omitEmpty,
+			Quoted:    f.quoted,
+			Inline:    f.inline,
+		}
+	}
+	return infos
+}
+
+// A ChangeKind is the kind of a SchemaChange.
+type ChangeKind int
+
+const (
+	// KeyAdded reports a key only present in the new type.
+	KeyAdded ChangeKind = iota
+
+	// KeyRemoved reports a key only present in the old type.
+	KeyRemoved
+
+	// KeyRenamed reports a field encoded under a different key
+	// in the new type.
+	KeyRenamed
+
+	// TypeChanged reports a key whose value has a different type
+	// in the new type.
+	TypeChanged
+)
+
+// A SchemaChange describes a difference between the maps encoded from
+// two struct types. The fields of inline structs are compared as the keys
+// they are flattened into, and named by their path of Go names,
+// separated by dots.
+type SchemaChange struct {
+	Kind    ChangeKind
+	Name    string       // Go name of the field, in the old type unless added
+	OldKey  string       // key in the old type, empty if added
+	NewKey  string       // key in the new type, empty if removed
+	OldType reflect.Type // type in the old type, nil if added
+	NewType reflect.Type // type in the new type, nil if removed
+}
+
+func (c SchemaChange) String() string {
+	switch c.Kind {
+	case KeyAdded:
+		return fmt.Sprintf("added key %q (%s %s)", c.NewKey, c.Name, c.NewType)
+	case KeyRemoved:
+		return fmt.Sprintf("removed key %q (%s %s)", c.OldKey, c.Name, c.OldType)
+	case KeyRenamed:
+		return fmt.Sprintf("renamed key %q to %q (%s)", c.OldKey, c.NewKey, c.Name)
+	case TypeChanged:
+		return fmt.Sprintf("changed type of key %q from %s to %s (%s)", c.OldKey, c.OldType, c.NewType, c.Name)
+	}
+	return fmt.Sprintf("SchemaChange(%d)", c.Kind)
+}
+
+// DiffSchema returns the changes between the maps that MakeMap encodes from
+// the struct types of oldT and newT, to detect the breaking changes to
+// persisted map formats. A field keeping its Go name under a new key is
+// reported as renamed, rather than as the removal of a key and the
+// addition of another. The changes to the keys of the old type come first,
+// in index sequence order, followed by the added keys.
+//
+// The oldT and newT may be structs, pointers to struct, or the reflect.Type
+// of either. DiffSchema panics if they are not.
+func DiffSchema(oldT, newT any) []SchemaChange {
+	olds, news := schemaKeys(structType(oldT)), schemaKeys(structType(newT))
+	oldByKey := make(map[string]schemaKey, len(olds))
+	for _, k := range olds {
+		oldByKey[k.key] = k
+	}
+	newByKey := make(map[string]schemaKey, len(news))
+	newByName := make(map[string]schemaKey, len(news))
+	for _, k := range news {
+		newByKey[k.key] = k
+		newByName[k.name] = k
+	}
+
+	var changes []SchemaChange
+	renamed := make(map[string]bool)
+	for _, o := range olds {
+		if n, ok := newByKey[o.key]; ok {
+			if o.typ != n.typ {
+				changes = append(changes, SchemaChange{TypeChanged, o.name, o.key, n.key, o.typ, n.typ})
+			}
+			continue
+		}
+		if n, ok := newByName[o.name]; ok {
+			if _, ok := oldByKey[n.key]; !ok {
+				renamed[n.key] = true
+				changes = append(changes, SchemaChange{KeyRenamed, o.name, o.key, n.key, o.typ, n.typ})
+				continue
+			}
+		}
+		changes = append(changes, SchemaChange{KeyRemoved, o.name, o.key, "", o.typ, nil})
+	}
+	for _, n := range news {
+		if _, ok := oldByKey[n.key]; !ok && !renamed[n.key] {
+			changes = append(changes, SchemaChange{KeyAdded, n.name, "", n.key, nil, n.typ})
+		}
+	}
+	return changes
+}
+
+type schemaKey struct {
+	name string
+	key  string
+	typ  reflect.Type
+}
+
+// schemaKeys returns the keys of the map encoded from the struct type t,
+// with the fields of inline structs flattened.
+func schemaKeys(t reflect.Type) []schemaKey {
+	var keys []schemaKey
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for _, f := range cachedTypeFields(t).list {
+			if ft := f.typ; f.inline {
+				for reflect.Pointer == ft.Kind() {
+					ft = ft.Elem()
+				}
+				if reflect.Struct == ft.Kind() {
+					walk(ft, prefix+f.sf.Name+".")
+					continue
+				}
+			}
+			keys = append(keys, schemaKey{prefix + f.sf.Name, f.name, f.typ})
+		}
+	}
+	walk(t, "")
+	return keys
+}
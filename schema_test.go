@@ -0,0 +1,84 @@
+package structof
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTypeSchema(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct {
+		X int `structof:"x"`
+	}
+	type Embedded struct {
+		E string
+	}
+	type T struct {
+		A int    `structof:"a,omitempty,string"`
+		B string `structof:"-"`
+		C Inner  `structof:",inline"`
+		Embedded
+		unexported int
+	}
+
+	want := []FieldInfo{
+		{Name: "A", Key: "a", Index: []int{0}, Type: reflect.TypeOf(0), OmitEmpty: true, Quoted: true},
+		{Name: "C", Key: "C", Index: []int{2}, Type: reflect.TypeOf(Inner{}), Inline: true},
+		{Name: "E", Key: "E", Index: []int{3, 0}, Type: reflect.TypeOf("")},
+	}
+	for _, i := range []any{T{}, &T{}, reflect.TypeOf(T{})} {
+		got := TypeSchema(i)
+		if diff := cmp.Diff(want, got, cmp.Comparer(func(x, y reflect.Type) bool { return x == y })); diff != "" {
+			t.Errorf("TypeSchema(%T) mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	got := TypeSchema(T{})
+	got[0].Index[0] = 1
+	if TypeSchema(T{})[0].Index[0] != 0 {
+		t.Error("TypeSchema shares the Index of its fields")
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	t.Parallel()
+
+	type Meta struct {
+		Created int64 `structof:"created"`
+	}
+	type V1 struct {
+		ID    int    `structof:"id"`
+		Name  string `structof:"name"`
+		Email string `structof:"email"`
+		Age   int    `structof:"age"`
+		Meta  *Meta  `structof:",inline"`
+	}
+	type V2 struct {
+		ID    string `structof:"id"`
+		Name  string `structof:"full_name"`
+		Age   int    `structof:"age"`
+		Phone string `structof:"phone"`
+		Meta  Meta   `structof:",inline"`
+	}
+
+	var got []string
+	for _, c := range DiffSchema(V1{}, &V2{}) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`changed type of key "id" from int to string (ID)`,
+		`renamed key "name" to "full_name" (Name)`,
+		`removed key "email" (Email string)`,
+		`added key "phone" (Phone string)`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DiffSchema mismatch (-want +got):\n%s", diff)
+	}
+
+	if changes := DiffSchema(V1{}, reflect.TypeOf(V1{})); changes != nil {
+		t.Errorf("DiffSchema of the same type = %v, want nil", changes)
+	}
+}
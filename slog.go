@@ -0,0 +1,74 @@
+package structof
+
+import (
+	"log/slog"
+	"reflect"
+	"sort"
+)
+
+// GroupValue returns a slog.Value of the struct s, which a slog.Handler
+// resolves into a group of the keys and values of s, in the order of
+// MakeSlice. The nested structs and maps are resolved into nested groups,
+// with their keys sorted, rather than being logged as flattened maps.
+// The struct is only encoded when the Handler resolves the value, so that
+// the records discarded by their level cost nothing.
+//
+// GroupValue panics if s is not a struct or non-nil pointer to struct.
+// See FillMap function's documentation for more information.
+func GroupValue(s any) slog.Value {
+	return defaultEncoder.GroupValue(s)
+}
+
+// Group returns a slog.Attr for the struct s under the key name, whose value
+// is the group of GroupValue, as in logger.Info("login", structof.Group("user", u)).
+func Group(name string, s any) slog.Attr {
+	return defaultEncoder.Group(name, s)
+}
+
+// GroupValue is like the GroupValue function but encodes with enc's configuration.
+func (enc *Encoder) GroupValue(s any) slog.Value {
+	v := reflect.ValueOf(s)
+	for reflect.Pointer == v.Kind() && !v.IsNil() {
+		v = v.Elem()
+	}
+	if reflect.Struct != v.Kind() {
+		panic("not struct or pointer to struct")
+	}
+	return slog.AnyValue(groupValuer{enc, s})
+}
+
+// Group is like the Group function but encodes with enc's configuration.
+func (enc *Encoder) Group(name string, s any) slog.Attr {
+	return slog.Attr{Key: name, Value: enc.GroupValue(s)}
+}
+
+// A groupValuer is a slog.LogValuer encoding a struct when resolved.
+type groupValuer struct {
+	enc *Encoder
+	s   any
+}
+
+func (g groupValuer) LogValue() slog.Value {
+	keys, values := g.enc.keysValues(g.s)
+	attrs := make([]slog.Attr, len(keys))
+	for i, key := range keys {
+		attrs[i] = slog.Attr{Key: key, Value: logValue(values[i])}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// logValue returns the slog.Value of an encoded value,
+// a group for a map with string keys.
+func logValue(v any) slog.Value {
+	rv := reflect.ValueOf(v)
+	if reflect.Map != rv.Kind() || reflect.String != rv.Type().Key().Kind() {
+		return slog.AnyValue(v)
+	}
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	attrs := make([]slog.Attr, len(keys))
+	for i, key := range keys {
+		attrs[i] = slog.Attr{Key: key.String(), Value: logValue(rv.MapIndex(key).Interface())}
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,58 @@
+package structof
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		Zip  string `structof:"zip"`
+		City string `structof:"city"`
+	}
+	type User struct {
+		Name    string            `structof:"name"`
+		ID      int               `structof:"id"`
+		Address *Address          `structof:"address"`
+		Labels  map[string]string `structof:"labels"`
+	}
+	u := &User{"foo", 23, &Address{"75001", "Paris"}, map[string]string{"b": "2", "a": "1"}}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("login", Group("user", u))
+	want := "level=INFO msg=login user.name=foo user.id=23 user.address.city=Paris user.address.zip=75001 user.labels.a=1 user.labels.b=2\n"
+	if got := buf.String(); want != got {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// The struct is encoded when the record is handled.
+	buf.Reset()
+	v := GroupValue(u)
+	u.Name = "bar"
+	logger.Info("login", "user", v)
+	if want := "level=INFO msg=login user.name=bar"; !bytes.HasPrefix(buf.Bytes(), []byte(want)) {
+		t.Errorf("got %q, want prefix %q", buf.String(), want)
+	}
+
+	for _, i := range []any{0, (*User)(nil)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("GroupValue(%#v) did not panic", i)
+				}
+			}()
+			GroupValue(i)
+		}()
+	}
+}
@@ -1,6 +1,7 @@
 package structof
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -148,3 +149,132 @@ func TestName(t *testing.T) {
 		}
 	}
 }
+
+func TestStruct_NonZeroMap(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `structof:"city"`
+		Zip  string `structof:"zip,omitempty"`
+	}
+	type T struct {
+		Name    string   `structof:"name"`
+		Age     int      `structof:"age"`
+		Active  bool     `structof:"active,omitempty"`
+		Tags    []string `structof:"tags,omitempty"`
+		Email   *string  `structof:"email"`
+		Address Address  `structof:"address"`
+		Home    Address  `structof:"home"`
+	}
+	v := T{Name: "foo", Tags: []string{}, Address: Address{City: "Paris"}}
+
+	want := map[string]any{
+		"name":    "foo",
+		"tags":    []string{},
+		"address": map[string]any{"city": "Paris"},
+	}
+	if got := MakeStruct(&v).NonZeroMap(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestStruct_ZeroFieldNames(t *testing.T) {
+	t.Parallel()
+
+	type Auth struct {
+		Token string `structof:"token"`
+	}
+	type T struct {
+		Host  string `structof:"host"`
+		Port  int    `structof:"port"`
+		Debug bool
+		*Auth
+	}
+	v := T{Port: 8080}
+
+	want := []string{"host", "Debug", "token"}
+	if got := MakeStruct(&v).ZeroFieldNames(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	v = T{Host: "localhost", Port: 8080, Debug: true, Auth: &Auth{"x"}}
+	if got := MakeStruct(&v).ZeroFieldNames(); len(got) != 0 {
+		t.Errorf("ZeroFieldNames = %q", got)
+	}
+}
+
+func TestStruct_InterfaceAddr(t *testing.T) {
+	t.Parallel()
+
+	type T struct {
+		A int
+	}
+	v := &T{A: 1}
+	s := MakeStruct(v)
+
+	if p, ok := s.Addr().(*T); !ok || p != v {
+		t.Errorf("Addr() = %#v, want %p", s.Addr(), v)
+	}
+	i := s.Interface()
+	v.A = 2
+	if got, ok := i.(T); !ok || got.A != 1 {
+		t.Errorf("Interface() = %#v, want T{A: 1}", i)
+	}
+}
+
+type pair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+func TestFullTypeName(t *testing.T) {
+	t.Parallel()
+
+	type T struct{}
+
+	tests := []struct {
+		i    any
+		want string
+	}{
+		{T{}, "github.com/weiwenchen2022/structof.T"},
+		{&T{}, "*github.com/weiwenchen2022/structof.T"},
+		{[]*url.URL{}, "[]*net/url.URL"},
+		{map[string][2]url.URL{}, "map[string][2]net/url.URL"},
+		{make(<-chan T), "<-chan github.com/weiwenchen2022/structof.T"},
+		{pair[int, *url.URL]{}, "github.com/weiwenchen2022/structof.pair[int,*net/url.URL]"},
+		{0, "int"},
+		{struct{ A int }{}, "struct { A int }"},
+	}
+	for _, tt := range tests {
+		if got := FullTypeName(tt.i); tt.want != got {
+			t.Errorf("FullTypeName(%T) = %q, want %q", tt.i, got, tt.want)
+		}
+	}
+
+	if got := MakeStruct(&url.URL{}).FullName(); got != "net/url.URL" {
+		t.Errorf("FullName() = %q", got)
+	}
+}
+
+func TestShortTypeName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		i    any
+		want string
+	}{
+		{pair[int, *url.URL]{}, "pair[int,*url.URL]"},
+		{pair[[]url.URL, map[string]pair[url.Values, func(url.URL) error]]{}, "pair[[]url.URL,map[string]structof.pair[url.Values,func(url.URL) error]]"},
+		{url.URL{}, "URL"},
+		{0, "int"},
+	}
+	for _, tt := range tests {
+		if got := ShortTypeName(tt.i); tt.want != got {
+			t.Errorf("ShortTypeName(%T) = %q, want %q", tt.i, got, tt.want)
+		}
+	}
+
+	if got := MakeStruct(&pair[string, url.URL]{}).ShortName(); got != "pair[string,url.URL]" {
+		t.Errorf("ShortName() = %q", got)
+	}
+}
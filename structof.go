@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"unsafe"
 
 	"github.com/weiwenchen2022/structtag"
 )
@@ -12,6 +13,9 @@ import (
 type Struct struct {
 	v   reflect.Value
 	typ reflect.Type
+
+	// Whether the unexported fields are included, set by Unexported.
+	unexported bool
 }
 
 // MakeStruct returns a Struct with the struct i.
@@ -25,6 +29,17 @@ func MakeStruct(i any) Struct {
 	return Struct{v: v, typ: v.Type()}
 }
 
+// Interface returns the struct value s wraps, a copy of the struct.
+func (s Struct) Interface() any {
+	return s.v.Interface()
+}
+
+// Addr returns the pointer to the struct s wraps, the one given to
+// MakeStruct, through which the struct can be changed.
+func (s Struct) Addr() any {
+	return s.v.Addr().Interface()
+}
+
 // FillMap fills into the map[string]any with struct field name as the key, and field value as element.
 // If i's Kind not struct or pointer to struct,
 // or v's type not map[string]any, or pointer to map[string]any, FillMap panics.
@@ -32,6 +47,15 @@ func (s Struct) FillMap(i any) {
 	FillMap(s.v.Addr().Interface(), i)
 }
 
+// Unexported returns a copy of s whose FieldByName and Fields also return
+// the unexported fields, for debuggers and test assertions. The unexported
+// fields are read-only: their Interface method returns a copy of their value
+// read through package unsafe, and Set, SetZero and SetFromString panic.
+func (s Struct) Unexported() Struct {
+	s.unexported = true
+	return s
+}
+
 // MakeMap converts the struct s to a map[string]any.
 // See FillMap function's documentation for more information.
 func (s Struct) MakeMap() map[string]any {
@@ -40,6 +64,17 @@ func (s Struct) MakeMap() map[string]any {
 	return m
 }
 
+// NonZeroMap is like MakeMap but returns only the fields whose value is not
+// the zero value of their type, as reported by reflect.Value.IsZero, whether
+// or not they have the "omitempty" option, as for the columns of a dynamic
+// UPDATE statement: a non-nil empty slice is kept, and so is a struct with a
+// non-zero field. The values of the fields are encoded as by MakeMap.
+func (s Struct) NonZeroMap() map[string]any {
+	enc := *defaultEncoder
+	enc.nonZero = true
+	return enc.MakeMap(s.v.Addr().Interface())
+}
+
 func (s Struct) MakeSlice() []any {
 	return MakeSlice(s.v.Addr().Interface())
 }
@@ -47,60 +82,94 @@ func (s Struct) MakeSlice() []any {
 // Fields returns a slice of StructField.
 // See Fields function's documentation for more information.
 func (s Struct) Fields() []Field {
+	if s.unexported {
+		return fieldsOf(s.v, cachedUnexportedFields(s.typ))
+	}
 	return Fields(s.v.Addr().Interface())
 }
 
 func (s Struct) FieldNames() []string {
 	fields := cachedTypeFields(s.typ)
-	names := make([]string, len(fields.list))
+	names := make([]string, 0, len(fields.list))
+	for i := range fields.list {
+		f := &fields.list[i]
+		if f.method != "" {
+			continue
+		}
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// ZeroFieldNames returns the names of the fields of FieldNames whose value
+// is the zero value of their type, in the same order, as for reporting the
+// required settings missing from a configuration. The fields promoted from
+// a nil embedded pointer are zero.
+func (s Struct) ZeroFieldNames() []string {
+	fields := cachedTypeFields(s.typ)
+	var names []string
 	for i := range fields.list {
 		f := &fields.list[i]
-		names[i] = f.name
+		if f.method != "" {
+			continue
+		}
+		if fv, err := s.v.FieldByIndexErr(f.index); err != nil || fv.IsZero() {
+			names = append(names, f.name)
+		}
 	}
 	return names
 }
 
 // FieldByName returns a single exported struct field that provides several high level functions
 // and a boolean indicating if the field was found.
+//
+// The unexported fields are found too if s is returned by Unexported,
+// except those reached through an interface holding a struct value.
+//
+// The name may be a dot-separated path to a nested field. The path is followed
+// through pointers to structs and through interfaces holding structs, including
+// embedded interfaces, so "Reader.Size" finds the field Size of the struct
+// held by an embedded io.Reader. A field reached through an interface holding a
+// struct value, rather than a pointer, is not addressable and cannot be Set.
 func (s Struct) FieldByName(name string) (Field, error) {
-	ft := s.typ
-	var sf reflect.StructField
+	v := s.v
 
 	names := strings.Split(name, ".")
-	index := make([]int, len(names))
 	for i, n := range names {
-		var ok bool
-		sf, ok = ft.FieldByNameFunc(func(s string) bool { return n == s })
+		sf, ok := v.Type().FieldByNameFunc(func(s string) bool { return n == s })
 		if !ok {
 			return Field{}, fmt.Errorf("field %q not found", name)
 		}
 
-		if !sf.IsExported() {
+		if !sf.IsExported() && !s.unexported {
 			return Field{}, fmt.Errorf("field %q not exported", name)
 		}
 
-		index[i] = sf.Index[0]
+		f, err := v.FieldByIndexErr(sf.Index)
+		if err != nil {
+			return Field{}, err
+		}
+		if !f.CanInterface() && !f.CanAddr() {
+			return Field{}, fmt.Errorf("field %q not exported and not addressable", name)
+		}
 		if len(names)-1 == i {
-			break
+			return Field{v: f, sf: sf, parent: v, path: name}, nil
 		}
 
-		ft = sf.Type
-		if reflect.Pointer == sf.Type.Kind() {
-			// Follow pointer.
-			ft = sf.Type.Elem()
+		// Follow pointers and interfaces.
+		for reflect.Pointer == f.Kind() || reflect.Interface == f.Kind() {
+			if f.IsNil() {
+				return Field{}, fmt.Errorf("field %q is nil", strings.Join(names[:i+1], "."))
+			}
+			f = f.Elem()
 		}
-		if reflect.Struct != ft.Kind() {
+		if reflect.Struct != f.Kind() {
 			return Field{}, fmt.Errorf("field %q not struct or pointer to struct",
 				strings.Join(names[:i+1], "."))
 		}
+		v = f
 	}
-	sf.Index = index
-
-	f, err := s.v.FieldByIndexErr(sf.Index)
-	if err != nil {
-		return Field{}, err
-	}
-	return Field{v: f, sf: sf}, nil
+	panic("unreachable")
 }
 
 // Name returns the s's type name within its package.
@@ -109,6 +178,19 @@ func (s Struct) Name() string {
 	return s.typ.Name()
 }
 
+// ShortName returns the s's type name within its package with the type
+// arguments of a generic type qualified by their package name,
+// as ShortTypeName does.
+func (s Struct) ShortName() string {
+	return shortTypeName(s.typ.Name())
+}
+
+// FullName returns the s's type name qualified by its package path,
+// as FullTypeName does.
+func (s Struct) FullName() string {
+	return fullTypeName(s.typ)
+}
+
 // IsZero reports whether v is the zero value for its type.
 // It panics if the argument is nil.
 func IsZero(i any) bool {
@@ -131,6 +213,82 @@ func TypeName(i any) string {
 	return reflect.TypeOf(i).Name()
 }
 
+// FullTypeName returns the name of the dynamic type of i qualified by the
+// path of its package, as "net/url.URL", so that the names of the types of
+// distinct packages do not collide in registries. The pointer, slice, array,
+// map and channel types are named after their element types, as "*net/url.URL",
+// and the type arguments of generic types are qualified too, as
+// "example.com/pkg.Pair[int,*net/url.URL]". The predeclared types are named
+// by their name, and the other unnamed types, such as struct types, as by
+// reflect.Type.String.
+// It panics if i is a nil interface value.
+func FullTypeName(i any) string {
+	return fullTypeName(reflect.TypeOf(i))
+}
+
+func fullTypeName(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		return t.PkgPath() + "." + t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + fullTypeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + fullTypeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), fullTypeName(t.Elem()))
+	case reflect.Map:
+		return "map[" + fullTypeName(t.Key()) + "]" + fullTypeName(t.Elem())
+	case reflect.Chan:
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + fullTypeName(t.Elem())
+		case reflect.SendDir:
+			return "chan<- " + fullTypeName(t.Elem())
+		}
+		return "chan " + fullTypeName(t.Elem())
+	}
+	return t.String()
+}
+
+// ShortTypeName is like TypeName but qualifies the type arguments of an
+// instantiated generic type by the name of their package rather than its
+// path, as they are written in Go source: "Box[int,*url.URL]" instead of
+// "Box[int,*net/url.URL]". The names of other types are returned as by
+// TypeName.
+// It panics if i is a nil interface value.
+func ShortTypeName(i any) string {
+	return shortTypeName(reflect.TypeOf(i).Name())
+}
+
+// shortTypeName returns name with the package paths qualifying the names
+// in its type arguments shortened to their last element.
+func shortTypeName(name string) string {
+	if !strings.Contains(name, "/") {
+		return name
+	}
+	var b strings.Builder
+	start := 0 // of the current identifier
+	for i := 0; i <= len(name); i++ {
+		if i < len(name) && !strings.ContainsRune("[],*() ", rune(name[i])) {
+			continue
+		}
+		ident := name[start:i]
+		if j := strings.LastIndexByte(ident, '/'); j >= 0 {
+			ident = ident[j+1:]
+		}
+		b.WriteString(ident)
+		if i < len(name) {
+			b.WriteByte(name[i])
+		}
+		start = i + 1
+	}
+	return b.String()
+}
+
 // FieldNames returns a list of the struct type's field name.
 // It panics if the v's kind is not struct or pointer to struct.
 func FieldNames(i any) []string {
@@ -158,29 +316,45 @@ func Fields(i any) []Field {
 	if v.Kind() != reflect.Pointer || v.IsNil() || v.Type().Elem().Kind() != reflect.Struct {
 		panic("not non-nil pointer to struct")
 	}
-	v = v.Elem()
+	return fieldsOf(v.Elem(), cachedTypeFields(v.Type().Elem()))
+}
 
-	fields := cachedTypeFields(v.Type())
+// fieldsOf returns the Fields of the struct v among fields,
+// skipping the fields reachable only through nil embedded pointers.
+func fieldsOf(v reflect.Value, fields structFields) []Field {
 	fs := make([]Field, len(fields.list))
 	j := 0
 	typ := v.Type()
 	for i := range fields.list {
 		f := &fields.list[i]
+		if f.method != "" {
+			// Computed by a method, the field has no value of its own.
+			continue
+		}
 		fv, err := v.FieldByIndexErr(f.index)
 		if err != nil {
 			continue
 		}
-		fs[i] = Field{v: fv, sf: typ.FieldByIndex(f.index)}
+		fs[j] = Field{v: fv, sf: typ.FieldByIndex(f.index), parent: v}
 		j++
 	}
 	return fs[:j]
-	// return MakeStruct(i).Fields()
 }
 
 // Field represents a single struct field that encapsulates high level functions around the field.
 type Field struct {
 	v  reflect.Value
 	sf reflect.StructField
+
+	// The struct holding the field.
+	parent reflect.Value
+
+	// Key of the tag naming the field, set by Named.
+	tagKey string
+
+	// Dotted path of the Go names leading to the field, set by
+	// FieldByName and FieldsRecursive.
+	path string
 }
 
 // Tag returns the tag associated with key in the tag string.
@@ -194,9 +368,61 @@ func (f Field) Tag(key string) structtag.Tag {
 //
 //	var i any = (v's underlying value)
 func (f Field) Interface() any {
+	if !f.v.CanInterface() {
+		// A read-only unexported field, addressable as found by FieldByName.
+		return reflect.NewAt(f.v.Type(), unsafe.Pointer(f.v.UnsafeAddr())).Elem().Interface()
+	}
 	return f.v.Interface()
 }
 
+// Value returns the reflect.Value of f, for the operations the Field methods
+// do not cover. It is settable if f is, as for the fields of a Struct made
+// from a pointer, and not settable otherwise, as for the fields reached
+// through an interface holding a struct value. The unexported fields of a
+// Struct returned by Unexported are read-only, package reflect panicking on
+// their Interface and Set methods.
+func (f Field) Value() reflect.Value {
+	return f.v
+}
+
+// Parent returns the Struct holding f, the outer struct for the fields
+// promoted from embedded structs. A Struct of a copy of the struct is
+// returned if the struct is not addressable, as when encoding a struct
+// passed by value.
+func (f Field) Parent() Struct {
+	v := f.parent
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	return Struct{v: v, typ: v.Type()}
+}
+
+// Struct returns the Struct of the struct field f, or of the struct f
+// points to, so that the Struct functions apply to nested structs.
+// It returns an error if f is neither a struct nor a non-nil pointer to
+// struct. As for Parent, a Struct of a copy of the struct is returned if it
+// is not addressable.
+func (f Field) Struct() (Struct, error) {
+	v := f.v
+	for reflect.Pointer == v.Kind() {
+		if v.IsNil() {
+			return Struct{}, fmt.Errorf("field %q is nil", f.sf.Name)
+		}
+		v = v.Elem()
+	}
+	if reflect.Struct != v.Kind() {
+		return Struct{}, fmt.Errorf("field %q not struct or pointer to struct", f.sf.Name)
+	}
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	return Struct{v: v, typ: v.Type()}, nil
+}
+
 // IsEmbedded reports whether the field is an embedded field.
 func (f Field) IsEmbedded() bool {
 	return f.sf.Anonymous
@@ -207,19 +433,122 @@ func (f Field) IsZero() bool {
 	return f.v.IsZero()
 }
 
-// Name returns the field name.
+// IsNil reports whether the pointer, interface, map, slice, function or
+// channel field f is nil. Unlike IsZero, it is false for the fields of
+// other kinds, so that a zero int or struct is not taken for a nil value.
+func (f Field) IsNil() bool {
+	switch f.v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return f.v.IsNil()
+	}
+	return false
+}
+
+// Name returns the field name, or the name given by the tag of f's Named
+// key, if any.
 func (f Field) Name() string {
+	if f.tagKey != "" {
+		if name := f.Tag(f.tagKey).Name; name != "" {
+			return name
+		}
+	}
 	return f.sf.Name
 }
 
-// Type returns the field's type.
+// Path returns the dotted path of the Go field names leading to f from the
+// struct it was found in, as given to FieldByName or found by
+// FieldsRecursive, and its Go name otherwise.
+func (f Field) Path() string {
+	if f.path == "" {
+		return f.sf.Name
+	}
+	return f.path
+}
+
+// Named returns a view of f whose Name is the name given by the tag with
+// key, such as "json" or "db", falling back to the Go name if the tag is
+// missing or gives no name, so that one traversal of the fields builds the
+// keys of several encodings. The tag name "-" is returned as is, for the
+// caller to skip the field. The other methods are those of f.
+func (f Field) Named(key string) Field {
+	f.tagKey = key
+	return f
+}
+
+// Type returns the field's type, the element type for a Field returned by Elem.
 func (f Field) Type() reflect.Type {
-	return f.sf.Type
+	return f.v.Type()
 }
 
 // Kind returns the field's kind.
 func (f Field) Kind() reflect.Kind {
-	return f.sf.Type.Kind()
+	return f.v.Kind()
+}
+
+// Len returns the length of the string, slice, array, map or channel field
+// f, or an error if f is of another kind.
+func (f Field) Len() (int, error) {
+	switch f.v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return f.v.Len(), nil
+	}
+	return 0, fmt.Errorf("field %q of kind %s has no length", f.sf.Name, f.v.Kind())
+}
+
+// Elem returns the value that the pointer or interface field f points to or
+// holds, as a Field with the name and tag of f, whose Type is the element
+// type or the dynamic type of the value held. It returns an error if f is
+// nil or is not a pointer or interface.
+// The value held by an interface is not addressable, and cannot be Set,
+// unless it is itself a pointer.
+func (f Field) Elem() (Field, error) {
+	switch f.v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+	default:
+		return Field{}, fmt.Errorf("field %q not pointer or interface", f.sf.Name)
+	}
+	if f.v.IsNil() {
+		return Field{}, fmt.Errorf("field %q is nil", f.sf.Name)
+	}
+	f.v = f.v.Elem()
+	return f, nil
+}
+
+// IsStruct reports whether f is a struct or a pointer to struct, through
+// any number of pointers.
+func (f Field) IsStruct() bool {
+	return reflect.Struct == indirectType(f.v.Type()).Kind()
+}
+
+// IsSlice reports whether f is a slice or a pointer to slice.
+func (f Field) IsSlice() bool {
+	return reflect.Slice == indirectType(f.v.Type()).Kind()
+}
+
+// IsMap reports whether f is a map or a pointer to map.
+func (f Field) IsMap() bool {
+	return reflect.Map == indirectType(f.v.Type()).Kind()
+}
+
+// IsPointer reports whether f is a pointer.
+func (f Field) IsPointer() bool {
+	return reflect.Pointer == f.v.Kind()
+}
+
+// IsPrimitive reports whether f is a boolean, number or string, or a
+// pointer to one of these, as MakeMap emits them. Structs encoded as a
+// single value, such as time.Time, are not primitive, nor are complex
+// numbers, which MakeMap does not encode.
+func (f Field) IsPrimitive() bool {
+	switch indirectType(f.v.Type()).Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	}
+	return false
 }
 
 // Set assigns x to the value v.
@@ -232,7 +561,96 @@ func (f Field) Set(i any) {
 	f.v.Set(v.Convert(f.v.Type()))
 }
 
+// MapIndex returns the element of the map field f under key, as a Field
+// with the name and tag of f, and whether key is in the map.
+// The element is not addressable and cannot be Set; see SetMapIndex.
+// It panics if f is not a map or key is not assignable or convertible to
+// the map's key type.
+func (f Field) MapIndex(key any) (Field, bool) {
+	e := f.mapValue().MapIndex(convertTo(key, f.v.Type().Key()))
+	if !e.IsValid() {
+		return Field{}, false
+	}
+	f.v = e
+	return f, true
+}
+
+// MapKeys returns the keys of the map field f, in unspecified order.
+// It panics if f is not a map.
+func (f Field) MapKeys() []any {
+	keys := f.mapValue().MapKeys()
+	a := make([]any, len(keys))
+	for i, k := range keys {
+		a[i] = k.Interface()
+	}
+	return a
+}
+
+// SetMapIndex sets the element of the map field f under key to value,
+// allocating the map if it is nil.
+// It panics if f is not a settable map, or key and value are not assignable
+// or convertible to the map's key and element types.
+func (f Field) SetMapIndex(key, value any) {
+	m := f.mapValue()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	m.SetMapIndex(convertTo(key, m.Type().Key()), convertTo(value, m.Type().Elem()))
+}
+
+// DeleteMapIndex deletes the element of the map field f under key, if any.
+// It panics if f is not a map or key is not assignable or convertible to
+// the map's key type.
+func (f Field) DeleteMapIndex(key any) {
+	m := f.mapValue()
+	if !m.IsNil() {
+		m.SetMapIndex(convertTo(key, m.Type().Key()), reflect.Value{})
+	}
+}
+
+// mapValue returns the value of the map field f; it panics if f is not a map.
+func (f Field) mapValue() reflect.Value {
+	if reflect.Map != f.v.Kind() {
+		panic(fmt.Sprintf("field %s not map", f.sf.Name))
+	}
+	return f.v
+}
+
+// convertTo returns the value of i assigned or converted to the type t,
+// the zero value of t if i is nil. It panics if i cannot be converted.
+func convertTo(i any, t reflect.Type) reflect.Value {
+	v := reflect.ValueOf(i)
+	switch {
+	case !v.IsValid():
+		return reflect.Zero(t)
+	case v.Type().AssignableTo(t):
+		return v
+	}
+	return v.Convert(t)
+}
+
 // SetZero sets f to be the zero value of f's type.
 func (f Field) SetZero() {
 	f.v.SetZero()
 }
+
+// SetFromString parses s according to f's type and assigns the result to f,
+// for values coming from command lines, environment variables and forms.
+// Booleans and numbers are parsed with the strconv package, durations with
+// time.ParseDuration, and url.URL with url.Parse. Types implementing
+// encoding.TextUnmarshaler, such as time.Time, parse s themselves. Slices
+// are parsed by splitting s at commas, each element being parsed according
+// to the element type, except byte slices, which are set to the bytes of s.
+// Pointers are allocated to hold the parsed value.
+//
+// SetFromString returns an error if s cannot be parsed into f's type.
+// It panics if f cannot be set, as Set does.
+func (f Field) SetFromString(s string) error {
+	if !f.v.CanSet() {
+		panic(fmt.Sprintf("structof: SetFromString of unsettable field %s", f.sf.Name))
+	}
+	if err := parseString(f.v, s); err != nil {
+		return fmt.Errorf("structof: field %s: %w", f.sf.Name, err)
+	}
+	return nil
+}
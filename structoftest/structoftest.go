@@ -0,0 +1,134 @@
+// Package structoftest provides helpers for testing the maps produced by
+// package structof.
+//
+// The helpers normalize the maps before comparing them, so that tests do
+// not depend on whether a collection came out as a typed slice or map,
+// such as []int or map[string]string, or as []any or map[string]any.
+package structoftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/weiwenchen2022/structof"
+)
+
+var update = flag.Bool("structoftest.update", false, "update the golden files of AssertGolden")
+
+// Normalize returns a copy of v in which every slice and array, except
+// []byte, is converted to []any and every map with string keys is converted
+// to map[string]any, recursively. Other values are returned as is.
+func Normalize(v any) any {
+	if v == nil {
+		return nil
+	}
+	return normalize(reflect.ValueOf(v))
+}
+
+func normalize(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return normalize(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if reflect.Uint8 == v.Type().Elem().Kind() {
+			break
+		}
+		if reflect.Slice == v.Kind() && v.IsNil() {
+			return []any(nil)
+		}
+		s := make([]any, v.Len())
+		for i := range s {
+			s[i] = normalize(v.Index(i))
+		}
+		return s
+	case reflect.Map:
+		if reflect.String != v.Type().Key().Kind() {
+			break
+		}
+		if v.IsNil() {
+			return map[string]any(nil)
+		}
+		m := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			m[it.Key().String()] = normalize(it.Value())
+		}
+		return m
+	}
+	if !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// AssertMapEqual reports a test error with a diff
+// if the normalized want and got maps differ.
+func AssertMapEqual(t testing.TB, want, got map[string]any, opts ...cmp.Option) {
+	t.Helper()
+
+	if diff := cmp.Diff(Normalize(want), Normalize(got), opts...); diff != "" {
+		t.Errorf("map mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// AssertGolden compares the normalized got map, encoded as indented JSON,
+// with the content of the golden file testdata/name.golden, and reports
+// a test error if they differ.
+//
+// When the test binary is run with the -structoftest.update flag,
+// AssertGolden writes the golden file instead.
+func AssertGolden(t testing.TB, name string, got map[string]any) {
+	t.Helper()
+
+	data, err := json.MarshalIndent(Normalize(got), "", "\t")
+	if err != nil {
+		t.Fatalf("encode %s: %v", name, err)
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if !bytes.Equal(want, data) {
+		t.Errorf("golden file %s mismatch (-want +got):\n%s", path, cmp.Diff(string(want), string(data)))
+	}
+}
+
+// RoundTrip encodes the struct v with an Encoder configured by
+// structof.WithRoundTrip, decodes the map with structof.FillStruct into a
+// new value of type T, and reports a test error with a diff if the value
+// differs from v. The opts are passed to cmp.Diff, such as to ignore the
+// unexported fields of T. T must be a struct type.
+func RoundTrip[T any](t testing.TB, v T, opts ...cmp.Option) {
+	t.Helper()
+
+	m := structof.NewEncoder(structof.WithRoundTrip()).MakeMap(v)
+	var got T
+	if err := structof.FillStruct(m, &got); err != nil {
+		t.Errorf("FillStruct: %v", err)
+		return
+	}
+	if diff := cmp.Diff(v, got, opts...); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
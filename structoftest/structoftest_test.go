@@ -0,0 +1,126 @@
+package structoftest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/weiwenchen2022/structof"
+	"github.com/weiwenchen2022/structof/structoftest"
+)
+
+// recorder records the errors reported through the testing.TB interface.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+type Address struct {
+	Country string `structof:"country"`
+}
+
+type Person struct {
+	Name      string            `structof:"name"`
+	Ints      []int             `structof:"ints"`
+	Labels    map[string]string `structof:"labels"`
+	Addresses []Address         `structof:"addresses"`
+}
+
+var person = Person{
+	Name:      "foobar",
+	Ints:      []int{1, 2},
+	Labels:    map[string]string{"foo": "bar"},
+	Addresses: []Address{{"Italy"}},
+}
+
+func TestAssertMapEqual(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]any{
+		"name":      "foobar",
+		"ints":      []any{1, 2},
+		"labels":    map[string]any{"foo": "bar"},
+		"addresses": []map[string]any{{"country": "Italy"}},
+	}
+	got := structof.MakeMap(person)
+
+	r := &recorder{TB: t}
+	structoftest.AssertMapEqual(r, want, got)
+	if len(r.errors) > 0 {
+		t.Errorf("AssertMapEqual reported %q", r.errors)
+	}
+
+	want["name"] = "baz"
+	structoftest.AssertMapEqual(r, want, got)
+	if len(r.errors) != 1 {
+		t.Errorf("AssertMapEqual should report a mismatch, reported %q", r.errors)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	t.Parallel()
+
+	structoftest.AssertGolden(t, "person", structof.MakeMap(person))
+
+	r := &recorder{TB: t}
+	structoftest.AssertGolden(r, "person", structof.MakeMap(Person{Name: "baz"}))
+	if len(r.errors) != 1 {
+		t.Errorf("AssertGolden should report a mismatch, reported %q", r.errors)
+	}
+}
+
+type Level int
+
+func (l Level) String() string { return fmt.Sprintf("level-%d", int(l)) }
+
+type Event struct {
+	Kind     string            `structof:"kind,trim"`
+	Level    Level             `structof:"level,stringer"`
+	Payload  any               `structof:"payload"`
+	Ptr      any               `structof:"ptr"`
+	Count    *int              `structof:"count"`
+	Tags     []string          `structof:"tags,indexmap"`
+	Labels   map[string]string `structof:"labels,omitempty"`
+	Address  Address           `structof:",inline"`
+	Quoted   int64             `structof:"quoted,string"`
+	When     time.Time         `structof:"when"`
+	Children []Address         `structof:"children,keyby=country"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	n := 3
+	e := Event{
+		Kind:     " created ",
+		Level:    2,
+		Payload:  Address{"Italy"},
+		Ptr:      &Address{"France"},
+		Count:    &n,
+		Tags:     []string{"a", "b"},
+		Address:  Address{"Spain"},
+		Quoted:   42,
+		When:     time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Children: []Address{{"Peru"}},
+	}
+	r := &recorder{TB: t}
+	structoftest.RoundTrip(r, e)
+	if len(r.errors) > 0 {
+		t.Errorf("RoundTrip reported %q", r.errors)
+	}
+
+	type Secret struct {
+		Name     string `structof:"name"`
+		Password string `structof:"password,decodeonly"`
+	}
+	structoftest.RoundTrip(r, Secret{"foo", "hunter2"})
+	if len(r.errors) != 1 {
+		t.Errorf("RoundTrip should report a mismatch, reported %q", r.errors)
+	}
+}
@@ -0,0 +1,5 @@
+// Package genpkg declares types for the tests of GenerateGo and of the
+// names of types in distinct packages of the same name.
+package genpkg
+
+type Point struct{ X, Y int }
@@ -0,0 +1,6 @@
+// Package genpkg is a package whose name differs from the last element of
+// its path, for the tests of GenerateGo and of the names of types in
+// distinct packages of the same name.
+package genpkg
+
+type Point struct{ X, Y, Z int }
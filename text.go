@@ -0,0 +1,64 @@
+package structof
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+var urlType = reflect.TypeOf(url.URL{})
+
+// newTextEncoder returns the encoder of the types encoded as strings, or nil:
+// url.URL, encoded as by its String method, and the array and slice types
+// implementing encoding.TextMarshaler, such as uuid.UUID and net.IP, encoded
+// as the text they marshal to instead of as their bytes.
+func newTextEncoder(t reflect.Type) encoderFunc {
+	switch {
+	case urlType == t:
+		return urlEncoder
+	case (reflect.Array == t.Kind() || reflect.Slice == t.Kind()) && t.Implements(textMarshalerType):
+		return textEncoder
+	}
+	return nil
+}
+
+func urlEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
+	u := v.Interface().(url.URL)
+	e.setLeaf(key, u.String())
+}
+
+func textEncoder(e *encodeState, key string, v reflect.Value, opts encOpts) {
+	if reflect.Slice == v.Kind() && v.IsNil() {
+		e.setNil(key, v)
+		return
+	}
+	b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		e.error(fmt.Errorf("structof: field %q: %w", joinKey(e.path, key), err))
+	}
+	e.setLeaf(key, string(b))
+}
+
+// unmarshalString stores into v the value the string s represents if v is a
+// url.URL, parsed by url.Parse, or implements encoding.TextUnmarshaler,
+// reporting whether it is one of them.
+func unmarshalString(v reflect.Value, s string) (bool, error) {
+	if urlType == v.Type() {
+		u, err := url.Parse(s)
+		if err != nil {
+			return true, err
+		}
+		v.Set(reflect.ValueOf(*u))
+		return true, nil
+	}
+	if !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return false, nil
+	}
+	p := reflect.New(v.Type())
+	if err := p.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+		return true, err
+	}
+	v.Set(p.Elem())
+	return true, nil
+}
@@ -0,0 +1,78 @@
+package structof
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testUUID mimics uuid.UUID.
+type testUUID [4]byte
+
+func (u testUUID) String() string { return hex.EncodeToString(u[:]) }
+
+func (u testUUID) MarshalText() ([]byte, error) { return []byte(u.String()), nil }
+
+func (u *testUUID) UnmarshalText(b []byte) error {
+	if hex.DecodedLen(len(b)) != len(u) {
+		return errors.New("invalid UUID length")
+	}
+	_, err := hex.Decode(u[:], b)
+	return err
+}
+
+func TestTextTypes(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		ID    testUUID `structof:"id"`
+		IP    net.IP   `structof:"ip"`
+		URL   url.URL  `structof:"url"`
+		Link  *url.URL `structof:"link"`
+		NoIP  net.IP   `structof:"no_ip"`
+		Peers []net.IP `structof:"peers"`
+	}
+	link, _ := url.Parse("https://example.com/a?b=c")
+	s := S{
+		ID:    testUUID{0xde, 0xad, 0xbe, 0xef},
+		IP:    net.ParseIP("192.0.2.1"),
+		URL:   url.URL{Scheme: "http", Host: "example.com", Path: "/x"},
+		Link:  link,
+		Peers: []net.IP{net.ParseIP("::1")},
+	}
+
+	m := MakeMap(&s)
+	want := map[string]any{
+		"id":    "deadbeef",
+		"ip":    "192.0.2.1",
+		"url":   "http://example.com/x",
+		"link":  "https://example.com/a?b=c",
+		"no_ip": net.IP(nil),
+		"peers": []any{"::1"},
+	}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Errorf("MakeMap mismatch (-want +got):\n%s", diff)
+	}
+
+	var got S
+	if err := FillStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(s, got); diff != "" {
+		t.Errorf("FillStruct mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, m := range []map[string]any{
+		{"id": "dead"},
+		{"ip": "not an ip"},
+		{"url": "http://[::1"},
+	} {
+		if err := FillStruct(m, &got); err == nil {
+			t.Errorf("FillStruct(%v) should return error", m)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/weiwenchen2022/structtag"
+)
+
+// A Transformer transforms the value of a field, named in its tag options,
+// as in `structof:"email,trim,lowercase"`. It is called with the value of
+// the field when encoding, and with its element of the map when decoding
+// with a Decoder configured by WithDecodeTransformers.
+type Transformer func(v any) (any, error)
+
+var transformers sync.Map // map[string]Transformer
+
+func init() {
+	RegisterTransformer("lowercase", stringTransformer(strings.ToLower))
+	RegisterTransformer("uppercase", stringTransformer(strings.ToUpper))
+	RegisterTransformer("trim", stringTransformer(strings.TrimSpace))
+}
+
+// RegisterTransformer makes the Transformer fn available under name to the
+// tag options of the fields, which run the transformers they name in order.
+// The transformers "lowercase", "uppercase" and "trim", applying
+// strings.ToLower, strings.ToUpper and strings.TrimSpace to strings and
+// keeping other values as they are, are registered by the package.
+//
+// RegisterTransformer panics if name is already registered, is the name of
+// an option of the package, or if fn is nil. It is meant to be called from
+// init functions.
+func RegisterTransformer(name string, fn Transformer) {
+	if fn == nil {
+		panic("structof: RegisterTransformer fn is nil")
+	}
+	if builtinOptions[name] || strings.ContainsAny(name, ",=") || name == "" {
+		panic("structof: RegisterTransformer invalid name " + name)
+	}
+	if _, dup := transformers.LoadOrStore(name, fn); dup {
+		panic("structof: RegisterTransformer called twice for " + name)
+	}
+}
+
+// stringTransformer returns a Transformer applying fn to strings.
+func stringTransformer(fn func(string) string) Transformer {
+	return func(v any) (any, error) {
+		rv := reflect.ValueOf(v)
+		if reflect.String != rv.Kind() {
+			return v, nil
+		}
+		s := reflect.New(rv.Type()).Elem()
+		s.SetString(fn(rv.String()))
+		return s.Interface(), nil
+	}
+}
+
+// builtinOptions are the tag options of the package without a value,
+// which are not transformer names.
+var builtinOptions = map[string]bool{
+	"omitempty": true,
+	"string":    true,
+	"inline":    true,
+	"squash":    true,
+	"stringer":  true,
+	"json":      true,
+	"indexmap":  true,
+
+	"encodeonly": true,
+	"decodeonly": true,
+}
+
+// valueOptions are the names of the tag options of the package with a value.
+var valueOptions = map[string]bool{
+	"alias":     true,
+	"default":   true,
+	"groups":    true,
+	"keyby":     true,
+	"method":    true,
+	"order":     true,
+	"precision": true,
+	"since":     true,
+	"typekey":   true,
+	"until":     true,
+}
+
+// parseTransforms returns the names of the options of opts
+// that may name transformers.
+func parseTransforms(opts structtag.TagOptions) []string {
+	var names []string
+	for _, opt := range strings.Split(string(opts), ",") {
+		if opt != "" && !builtinOptions[opt] && !strings.Contains(opt, "=") {
+			names = append(names, opt)
+		}
+	}
+	return names
+}
+
+// transform runs the transformers named by names on v, in order.
+// The names without a registered Transformer are skipped.
+func transform(names []string, v any) (any, error) {
+	for _, name := range names {
+		fn, ok := transformers.Load(name)
+		if !ok {
+			continue
+		}
+		var err error
+		if v, err = fn.(Transformer)(v); err != nil {
+			return nil, fmt.Errorf("transformer %s: %w", name, err)
+		}
+	}
+	return v, nil
+}
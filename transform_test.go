@@ -0,0 +1,76 @@
+package structof
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type transformEmail string
+
+func init() {
+	RegisterTransformer("test_mask", func(v any) (any, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("not a string")
+		}
+		return strings.Repeat("*", len(s)), nil
+	})
+}
+
+func TestTransformers(t *testing.T) {
+	t.Parallel()
+
+	type S struct {
+		Email    transformEmail `structof:"email,trim,lowercase"`
+		Name     string         `structof:"name,omitempty,uppercase,unknown"`
+		Password string         `structof:"password,test_mask"`
+		Count    int            `structof:"count,trim"`
+	}
+	s := S{Email: "  Foo@Example.COM ", Name: "foo", Password: "hunter2", Count: 3}
+
+	m := MakeMap(s)
+	want := map[string]any{
+		"email":    transformEmail("foo@example.com"),
+		"name":     "FOO",
+		"password": "*******",
+		"count":    3,
+	}
+	if !cmp.Equal(want, m) {
+		t.Error(cmp.Diff(want, m))
+	}
+	checkLazyMap(t, defaultEncoder, s)
+
+	var got S
+	in := map[string]any{"email": " Bar@Example.com", "name": "bar", "password": "secret"}
+	if err := NewDecoder(WithDecodeTransformers()).FillStruct(in, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (S{Email: "bar@example.com", Name: "BAR", Password: "******"}); want != got {
+		t.Errorf("FillStruct = %+v, want %+v", got, want)
+	}
+	if err := FillStruct(in, &got); err != nil || got.Email != " Bar@Example.com" {
+		t.Errorf("FillStruct without WithDecodeTransformers = %+v, %v", got, err)
+	}
+
+	type Bad struct {
+		N int `structof:"n,test_mask"`
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("MakeMap with failing transformer should panic")
+			}
+		}()
+		MakeMap(Bad{})
+	}()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterTransformer of builtin option should panic")
+		}
+	}()
+	RegisterTransformer("omitempty", func(v any) (any, error) { return v, nil })
+}
@@ -0,0 +1,64 @@
+package structof
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+var unexportedFieldCache typeCache[structFields]
+
+// cachedUnexportedFields is like cachedTypeFields but includes the
+// unexported fields.
+func cachedUnexportedFields(t reflect.Type) structFields {
+	if f, ok := unexportedFieldCache.Load(t); ok {
+		return f
+	}
+	f, _ := unexportedFieldCache.LoadOrStore(t, typeFields(t, true, false))
+	return f
+}
+
+var taglessFieldCache, taglessUnexportedFieldCache typeCache[structFields]
+
+// cachedTaglessFields is like cachedTypeFields but ignores the structof
+// tags, and includes the unexported fields if unexported is set.
+func cachedTaglessFields(t reflect.Type, unexported bool) structFields {
+	cache := &taglessFieldCache
+	if unexported {
+		cache = &taglessUnexportedFieldCache
+	}
+	if f, ok := cache.Load(t); ok {
+		return f
+	}
+	f, _ := cache.LoadOrStore(t, typeFields(t, unexported, true))
+	return f
+}
+
+// structFields returns the fields of the struct type t that enc encodes.
+func (enc *Encoder) structFields(t reflect.Type) structFields {
+	if enc.tagless {
+		return cachedTaglessFields(t, enc.unexported && !keptOpaque(t))
+	}
+	if enc.unexported && !keptOpaque(t) {
+		return cachedUnexportedFields(t)
+	}
+	return cachedTypeFields(t)
+}
+
+// keptOpaque reports whether the unexported fields of the struct type t are
+// not encoded by WithUnexported, t having its own representation.
+func keptOpaque(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(stringerType) || pt.Implements(textMarshalerType)
+}
+
+// readableValue returns value, made to return readable copies of the
+// unexported fields of the addressable struct values it is given.
+func readableValue(value func(reflect.Value) (reflect.Value, bool)) func(reflect.Value) (reflect.Value, bool) {
+	return func(v reflect.Value) (reflect.Value, bool) {
+		fv, ok := value(v)
+		if ok && !fv.CanInterface() {
+			fv = reflect.NewAt(fv.Type(), unsafe.Pointer(fv.UnsafeAddr())).Elem()
+		}
+		return fv, ok
+	}
+}
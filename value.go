@@ -0,0 +1,45 @@
+package structof
+
+import "reflect"
+
+// A Value is the entry of a map made by MakeValueMap: the encoded value of a
+// field with the type of the field.
+type Value struct {
+	// Type is the Go type of the field, such as *time.Time for a pointer
+	// field or the interface type for an interface field.
+	Type reflect.Type
+
+	// Kind is the kind of Type.
+	Kind reflect.Kind
+
+	// Value is the value of the field as encoded by MakeMap.
+	Value any
+}
+
+// MakeValueMap is like MakeMap but returns the type of each field along with
+// its encoded value, in one pass over the struct, for the consumers aware of
+// schemas such as query builders and validators. The nested structs, maps
+// and slices are encoded as by MakeMap, only the keys of s holding a Value.
+// The fields of an inline struct have their own types.
+//
+// The Type of a field is kept when its value is changed by the "stringer",
+// "json" or "indexmap" options, a Transformer or WithValueFunc.
+func MakeValueMap(s any) map[string]Value {
+	return defaultEncoder.MakeValueMap(s)
+}
+
+// MakeValueMap is like the MakeValueMap function but encodes with enc's configuration.
+func (enc *Encoder) MakeValueMap(s any) map[string]Value {
+	types := make(map[string]reflect.Type)
+	m := enc.makeMap(s, types)
+	values := make(map[string]Value, len(m))
+	for key, elem := range m {
+		t := types[key]
+		var kind reflect.Kind
+		if t != nil {
+			kind = t.Kind()
+		}
+		values[key] = Value{Type: t, Kind: kind, Value: elem}
+	}
+	return values
+}
@@ -0,0 +1,58 @@
+package structof
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMakeValueMap(t *testing.T) {
+	t.Parallel()
+
+	type Address struct {
+		City string `structof:"city"`
+	}
+	type Base struct {
+		ID int64 `structof:"id"`
+	}
+	type S struct {
+		Base    `structof:",inline"`
+		Name    string         `structof:"name"`
+		Created *time.Time     `structof:"created"`
+		Tags    []string       `structof:"tags"`
+		Address Address        `structof:"address"`
+		Level   stringerLevel  `structof:"level,stringer"`
+		Extra   any            `structof:"extra"`
+		Skipped string         `structof:"skipped,omitempty"`
+		Attrs   map[string]int `structof:"attrs"`
+	}
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := S{
+		Base:    Base{7},
+		Name:    "foo",
+		Created: &created,
+		Tags:    []string{"a"},
+		Address: Address{"Paris"},
+		Level:   2,
+		Extra:   1.5,
+		Attrs:   map[string]int{"x": 1},
+	}
+
+	typeOf := reflect.TypeOf
+	want := map[string]Value{
+		"id":      {typeOf(int64(0)), reflect.Int64, int64(7)},
+		"name":    {typeOf(""), reflect.String, "foo"},
+		"created": {typeOf(&created), reflect.Pointer, created},
+		"tags":    {typeOf([]string(nil)), reflect.Slice, []string{"a"}},
+		"address": {typeOf(Address{}), reflect.Struct, map[string]any{"city": "Paris"}},
+		"level":   {typeOf(stringerLevel(0)), reflect.Int, stringerLevel(2).String()},
+		"extra":   {typeOf((*any)(nil)).Elem(), reflect.Interface, 1.5},
+		"attrs":   {typeOf(map[string]int(nil)), reflect.Map, map[string]int{"x": 1}},
+	}
+	got := MakeValueMap(&s)
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(x, y reflect.Type) bool { return x == y })); diff != "" {
+		t.Errorf("MakeValueMap mismatch (-want +got):\n%s", diff)
+	}
+}
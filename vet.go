@@ -0,0 +1,260 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/weiwenchen2022/structtag"
+)
+
+// VetTags reports the mistakes in the structof tags of the struct type of i,
+// and of the struct types of its fields, at any depth, that otherwise
+// silently change the encoding: malformed tags, invalid key names, unknown
+// options, options applied to fields of unsupported kinds, and keys claimed
+// by several fields, one of them hiding the others.
+// It returns nil if there is no mistake, and is meant for tests:
+//
+//	func TestTags(t *testing.T) {
+//		for _, err := range structof.VetTags(Config{}) {
+//			t.Error(err)
+//		}
+//	}
+//
+// The i may be a struct, a pointer to struct, or the reflect.Type of either.
+// VetTags panics if it is not.
+func VetTags(i any) []error {
+	v := vetter{seen: make(map[reflect.Type]bool)}
+	v.vet(structType(i))
+	return v.errs
+}
+
+type vetter struct {
+	seen map[reflect.Type]bool
+	errs []error
+}
+
+func (v *vetter) errorf(t reflect.Type, sf reflect.StructField, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("structof: %s.%s: %s", t, sf.Name, fmt.Sprintf(format, args...)))
+}
+
+func (v *vetter) vet(t reflect.Type) {
+	if v.seen[t] {
+		return
+	}
+	v.seen[t] = true
+
+	var nested []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous && !isMethodField(sf) {
+			continue
+		}
+		nested = append(nested, sf.Type)
+		v.vetTag(t, sf)
+	}
+	v.vetKeys(t)
+
+	for _, ft := range nested {
+		for {
+			switch ft.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+				ft = ft.Elem()
+				continue
+			}
+			break
+		}
+		if reflect.Struct == ft.Kind() {
+			v.vet(ft)
+		}
+	}
+}
+
+// vetTag checks the structof tag of the field sf of t.
+func (v *vetter) vetTag(t reflect.Type, sf reflect.StructField) {
+	tag, ok := structtag.StructTag(sf.Tag).Lookup("structof")
+	if !ok {
+		if strings.Contains(string(sf.Tag), "structof:") {
+			v.errorf(t, sf, "malformed tag %s", sf.Tag)
+		}
+		return
+	}
+	if tag.String() == `structof:"-"` {
+		return
+	}
+	if tag.Name != "" && !isValidTag(tag.Name) {
+		v.errorf(t, sf, "invalid key name %q", tag.Name)
+	}
+
+	ft := sf.Type
+	if ft.Name() == "" && reflect.Pointer == ft.Kind() {
+		ft = ft.Elem()
+	}
+	for _, opt := range strings.Split(string(tag.Options), ",") {
+		if opt == "" {
+			continue
+		}
+		if name, value, ok := strings.Cut(opt, "="); ok {
+			if !valueOptions[name] {
+				v.errorf(t, sf, "unknown option %q", name)
+			}
+			if name == "keyby" && reflect.Slice != ft.Kind() && reflect.Array != ft.Kind() {
+				v.errorf(t, sf, "option %q does not apply to %s", name, sf.Type)
+			}
+			if name == "typekey" && reflect.Interface != ft.Kind() {
+				v.errorf(t, sf, "option %q does not apply to %s", name, sf.Type)
+			}
+			if _, err := strconv.Atoi(value); name == "order" && err != nil {
+				v.errorf(t, sf, "invalid order %q", value)
+			}
+			if n, err := strconv.Atoi(value); name == "precision" && (err != nil || n < 0) {
+				v.errorf(t, sf, "invalid precision %q", value)
+			}
+			if name == "precision" && bigFloatType != ft && bigRatType != ft {
+				v.errorf(t, sf, "option %q does not apply to %s", name, sf.Type)
+			}
+			if name == "method" {
+				v.vetMethod(t, sf, value)
+			}
+			if name == "alias" {
+				for _, alias := range strings.Split(value, "|") {
+					if !isValidTag(alias) {
+						v.errorf(t, sf, "invalid alias %q", alias)
+					}
+				}
+			}
+			continue
+		}
+		switch opt {
+		case "string":
+			switch ft.Kind() {
+			case reflect.Bool,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64,
+				reflect.String,
+				reflect.Struct:
+			default:
+				v.errorf(t, sf, "option %q does not apply to %s", opt, sf.Type)
+			}
+		case "inline", "squash":
+			if reflect.Struct != ft.Kind() {
+				v.errorf(t, sf, "option %q does not apply to %s", opt, sf.Type)
+			}
+		case "indexmap":
+			if reflect.Slice != ft.Kind() && reflect.Array != ft.Kind() {
+				v.errorf(t, sf, "option %q does not apply to %s", opt, sf.Type)
+			}
+		default:
+			if _, ok := transformers.Load(opt); !ok && !builtinOptions[opt] {
+				v.errorf(t, sf, "unknown option %q", opt)
+			}
+		}
+	}
+}
+
+// vetMethod checks the method named by the "method" option of the field
+// sf of t.
+func (v *vetter) vetMethod(t reflect.Type, sf reflect.StructField, name string) {
+	if sf.Name != "_" {
+		v.errorf(t, sf, "option %q does not apply to non-blank field", "method")
+		return
+	}
+	m, ok := reflect.PointerTo(t).MethodByName(name)
+	switch {
+	case !ok:
+		v.errorf(t, sf, "method %q not found", name)
+	case m.Type.NumIn() != 1 || m.Type.NumOut() != 1:
+		v.errorf(t, sf, "method %q does not take no argument and return one value", name)
+	}
+}
+
+// vetKeys checks that each key of the struct type t is claimed by a single
+// field, among the fields of t, of its embedded structs, visible at the
+// same depth, and of its inline structs.
+func (v *vetter) vetKeys(t reflect.Type) {
+	type candidate struct {
+		t  reflect.Type
+		sf reflect.StructField
+	}
+
+	// Keys claimed by embedded fields at a given depth hide the deeper
+	// ones, as for Go's promoted fields; several at the same depth hide
+	// each other.
+	claimed := make(map[string]string)
+	visited := map[reflect.Type]bool{t: true}
+	for level := []reflect.Type{t}; len(level) > 0; {
+		var next []reflect.Type
+		var names []string
+		keys := make(map[string][]candidate)
+		for _, lt := range level {
+			for i := 0; i < lt.NumField(); i++ {
+				sf := lt.Field(i)
+				tag, _ := structtag.StructTag(sf.Tag).Lookup("structof")
+				if tag.String() == `structof:"-"` {
+					continue
+				}
+				name := tag.Name
+				if !isValidTag(name) {
+					name = ""
+				}
+				ft := sf.Type
+				if reflect.Pointer == ft.Kind() && ft.Name() == "" {
+					ft = ft.Elem()
+				}
+				if name == "" && sf.Anonymous && reflect.Struct == ft.Kind() {
+					if !visited[ft] {
+						visited[ft] = true
+						next = append(next, ft)
+					}
+					continue
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = sf.Name
+				}
+				if _, ok := keys[name]; !ok {
+					names = append(names, name)
+				}
+				keys[name] = append(keys[name], candidate{lt, sf})
+			}
+		}
+		for _, name := range names {
+			cs := keys[name]
+			if _, ok := claimed[name]; ok {
+				continue
+			}
+			claimed[name] = cs[0].sf.Name
+			if len(cs) > 1 {
+				v.errorf(cs[1].t, cs[1].sf, "key %q also claimed by %s.%s", name, cs[0].t, cs[0].sf.Name)
+			}
+		}
+		level = next
+	}
+
+	// The fields of inline structs are flattened into the map of t,
+	// where they overwrite each other. An inline struct of a type already
+	// being walked, which would recur forever, is not walked again.
+	emitted := make(map[string]string)
+	walking := make(map[reflect.Type]bool)
+	var walk func(ft reflect.Type, prefix string)
+	walk = func(ft reflect.Type, prefix string) {
+		walking[ft] = true
+		defer delete(walking, ft)
+		for _, f := range cachedTypeFields(ft).list {
+			if ft := indirectType(f.typ); f.inline && reflect.Struct == ft.Kind() && !walking[ft] {
+				walk(ft, prefix+f.sf.Name+".")
+				continue
+			}
+			if other, ok := emitted[f.name]; ok && prefix != "" {
+				v.errs = append(v.errs, fmt.Errorf("structof: %s: key %q claimed by both %s and %s", t, f.name, other, prefix+f.sf.Name))
+				continue
+			}
+			emitted[f.name] = prefix + f.sf.Name
+		}
+	}
+	walk(t, "")
+}
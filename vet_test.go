@@ -0,0 +1,122 @@
+package structof
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type vetEmbedA struct {
+	ID int
+}
+
+type vetEmbedB struct {
+	ID int
+}
+
+type vetInline struct {
+	Name string `structof:"name"`
+}
+
+type vetNested struct {
+	Bad int `structof:"bad,nosuch"`
+}
+
+func TestVetTags(t *testing.T) {
+	t.Parallel()
+
+	type Good struct {
+		A int            `structof:"a,omitempty,string"`
+		B string         `structof:"b,alias=bb|bbb,default=x,trim"`
+		C vetInline      `structof:",inline"`
+		D *int           `structof:"d,string"`
+		E []vetInline    `structof:"e"`
+		F map[string]int `structof:"-"`
+		vetEmbedA
+	}
+	if errs := VetTags(Good{}); errs != nil {
+		t.Errorf("VetTags(Good) = %v, want nil", errs)
+	}
+
+	type Bad struct {
+		A    int             `structof:"a,nosuch"`
+		B    []int           `structof:"b,string"`
+		C    int             `structof:"c,inline"`
+		D    int             `structof:"d,size=3"`
+		E    int             `structof:"e,alias=ok|"`
+		Name string          `structof:"name"`
+		G    vetInline       `structof:",inline"`
+		H    map[string]bool `structof:"h,omitempty,string"`
+		I    []vetNested
+		J    int    `structof:"j,indexmap,keyby=id"`
+		K    string `structof:"k,typekey=type"`
+		L    int    `structof:"l,order=first"`
+		M    int    `structof:"m,precision=-1"`
+		N    int    `structof:"n,method=String"`
+		_    int    `structof:"o,method=Missing"`
+		vetEmbedA
+		vetEmbedB
+	}
+	var got []string
+	for _, err := range VetTags(reflect.TypeOf(&Bad{})) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`structof: structof.Bad.A: unknown option "nosuch"`,
+		`structof: structof.Bad.B: option "string" does not apply to []int`,
+		`structof: structof.Bad.C: option "inline" does not apply to int`,
+		`structof: structof.Bad.D: unknown option "size"`,
+		`structof: structof.Bad.E: invalid alias ""`,
+		`structof: structof.Bad.H: option "string" does not apply to map[string]bool`,
+		`structof: structof.Bad.J: option "indexmap" does not apply to int`,
+		`structof: structof.Bad.J: option "keyby" does not apply to int`,
+		`structof: structof.Bad.K: option "typekey" does not apply to string`,
+		`structof: structof.Bad.L: invalid order "first"`,
+		`structof: structof.Bad.M: invalid precision "-1"`,
+		`structof: structof.Bad.M: option "precision" does not apply to int`,
+		`structof: structof.Bad.N: option "method" does not apply to non-blank field`,
+		`structof: structof.Bad._: method "Missing" not found`,
+		`structof: structof.vetEmbedB.ID: key "ID" also claimed by structof.vetEmbedA.ID`,
+		`structof: structof.Bad: key "name" claimed by both Name and G.Name`,
+		`structof: structof.vetNested.Bad: unknown option "nosuch"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("VetTags(Bad) mismatch (-want +got):\n%s", diff)
+	}
+
+	malformed := reflect.StructOf([]reflect.StructField{
+		{Name: "F", Type: reflect.TypeOf(0), Tag: `structof:"f`},
+	})
+	got = nil
+	for _, err := range VetTags(malformed) {
+		got = append(got, err.Error())
+	}
+	want = []string{"structof: struct { F int \"structof:\\\"f\" }.F: malformed tag structof:\"f"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("VetTags(malformed) mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, i := range []any{0, "", nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("VetTags(%#v) did not panic", i)
+				}
+			}()
+			VetTags(i)
+		}()
+	}
+}
+
+func TestVetTagsRecursiveInline(t *testing.T) {
+	t.Parallel()
+
+	type R struct {
+		V    int
+		Next *R `structof:",inline"`
+	}
+	if errs := VetTags(R{}); errs != nil {
+		t.Errorf("VetTags(R) = %v, want nil", errs)
+	}
+}
@@ -0,0 +1,373 @@
+package structof
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A WalkAction tells Walk how to proceed after visiting a field.
+type WalkAction int
+
+const (
+	// WalkContinue continues the traversal, descending into the field.
+	WalkContinue WalkAction = iota
+	// WalkSkip continues the traversal without descending into the field.
+	WalkSkip
+	// WalkStop stops the traversal.
+	WalkStop
+)
+
+// Walk visits every exported field of the struct s depth-first, calling fn
+// for each field with its path, in the order given by Fields.
+// It panics if s is not non-nil pointer to struct.
+//
+// Paths join the Go field names with dots, the way FieldByName expects them.
+// Walk descends into fields holding a struct or a non-nil pointer to struct,
+// and into the struct elements of slices, arrays and maps, whose paths are
+// suffixed with the element index or map key in brackets, as in "Items[2].SKU"
+// or "Users[admin].Name". Map elements are not addressable, so their fields
+// are visited on copies and setting them does not modify the map.
+// A pointer already being walked higher up the path is not followed again,
+// so cyclic structures terminate.
+//
+// The traversal is controlled by the WalkAction returned by fn.
+func Walk(s any, fn func(path string, f Field) WalkAction) {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Type().Elem().Kind() != reflect.Struct {
+		panic("not non-nil pointer to struct")
+	}
+	w := walker{fn: fn, seen: map[any]bool{ptrKey(v): true}}
+	w.walkStruct("", v.Elem())
+}
+
+type walker struct {
+	fn func(path string, f Field) WalkAction
+
+	// Pointers being walked in the current path, by ptrKey.
+	seen map[any]bool
+}
+
+// A typedPtr identifies a pointer by its address and type, as a pointer to
+// the first field of a struct has the address of the struct itself.
+type typedPtr struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+// ptrKey returns the key of the pointer v in the sets of pointers visited.
+func ptrKey(v reflect.Value) any {
+	return typedPtr{v.Pointer(), v.Type()}
+}
+
+// walkStruct walks the fields of the struct v and reports whether to stop.
+func (w *walker) walkStruct(prefix string, v reflect.Value) bool {
+	for _, f := range fieldsOf(v, cachedTypeFields(v.Type())) {
+		path := f.Name()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		switch w.fn(path, f) {
+		case WalkStop:
+			return true
+		case WalkSkip:
+			continue
+		}
+		if w.walkValue(path, f.v) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkValue descends into v if it holds structs and reports whether to stop.
+func (w *walker) walkValue(path string, v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Struct:
+		return w.walkStruct(path, v)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return false
+		}
+		if reflect.Pointer == v.Kind() {
+			ptr := ptrKey(v)
+			if w.seen[ptr] {
+				return false
+			}
+			w.seen[ptr] = true
+			defer delete(w.seen, ptr)
+		}
+		return w.walkValue(path, v.Elem())
+	case reflect.Slice, reflect.Array:
+		if !holdsStruct(v.Type().Elem()) {
+			return false
+		}
+		for i := 0; i < v.Len(); i++ {
+			if w.walkValue(fmt.Sprintf("%s[%d]", path, i), v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		if !holdsStruct(v.Type().Elem()) {
+			return false
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, k := range keys {
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(k))
+			if w.walkValue(fmt.Sprintf("%s[%v]", path, k), e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// holdsStruct reports whether t is a struct or an interface or a pointer
+// that may lead to one.
+func holdsStruct(t reflect.Type) bool {
+	for reflect.Pointer == t.Kind() {
+		t = t.Elem()
+	}
+	return reflect.Struct == t.Kind() || reflect.Interface == t.Kind()
+}
+
+// A Leaf is a field holding no further fields, found by Leaves.
+type Leaf struct {
+	Path  string
+	Value any
+	Field Field
+}
+
+// Leaves flattens the struct s into its leaf fields, in the order Walk visits
+// them, so that the whole struct can be read or modified in one loop.
+// A leaf is a field that Walk does not descend into further: a field that
+// is not a struct, or a nil pointer, or an empty collection of structs,
+// or a struct without exported fields, such as time.Time.
+// It panics if s is not non-nil pointer to struct.
+func Leaves(s any) []Leaf {
+	var leaves []Leaf
+	Walk(s, func(path string, f Field) WalkAction {
+		// Walk is depth-first, so the last leaf is the parent of this
+		// field if it is a prefix of its path.
+		if n := len(leaves); n > 0 {
+			parent := leaves[n-1].Path
+			if strings.HasPrefix(path, parent) && len(path) > len(parent) &&
+				(path[len(parent)] == '.' || path[len(parent)] == '[') {
+				leaves = leaves[:n-1]
+			}
+		}
+		leaves = append(leaves, Leaf{Path: path, Field: f})
+		return WalkContinue
+	})
+	for i := range leaves {
+		l := &leaves[i]
+		l.Value = l.Field.Interface()
+	}
+	return leaves
+}
+
+// FieldsRecursive returns the fields of the struct s, promoted fields
+// included as by Fields, with the fields holding structs replaced by their
+// own fields down to maxDepth levels of nesting, each with its Path, as
+// for form generators and mappers. The fields at depth maxDepth are not
+// replaced, so that 1 returns the fields of Fields; maxDepth 0 or less
+// does not limit the depth.
+//
+// A field is replaced if it is a struct with exported fields, or a non-nil
+// pointer or interface holding one, unless the pointer is already being
+// followed higher up the path, so that cyclic structures terminate. The
+// elements of slices, arrays and maps are not followed, and structs without
+// exported fields, such as time.Time, are fields themselves.
+// It panics if s is not non-nil pointer to struct.
+func FieldsRecursive(s any, maxDepth int) []Field {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Type().Elem().Kind() != reflect.Struct {
+		panic("not non-nil pointer to struct")
+	}
+	r := fieldsRecursion{maxDepth: maxDepth, seen: map[any]bool{ptrKey(v): true}}
+	return r.appendFields(nil, "", v.Elem(), 1)
+}
+
+// SettableFields is like FieldsRecursive but returns settable fields only,
+// allocating the pointers as needed, so that a single loop reads and sets
+// every leaf of a nested struct, such as a configuration.
+//
+// The nil pointers to structs with exported fields, embedded or not, are
+// set to new structs whose fields are returned, unless the struct type is
+// already being followed higher up the path, which would recurse forever:
+// such a pointer is returned as it is. An interface is followed only if it
+// holds a non-nil pointer, its value being unaddressable otherwise.
+// The fields promoted from an embedded pointer to an unexported struct
+// type, which cannot be allocated, are omitted if it is nil.
+// It panics if s is not non-nil pointer to struct.
+func SettableFields(s any, maxDepth int) []Field {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Type().Elem().Kind() != reflect.Struct {
+		panic("not non-nil pointer to struct")
+	}
+	r := fieldsRecursion{
+		maxDepth: maxDepth,
+		seen:     map[any]bool{ptrKey(v): true},
+		alloc:    true,
+		types:    make(map[reflect.Type]bool),
+	}
+	return r.appendFields(nil, "", v.Elem(), 1)
+}
+
+type fieldsRecursion struct {
+	maxDepth int
+
+	// Pointers being followed in the current path.
+	seen map[any]bool
+
+	// Whether the nil pointers are allocated, for SettableFields,
+	// and the struct types being followed in the current path.
+	alloc bool
+	types map[reflect.Type]bool
+}
+
+// appendFields appends to fields the fields of the struct v at depth,
+// prefixing their paths with prefix.
+func (r *fieldsRecursion) appendFields(fields []Field, prefix string, v reflect.Value, depth int) []Field {
+	if r.alloc {
+		r.types[v.Type()] = true
+		defer delete(r.types, v.Type())
+	}
+	for _, f := range r.fieldsOf(v) {
+		f.path = joinKey(prefix, f.sf.Name)
+		if r.maxDepth > 0 && depth >= r.maxDepth {
+			fields = append(fields, f)
+			continue
+		}
+
+		sv, ptr := r.follow(f.v)
+		if reflect.Struct != sv.Kind() || len(cachedTypeFields(sv.Type()).list) == 0 || r.seen[ptr] {
+			fields = append(fields, f)
+			continue
+		}
+		if ptr != nil {
+			r.seen[ptr] = true
+		}
+		fields = r.appendFields(fields, f.path, sv, depth+1)
+		if ptr != nil {
+			delete(r.seen, ptr)
+		}
+	}
+	return fields
+}
+
+// fieldsOf returns the Fields of the struct v, allocating the embedded
+// pointers holding them if r.alloc is set.
+func (r *fieldsRecursion) fieldsOf(v reflect.Value) []Field {
+	fields := cachedTypeFields(v.Type())
+	if !r.alloc {
+		return fieldsOf(v, fields)
+	}
+	fs := make([]Field, 0, len(fields.list))
+	for i := range fields.list {
+		f := &fields.list[i]
+		if f.method != "" {
+			continue
+		}
+		fv, err := fieldByIndexAlloc(v, f.index)
+		if err != nil {
+			continue
+		}
+		fs = append(fs, Field{v: fv, sf: v.Type().FieldByIndex(f.index), parent: v})
+	}
+	return fs
+}
+
+// follow returns the value that the pointers and interfaces v holds, through
+// the non-nil ones, allocating the nil pointers to structs to follow if
+// r.alloc is set, and the first pointer followed, if any.
+func (r *fieldsRecursion) follow(v reflect.Value) (reflect.Value, any) {
+	var ptr any
+	for {
+		switch v.Kind() {
+		case reflect.Interface:
+			if v.IsNil() || r.alloc && reflect.Pointer != v.Elem().Kind() {
+				return v, ptr
+			}
+			v = v.Elem()
+			continue
+		case reflect.Pointer:
+		default:
+			return v, ptr
+		}
+		if v.IsNil() {
+			et := indirectType(v.Type().Elem())
+			if !r.alloc || !v.CanSet() || reflect.Struct != et.Kind() ||
+				len(cachedTypeFields(et).list) == 0 || r.types[et] {
+				return v, ptr
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if ptr == nil {
+			ptr = ptrKey(v)
+		}
+		v = v.Elem()
+	}
+}
+
+// FieldPaths returns the paths of every leaf field reachable from the struct
+// type of i, in the order given by Fields. The i may be a struct, a pointer
+// to struct, or the reflect.Type of either.
+//
+// Paths are formed as by Walk but from the type alone: fields holding structs
+// or pointers to structs are replaced by the paths of their own fields, and
+// the elements of slices, arrays and maps of structs are denoted by "[]",
+// as in "User.Address.City" or "Items[].SKU". Structs without exported
+// fields, such as time.Time, are leaves. A recursive type is reported as
+// a leaf where it recurs.
+func FieldPaths(i any) []string {
+	return appendFieldPaths(nil, "", structType(i), make(map[reflect.Type]bool))
+}
+
+func appendFieldPaths(paths []string, prefix string, t reflect.Type, seen map[reflect.Type]bool) []string {
+	seen[t] = true
+	defer delete(seen, t)
+
+	fields := cachedTypeFields(t)
+	for i := range fields.list {
+		f := &fields.list[i]
+		if f.method != "" {
+			continue
+		}
+		path := t.FieldByIndex(f.index).Name
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		// The elements of nested collections are denoted by one "[]"
+		// each, as in "Grid[][].Value", if they are structs.
+		ft, suffix := typeByIndex(t, f.index), ""
+		for et, es := ft, ""; ; {
+			switch et.Kind() {
+			case reflect.Pointer:
+				et = et.Elem()
+				continue
+			case reflect.Slice, reflect.Array, reflect.Map:
+				et, es = et.Elem(), es+"[]"
+				continue
+			}
+			if reflect.Struct == et.Kind() {
+				ft, suffix = et, es
+			}
+			break
+		}
+
+		if reflect.Struct != ft.Kind() || seen[ft] || len(cachedTypeFields(ft).list) == 0 {
+			paths = append(paths, path+suffix)
+			continue
+		}
+		paths = appendFieldPaths(paths, path+suffix, ft, seen)
+	}
+	return paths
+}
@@ -0,0 +1,326 @@
+package structof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			City string
+		}
+		Item struct {
+			SKU string
+		}
+		User struct {
+			Name    string
+			Address *Address
+			Items   []Item
+			Secret  struct{ Token string }
+		}
+	)
+
+	u := &User{
+		Name:    "foobar",
+		Address: &Address{"Paris"},
+		Items:   []Item{{"a"}, {"b"}},
+	}
+
+	var paths []string
+	Walk(u, func(path string, f Field) WalkAction {
+		paths = append(paths, path)
+		if f.Name() == "Secret" {
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+	want := []string{
+		"Name",
+		"Address", "Address.City",
+		"Items", "Items[0].SKU", "Items[1].SKU",
+		"Secret",
+	}
+	if !cmp.Equal(want, paths) {
+		t.Error(cmp.Diff(want, paths))
+	}
+
+	paths = paths[:0]
+	Walk(u, func(path string, f Field) WalkAction {
+		paths = append(paths, path)
+		if path == "Address.City" {
+			f.Set("London")
+			return WalkStop
+		}
+		return WalkContinue
+	})
+	want = []string{"Name", "Address", "Address.City"}
+	if !cmp.Equal(want, paths) {
+		t.Error(cmp.Diff(want, paths))
+	}
+	if u.Address.City != "London" {
+		t.Errorf("Walk field set got %q want %q", u.Address.City, "London")
+	}
+}
+
+func TestWalkFirstFieldPointer(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct{ X, Y int }
+	type Outer struct {
+		Inner Inner
+		P     *Inner
+	}
+	o := &Outer{Inner: Inner{1, 2}}
+	o.P = &o.Inner
+
+	var paths []string
+	Walk(o, func(path string, _ Field) WalkAction {
+		paths = append(paths, path)
+		return WalkContinue
+	})
+	want := []string{"Inner", "Inner.X", "Inner.Y", "P", "P.X", "P.Y"}
+	if !cmp.Equal(want, paths) {
+		t.Error(cmp.Diff(want, paths))
+	}
+}
+
+func TestWalkCycle(t *testing.T) {
+	t.Parallel()
+
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	n := &Node{Name: "a"}
+	n.Next = &Node{Name: "b", Next: n}
+
+	var paths []string
+	Walk(n, func(path string, _ Field) WalkAction {
+		paths = append(paths, path)
+		return WalkContinue
+	})
+	want := []string{"Name", "Next", "Next.Name", "Next.Next"}
+	if !cmp.Equal(want, paths) {
+		t.Error(cmp.Diff(want, paths))
+	}
+}
+
+func TestFieldPaths(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			City string
+		}
+		Item struct {
+			SKU string
+		}
+		Node struct {
+			Next *Node
+		}
+		Base struct {
+			ID int
+		}
+		User struct {
+			Base
+			Name    string
+			Address *Address
+			Items   []*Item
+			Tags    []string
+			Node    Node
+			Ignored string `structof:"-"`
+			Grid    [][]Item
+			ByName  map[string][2]*Item
+			Matrix  [][]int
+		}
+	)
+
+	paths := FieldPaths(User{})
+	want := []string{"ID", "Name", "Address.City", "Items[].SKU", "Tags", "Node.Next", "Grid[][].SKU", "ByName[][].SKU", "Matrix"}
+	if !cmp.Equal(want, paths) {
+		t.Error(cmp.Diff(want, paths))
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	t.Parallel()
+
+	type (
+		Address struct {
+			City string
+		}
+		Item struct {
+			SKU string
+		}
+		User struct {
+			Name    string
+			Address *Address
+			Billing *Address
+			Items   []Item
+		}
+	)
+
+	u := &User{
+		Name:    "foobar",
+		Address: &Address{"Paris"},
+		Items:   []Item{{"a"}, {"b"}},
+	}
+	leaves := Leaves(u)
+
+	paths := make([]string, len(leaves))
+	values := make([]any, len(leaves))
+	for i, l := range leaves {
+		paths[i], values[i] = l.Path, l.Value
+	}
+	wantPaths := []string{"Name", "Address.City", "Billing", "Items[0].SKU", "Items[1].SKU"}
+	if !cmp.Equal(wantPaths, paths) {
+		t.Error(cmp.Diff(wantPaths, paths))
+	}
+	wantValues := []any{"foobar", "Paris", (*Address)(nil), "a", "b"}
+	if !cmp.Equal(wantValues, values) {
+		t.Error(cmp.Diff(wantValues, values))
+	}
+
+	leaves[3].Field.Set("c")
+	if u.Items[0].SKU != "c" {
+		t.Errorf("Leaf field set got %q want %q", u.Items[0].SKU, "c")
+	}
+}
+
+func TestFieldsRecursive(t *testing.T) {
+	t.Parallel()
+
+	type Geo struct {
+		Lat, Lng float64
+	}
+	type Address struct {
+		City string
+		Geo  *Geo
+	}
+	type Base struct {
+		ID int
+	}
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	type S struct {
+		Base
+		Name    string
+		Address Address
+		Home    *Address
+		Any     any
+		Tags    []Address
+		Created time.Time
+		Node    *Node
+	}
+	s := S{Address: Address{Geo: &Geo{}}, Any: &Geo{}}
+	s.Node = &Node{Name: "a"}
+	s.Node.Next = s.Node
+
+	paths := func(fields []Field) []string {
+		var paths []string
+		for _, f := range fields {
+			paths = append(paths, f.Path())
+		}
+		return paths
+	}
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{1, []string{"ID", "Name", "Address", "Home", "Any", "Tags", "Created", "Node"}},
+		{2, []string{"ID", "Name", "Address.City", "Address.Geo", "Home", "Any.Lat", "Any.Lng", "Tags", "Created", "Node.Name", "Node.Next"}},
+		{0, []string{"ID", "Name", "Address.City", "Address.Geo.Lat", "Address.Geo.Lng", "Home", "Any.Lat", "Any.Lng", "Tags", "Created", "Node.Name", "Node.Next"}},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, paths(FieldsRecursive(&s, tt.maxDepth))); diff != "" {
+			t.Errorf("FieldsRecursive(%d) mismatch (-want +got):\n%s", tt.maxDepth, diff)
+		}
+	}
+
+	for _, f := range FieldsRecursive(&s, 0) {
+		if f.Path() == "Address.Geo.Lat" {
+			f.Set(1.5)
+		}
+	}
+	if s.Address.Geo.Lat != 1.5 {
+		t.Errorf("Lat = %v, want 1.5", s.Address.Geo.Lat)
+	}
+}
+
+func TestFieldsRecursiveFirstFieldPointer(t *testing.T) {
+	t.Parallel()
+
+	type Inner struct{ X, Y int }
+	type Outer struct {
+		Inner Inner
+		P     *Inner
+	}
+	o := &Outer{Inner: Inner{1, 2}}
+	o.P = &o.Inner
+
+	want := []string{"Inner.X", "Inner.Y", "P.X", "P.Y"}
+	for name, fields := range map[string][]Field{
+		"FieldsRecursive": FieldsRecursive(o, 0),
+		"SettableFields":  SettableFields(o, 0),
+	} {
+		var paths []string
+		for _, f := range fields {
+			paths = append(paths, f.Path())
+		}
+		if diff := cmp.Diff(want, paths); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestSettableFields(t *testing.T) {
+	t.Parallel()
+
+	type TLS struct {
+		Cert string
+	}
+	type Server struct {
+		Host string
+		TLS  *TLS
+	}
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	type Config struct {
+		*Server
+		Port    int
+		Any     any
+		Node    *Node
+		Created time.Time
+	}
+	var c Config
+	var paths []string
+	for _, f := range SettableFields(&c, 0) {
+		paths = append(paths, f.Path())
+		if !f.Value().CanSet() {
+			t.Errorf("field %s not settable", f.Path())
+		}
+		if f.Path() == "TLS.Cert" {
+			f.Set("cert.pem")
+		}
+	}
+
+	want := []string{"Host", "TLS.Cert", "Port", "Any", "Node.Name", "Node.Next", "Created"}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("SettableFields mismatch (-want +got):\n%s", diff)
+	}
+	if c.Server == nil || c.TLS == nil || c.TLS.Cert != "cert.pem" {
+		t.Errorf("Config = %+v", c)
+	}
+	if c.Node == nil || c.Node.Next != nil {
+		t.Errorf("Node = %+v", c.Node)
+	}
+}